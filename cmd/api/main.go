@@ -3,17 +3,31 @@
 // @description API for managing user subscriptions
 // @host localhost:3000
 // @BasePath /
+// @securityDefinitions.apikey AdminToken
+// @in header
+// @name Authorization
 
 package main
 
 import (
+	"context"
+	"errors"
 	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/morphlinkk/subscriptions/internal/config"
 	"github.com/morphlinkk/subscriptions/internal/logger"
 	"github.com/morphlinkk/subscriptions/internal/server"
 )
 
+// shutdownTimeout bounds how long in-flight requests may take to finish once
+// the process is asked to stop.
+const shutdownTimeout = 10 * time.Second
+
 func main() {
 	cfg, err := config.Load()
 	if err != nil {
@@ -22,13 +36,32 @@ func main() {
 
 	logger.SetLogLevel(cfg)
 
-	srv, err := server.NewServer(cfg)
+	srv, runJobs, err := server.NewServer(cfg)
 	if err != nil {
 		logger.Fatal("Failed to create server", "error", err)
 	}
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go runJobs(ctx)
+
+	httpServer := &http.Server{Addr: ":" + cfg.ServerPort, Handler: srv}
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		slog.Info("Shutting down server")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			slog.Error("Failed to shut down server", "error", err)
+		}
+	}()
+
 	slog.Info("Starting server on", "port", cfg.ServerPort)
-	if err := srv.Run(":" + cfg.ServerPort); err != nil {
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		logger.Fatal("Failed to start server", "error", err)
 	}
+	<-stopped
 }
@@ -0,0 +1,80 @@
+// Command rates imports exchange rates from a CSV or ECB XML file into the
+// database. Rates already stored for the same date and currency pair are
+// replaced.
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/morphlinkk/subscriptions/internal/config"
+	"github.com/morphlinkk/subscriptions/internal/db"
+	"github.com/morphlinkk/subscriptions/internal/logger"
+	"github.com/morphlinkk/subscriptions/internal/model"
+	"github.com/morphlinkk/subscriptions/internal/rates"
+	"github.com/morphlinkk/subscriptions/internal/repository"
+)
+
+func main() {
+	file := flag.String("file", "", "path of the file to import")
+	format := flag.String("format", "", "csv or ecb, detected from the file extension (.csv, .xml) when empty")
+	flag.Parse()
+
+	if *file == "" {
+		logger.Fatal("Missing -file")
+	}
+	if *format == "" {
+		switch strings.ToLower(filepath.Ext(*file)) {
+		case ".csv":
+			*format = "csv"
+		case ".xml":
+			*format = "ecb"
+		default:
+			logger.Fatal("Cannot detect the format, pass -format", "file", *file)
+		}
+	}
+
+	var parse func(io.Reader) ([]model.ExchangeRate, error)
+	switch *format {
+	case "csv":
+		parse = rates.ParseCSV
+	case "ecb":
+		parse = rates.ParseECB
+	default:
+		logger.Fatal("Unknown format, use csv or ecb", "format", *format)
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		logger.Fatal("Failed to open file", "error", err)
+	}
+	parsed, err := parse(f)
+	f.Close()
+	if err != nil {
+		logger.Fatal("Failed to parse file", "file", *file, "error", err)
+	}
+
+	ctx := context.Background()
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Fatal("Failed to load configuration", "error", err)
+	}
+
+	store, err := db.NewStore(ctx, *cfg)
+	if err != nil {
+		logger.Fatal("Failed to connect to database", "error", err)
+	}
+	defer store.Close()
+
+	n, err := repository.NewExchangeRateRepository(store).ImportRates(ctx, parsed)
+	if err != nil {
+		logger.Fatal("Failed to import rates", "error", err)
+	}
+
+	slog.Info("Imported exchange rates", "file", *file, "rates", n)
+}
@@ -0,0 +1,155 @@
+// Package apperr defines the domain errors shared by the repository, service
+// and handler layers. Handlers map an error's Kind to an HTTP status in one
+// place, so lower layers never deal with status codes.
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindNotFound
+	KindValidation
+	KindConflict
+	KindForbidden
+	KindPreconditionFailed
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindNotFound:
+		return "not_found"
+	case KindValidation:
+		return "validation"
+	case KindConflict:
+		return "conflict"
+	case KindForbidden:
+		return "forbidden"
+	case KindPreconditionFailed:
+		return "precondition_failed"
+	default:
+		return "internal"
+	}
+}
+
+// Field codes used in FieldError.Code.
+const (
+	CodeRequired      = "required"
+	CodeInvalid       = "invalid"
+	CodeInvalidFormat = "invalid_format"
+	CodeOutOfRange    = "out_of_range"
+	CodeUnknown       = "unknown"
+)
+
+// FieldError describes a problem with a single input field.
+type FieldError struct {
+	Field   string
+	Code    string
+	Message string
+}
+
+func Field(field, code, message string) FieldError {
+	return FieldError{Field: field, Code: code, Message: message}
+}
+
+type Error struct {
+	Kind    Kind
+	Message string
+	Fields  []FieldError
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if len(e.Fields) == 0 {
+		return e.Message
+	}
+
+	parts := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		parts[i] = f.Message
+	}
+	return e.Message + ": " + strings.Join(parts, "; ")
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func NotFound(format string, args ...any) *Error {
+	return &Error{Kind: KindNotFound, Message: fmt.Sprintf(format, args...)}
+}
+
+func Validation(message string, fields ...FieldError) *Error {
+	return &Error{Kind: KindValidation, Message: message, Fields: fields}
+}
+
+// InvalidField is a shorthand for a validation error on a single field.
+func InvalidField(field, code, message string) *Error {
+	return Validation("validation failed", Field(field, code, message))
+}
+
+func Conflict(format string, args ...any) *Error {
+	return &Error{Kind: KindConflict, Message: fmt.Sprintf(format, args...)}
+}
+
+func Forbidden(format string, args ...any) *Error {
+	return &Error{Kind: KindForbidden, Message: fmt.Sprintf(format, args...)}
+}
+
+// PreconditionFailed reports a conditional request whose condition no longer
+// holds, e.g. a stale version.
+func PreconditionFailed(format string, args ...any) *Error {
+	return &Error{Kind: KindPreconditionFailed, Message: fmt.Sprintf(format, args...)}
+}
+
+// Wrap attaches kind and message to err while keeping it in the chain.
+func Wrap(err error, kind Kind, message string) *Error {
+	return &Error{Kind: kind, Message: message, Err: err}
+}
+
+// As returns the *Error in err's chain, if any.
+func As(err error) (*Error, bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e, true
+	}
+	return nil, false
+}
+
+// KindOf returns the Kind of err, or KindInternal when err is not a domain
+// error.
+func KindOf(err error) Kind {
+	if e, ok := As(err); ok {
+		return e.Kind
+	}
+	return KindInternal
+}
+
+// Validator collects field errors so a request can report all of them at once.
+type Validator struct {
+	fields []FieldError
+}
+
+func (v *Validator) Add(field, code, message string) {
+	v.fields = append(v.fields, Field(field, code, message))
+}
+
+// Check adds a field error when ok is false.
+func (v *Validator) Check(ok bool, field, code, message string) {
+	if !ok {
+		v.Add(field, code, message)
+	}
+}
+
+// Err returns a validation error holding every collected field error, or nil.
+func (v *Validator) Err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return Validation("validation failed", v.fields...)
+}
@@ -16,6 +16,11 @@ type Config struct {
 	DatabaseMaxConnections  int           `mapstructure:"DATABASE_MAXCONNS"`
 	DatabaseMinConnections  int           `mapstructure:"DATABASE_MINCONNS"`
 	DatabaseMaxConnLifetime time.Duration `mapstructure:"DATABASE_MAXCONNLIFETIME"`
+	AdminToken              string        `mapstructure:"ADMIN_TOKEN"`
+	IdempotencyTTL          time.Duration `mapstructure:"IDEMPOTENCY_TTL"`
+	TrialJobInterval        time.Duration `mapstructure:"TRIAL_JOB_INTERVAL"`
+	TrialReminderDays       int           `mapstructure:"TRIAL_REMINDER_DAYS"`
+	PriceJobInterval        time.Duration `mapstructure:"PRICE_JOB_INTERVAL"`
 }
 
 func Load() (*Config, error) {
@@ -56,6 +61,11 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("DATABASE_MAXCONNECTIONS", 25)
 	v.SetDefault("DATABASE_MINCONNECTIONS", 5)
 	v.SetDefault("DATABASE_MAXCONNLIFETIME", 30*time.Minute)
+	v.SetDefault("ADMIN_TOKEN", "")
+	v.SetDefault("IDEMPOTENCY_TTL", 24*time.Hour)
+	v.SetDefault("TRIAL_JOB_INTERVAL", 24*time.Hour)
+	v.SetDefault("TRIAL_REMINDER_DAYS", 3)
+	v.SetDefault("PRICE_JOB_INTERVAL", time.Hour)
 }
 
 func (c *Config) Validate() error {
@@ -71,5 +81,21 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("DATABASE_MAXCONNECTIONS must be at least 1")
 	}
 
+	if c.IdempotencyTTL <= 0 {
+		return fmt.Errorf("IDEMPOTENCY_TTL must be positive")
+	}
+
+	if c.TrialJobInterval <= 0 {
+		return fmt.Errorf("TRIAL_JOB_INTERVAL must be positive")
+	}
+
+	if c.TrialReminderDays < 0 {
+		return fmt.Errorf("TRIAL_REMINDER_DAYS must not be negative")
+	}
+
+	if c.PriceJobInterval <= 0 {
+		return fmt.Errorf("PRICE_JOB_INTERVAL must be positive")
+	}
+
 	return nil
 }
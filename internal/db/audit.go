@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+
+	"github.com/morphlinkk/subscriptions/internal/model"
+)
+
+const auditColumns = `id, subscription_id, operation, actor, request_id, before, after, created_at`
+
+// auditFields returns the scan destinations matching auditColumns.
+func auditFields(e *model.SubscriptionAuditEntry) []any {
+	return []any{
+		&e.ID,
+		&e.SubscriptionID,
+		&e.Operation,
+		&e.Actor,
+		&e.RequestID,
+		&e.Before,
+		&e.After,
+		&e.CreatedAt,
+	}
+}
+
+const addSubscriptionAuditQuery = `
+	INSERT INTO subscription_audit (
+			subscription_id,
+			operation,
+			actor,
+			request_id,
+			before,
+			after
+	)
+	VALUES ($1,$2,$3,$4,$5,$6)
+`
+
+func (q *Queries) AddSubscriptionAudit(ctx context.Context, params model.AddSubscriptionAuditParams) error {
+	_, err := q.db.Exec(ctx, addSubscriptionAuditQuery,
+		params.SubscriptionID,
+		params.Operation,
+		params.Actor,
+		params.RequestID,
+		params.Before,
+		params.After,
+	)
+	return err
+}
+
+// ListSubscriptionAudit returns the audit entries matching params, oldest
+// first. To is exclusive.
+func (q *Queries) ListSubscriptionAudit(ctx context.Context, params model.ListAuditParams) ([]model.SubscriptionAuditEntry, error) {
+	b := &queryBuilder{}
+	if params.SubscriptionID != nil {
+		b.where("subscription_id = " + b.arg(*params.SubscriptionID))
+	}
+	if params.Actor != nil {
+		b.where("actor = " + b.arg(*params.Actor))
+	}
+	if params.Operation != nil {
+		b.where("operation = " + b.arg(*params.Operation))
+	}
+	if params.RequestID != nil {
+		b.where("request_id = " + b.arg(*params.RequestID))
+	}
+	if params.From != nil {
+		b.where("created_at >= " + b.arg(*params.From))
+	}
+	if params.To != nil {
+		b.where("created_at < " + b.arg(*params.To))
+	}
+
+	query := `
+	SELECT ` + auditColumns + `
+	FROM subscription_audit` + b.whereClause() + `
+	ORDER BY id
+	LIMIT ` + b.arg(params.Limit) + ` OFFSET ` + b.arg(params.Offset)
+
+	rows, err := q.db.Query(ctx, query, b.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []model.SubscriptionAuditEntry
+
+	for rows.Next() {
+		var e model.SubscriptionAuditEntry
+		if err := rows.Scan(auditFields(&e)...); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/morphlinkk/subscriptions/internal/model"
+)
+
+const upsertExchangeRatesQuery = `
+	INSERT INTO exchange_rates (rate_date, base, quote, rate)
+	SELECT * FROM unnest($1::date[], $2::text[], $3::text[], $4::numeric[])
+	ON CONFLICT (base, quote, rate_date) DO UPDATE SET rate = EXCLUDED.rate
+`
+
+// UpsertExchangeRates stores rates, replacing the stored rate of the same
+// date and currency pair. A pair may only appear once per date in rates.
+func (q *Queries) UpsertExchangeRates(ctx context.Context, rates []model.ExchangeRate) error {
+	dates := make([]time.Time, len(rates))
+	bases := make([]string, len(rates))
+	quotes := make([]string, len(rates))
+	values := make([]float64, len(rates))
+	for i, r := range rates {
+		dates[i] = r.Date
+		bases[i] = r.Base
+		quotes[i] = r.Quote
+		values[i] = r.Rate
+	}
+
+	_, err := q.db.Exec(ctx, upsertExchangeRatesQuery, dates, bases, quotes, values)
+	return err
+}
+
+const listMonthlyExchangeRatesQuery = `
+	SELECT m.month, r.rate_date, r.base, r.quote, r.rate
+	FROM generate_series($1::timestamp, $2::timestamp, interval '1 month') AS m(month)
+	CROSS JOIN LATERAL (
+		SELECT DISTINCT ON (base, quote) rate_date, base, quote, rate::float8
+		FROM exchange_rates
+		WHERE rate_date < m.month + interval '1 month'
+			AND (base = ANY($3) OR quote = ANY($3))
+		ORDER BY base, quote, rate_date DESC
+	) r
+	ORDER BY m.month, r.base, r.quote
+`
+
+// ListMonthlyExchangeRates returns, for every month from first to last, the
+// latest rate of each currency pair involving one of currencies that is dated
+// within or before that month.
+func (q *Queries) ListMonthlyExchangeRates(ctx context.Context, first, last time.Time, currencies []string) ([]model.AppliedRate, error) {
+	rows, err := q.db.Query(ctx, listMonthlyExchangeRatesQuery, first, last, currencies)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rates []model.AppliedRate
+
+	for rows.Next() {
+		var r model.AppliedRate
+		if err := rows.Scan(&r.Month, &r.Date, &r.Base, &r.Quote, &r.Rate); err != nil {
+			return nil, err
+		}
+		rates = append(rates, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return rates, nil
+}
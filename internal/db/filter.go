@@ -0,0 +1,299 @@
+package db
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/morphlinkk/subscriptions/internal/model"
+)
+
+// queryBuilder accumulates WHERE conditions together with their positional
+// arguments. Values only ever reach the query as arguments; the SQL text is
+// assembled from fixed fragments.
+type queryBuilder struct {
+	conds []string
+	args  []any
+	// search is the placeholder of the fuzzy search query, if any.
+	search string
+}
+
+// arg appends v to the argument list and returns its placeholder.
+func (b *queryBuilder) arg(v any) string {
+	b.args = append(b.args, v)
+	return "$" + strconv.Itoa(len(b.args))
+}
+
+func (b *queryBuilder) where(cond string) {
+	b.conds = append(b.conds, cond)
+}
+
+func (b *queryBuilder) whereClause() string {
+	if len(b.conds) == 0 {
+		return ""
+	}
+	return "\n\tWHERE " + strings.Join(b.conds, "\n\t\tAND ")
+}
+
+// escapeLike escapes the LIKE wildcards in s.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// addSubscriptionFilters adds the filters of params to b. Month bounds are
+// inclusive, so "to" filters compare against the first day of the next month.
+func addSubscriptionFilters(b *queryBuilder, params model.ListSubscriptionsParams) {
+	b.where("deleted_at IS NULL")
+
+	if params.Query != nil {
+		b.search = b.arg(strings.ToLower(strings.TrimSpace(*params.Query))) + "::text"
+		b.where(withSearch(searchMatchExpr, b.search))
+	}
+
+	if params.UserID != nil {
+		b.where("user_id = " + b.arg(*params.UserID))
+	}
+	if params.ServiceName != nil {
+		b.where("service_name = " + b.arg(*params.ServiceName))
+	}
+	if params.ServicePrefix != nil {
+		b.where("service_name ILIKE " + b.arg(escapeLike(*params.ServicePrefix)+"%") + ` ESCAPE '\'`)
+	}
+	if params.PriceMin != nil {
+		b.where("price >= " + b.arg(*params.PriceMin))
+	}
+	if params.PriceMax != nil {
+		b.where("price <= " + b.arg(*params.PriceMax))
+	}
+	if params.ActiveOn != nil {
+		on := b.arg(*params.ActiveOn)
+		b.where(fmt.Sprintf("start_date <= %[1]s::timestamp AND (end_date IS NULL OR end_date >= date_trunc('month', %[1]s::timestamp))", on))
+	}
+	if params.ActiveIn != nil {
+		in := b.arg(*params.ActiveIn)
+		b.where(fmt.Sprintf("start_date <= %[1]s::timestamp AND (end_date IS NULL OR end_date >= %[1]s::timestamp)", in))
+	}
+	if params.StartedFrom != nil {
+		b.where("start_date >= " + b.arg(*params.StartedFrom))
+	}
+	if params.StartedTo != nil {
+		b.where("start_date < " + b.arg(nextMonth(*params.StartedTo)))
+	}
+	if params.EndedFrom != nil {
+		b.where("end_date >= " + b.arg(*params.EndedFrom))
+	}
+	if params.EndedTo != nil {
+		b.where("end_date < " + b.arg(nextMonth(*params.EndedTo)))
+	}
+	if params.EndingWithin != nil {
+		b.where(`COALESCE(cancel_at, (date_trunc('month', end_date) + interval '1 month - 1 day')::date)
+			BETWEEN ` + b.arg(params.Today) + `::date AND ` + b.arg(params.Today.AddDate(0, 0, *params.EndingWithin)) + `::date`)
+	}
+	if params.HasEndDate != nil {
+		if *params.HasEndDate {
+			b.where("end_date IS NOT NULL")
+		} else {
+			b.where("end_date IS NULL")
+		}
+	}
+}
+
+// searchToken stands for the search query placeholder in search expressions.
+const searchToken = "{q}"
+
+const (
+	// searchMatchExpr uses the pg_trgm operators so the trigram index on
+	// lower(service_name) applies.
+	searchMatchExpr = "(lower(service_name) % {q} OR {q} <% lower(service_name))"
+	searchRankExpr  = "GREATEST(similarity(lower(service_name), {q}), word_similarity({q}, lower(service_name)))"
+)
+
+func withSearch(expr, placeholder string) string {
+	return strings.ReplaceAll(expr, searchToken, placeholder)
+}
+
+func nextMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// sortKey is a column subscriptions can be ordered by. expr never evaluates
+// to NULL so keyset comparisons stay total.
+type sortKey struct {
+	expr  string
+	cast  string
+	value func(model.Subscription) string
+	desc  bool
+}
+
+const cursorTimeLayout = "2006-01-02 15:04:05.999999"
+
+var subscriptionSortKeys = map[string]sortKey{
+	model.SortByID: {
+		expr:  "id",
+		cast:  "bigint",
+		value: func(s model.Subscription) string { return strconv.FormatInt(s.ID, 10) },
+	},
+	model.SortByServiceName: {
+		expr:  "service_name",
+		cast:  "text",
+		value: func(s model.Subscription) string { return s.Service },
+	},
+	model.SortByPrice: {
+		expr:  "price",
+		cast:  "bigint",
+		value: func(s model.Subscription) string { return strconv.Itoa(s.Price) },
+	},
+	model.SortByStartDate: {
+		expr:  "start_date",
+		cast:  "timestamp",
+		value: func(s model.Subscription) string { return s.StartDate.Format(cursorTimeLayout) },
+	},
+	model.SortByRelevance: {
+		expr: searchRankExpr,
+		cast: "real",
+		value: func(s model.Subscription) string {
+			if s.Relevance == nil {
+				return "0"
+			}
+			return strconv.FormatFloat(float64(*s.Relevance), 'g', -1, 32)
+		},
+	},
+	model.SortByEndDate: {
+		expr: "COALESCE(end_date, 'infinity'::timestamp)",
+		cast: "timestamp",
+		value: func(s model.Subscription) string {
+			if s.EndDate == nil {
+				return "infinity"
+			}
+			return s.EndDate.Format(cursorTimeLayout)
+		},
+	},
+}
+
+// resolveSort returns the sort keys for s, always ending with id so that the
+// order is total. search is the search query placeholder; it is required when
+// s sorts by relevance.
+func resolveSort(s model.SubscriptionSort, search string) ([]sortKey, error) {
+	if len(s) == 0 {
+		s = model.DefaultSubscriptionSort
+	}
+
+	keys := make([]sortKey, 0, len(s)+1)
+	hasID := false
+	for _, f := range s {
+		k, ok := subscriptionSortKeys[f.Field]
+		if !ok {
+			return nil, fmt.Errorf("unknown sort field %q", f.Field)
+		}
+		if strings.Contains(k.expr, searchToken) {
+			if search == "" {
+				return nil, fmt.Errorf("sort field %q requires a search query", f.Field)
+			}
+			k.expr = withSearch(k.expr, search)
+		}
+		k.desc = f.Desc
+		keys = append(keys, k)
+		hasID = hasID || f.Field == model.SortByID
+	}
+	if !hasID {
+		k := subscriptionSortKeys[model.SortByID]
+		k.desc = true
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+func orderByClause(keys []sortKey) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		dir := "ASC"
+		if k.desc {
+			dir = "DESC"
+		}
+		parts[i] = k.expr + " " + dir
+	}
+	return strings.Join(parts, ", ")
+}
+
+// keysetCondition matches the rows that come after values in the order given
+// by keys: (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ..., with < for descending
+// keys.
+func keysetCondition(b *queryBuilder, keys []sortKey, values []string) string {
+	placeholders := make([]string, len(keys))
+	for i, k := range keys {
+		placeholders[i] = b.arg(values[i]) + "::" + k.cast
+	}
+
+	ors := make([]string, len(keys))
+	for i, k := range keys {
+		ands := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, keys[j].expr+" = "+placeholders[j])
+		}
+		op := ">"
+		if k.desc {
+			op = "<"
+		}
+		ands = append(ands, k.expr+" "+op+" "+placeholders[i])
+		ors[i] = "(" + strings.Join(ands, " AND ") + ")"
+	}
+	return "(" + strings.Join(ors, " OR ") + ")"
+}
+
+// castParsers check that a cursor value converts to the type of its key, so
+// that a forged cursor never reaches the query as a failing cast.
+var castParsers = map[string]func(string) bool{
+	"bigint": func(v string) bool {
+		_, err := strconv.ParseInt(v, 10, 64)
+		return err == nil
+	},
+	"real": func(v string) bool {
+		f, err := strconv.ParseFloat(v, 32)
+		return err == nil && !math.IsNaN(f) && !math.IsInf(f, 0)
+	},
+	"text": func(v string) bool {
+		return utf8.ValidString(v) && !strings.ContainsRune(v, 0)
+	},
+	"timestamp": func(v string) bool {
+		if v == "infinity" {
+			return true
+		}
+		_, err := time.Parse(cursorTimeLayout, v)
+		return err == nil
+	},
+}
+
+// ValidateCursor reports whether c holds one value of the right type for
+// every key of sort.
+func ValidateCursor(c model.SubscriptionCursor, sort model.SubscriptionSort) error {
+	keys, err := resolveSort(sort, searchToken)
+	if err != nil {
+		return err
+	}
+	if len(c.Values) != len(keys) {
+		return fmt.Errorf("cursor has %d values, sort needs %d", len(c.Values), len(keys))
+	}
+	for i, k := range keys {
+		if !castParsers[k.cast](c.Values[i]) {
+			return fmt.Errorf("cursor value %d is not a valid %s", i, k.cast)
+		}
+	}
+	return nil
+}
+
+// NewSubscriptionCursor returns the cursor positioned at s for the given sort.
+func NewSubscriptionCursor(s model.Subscription, sort model.SubscriptionSort) (*model.SubscriptionCursor, error) {
+	keys, err := resolveSort(sort, searchToken)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]string, len(keys))
+	for i, k := range keys {
+		values[i] = k.value(s)
+	}
+	return &model.SubscriptionCursor{Sort: sort.String(), Values: values}, nil
+}
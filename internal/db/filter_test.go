@@ -0,0 +1,42 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/morphlinkk/subscriptions/internal/model"
+)
+
+func TestValidateCursor(t *testing.T) {
+	byPrice := model.SubscriptionSort{{Field: model.SortByPrice}}
+	byEnd := model.SubscriptionSort{{Field: model.SortByEndDate}}
+	byName := model.SubscriptionSort{{Field: model.SortByServiceName}}
+
+	tests := []struct {
+		name    string
+		sort    model.SubscriptionSort
+		values  []string
+		wantErr bool
+	}{
+		{"price and id", byPrice, []string{"799", "12"}, false},
+		{"default sort", nil, []string{"2026-03-01 00:00:00", "12"}, false},
+		{"open end date", byEnd, []string{"infinity", "12"}, false},
+		{"service name", byName, []string{"Яндекс Плюс", "12"}, false},
+		{"missing id", byPrice, []string{"799"}, true},
+		{"extra value", byPrice, []string{"799", "12", "1"}, true},
+		{"price out of bigint range", byPrice, []string{"9223372036854775808", "12"}, true},
+		{"id not a number", byPrice, []string{"799", "twelve"}, true},
+		{"malformed date", nil, []string{"01.03.2026", "12"}, true},
+		{"name with a NUL byte", byName, []string{"Net\x00flix", "12"}, true},
+		{"name not UTF-8", byName, []string{"\xff", "12"}, true},
+		{"unknown sort field", model.SubscriptionSort{{Field: "user_id"}}, []string{"1", "12"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCursor(model.SubscriptionCursor{Values: tt.values}, tt.sort)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCursor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
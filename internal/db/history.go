@@ -0,0 +1,119 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/morphlinkk/subscriptions/internal/model"
+)
+
+const closeSubscriptionHistoryQuery = `
+	UPDATE subscription_history
+	SET valid_to = now()
+	WHERE id = $1 AND valid_to IS NULL
+`
+
+// CloseSubscriptionHistory ends the currently valid state of subscription id.
+func (q *Queries) CloseSubscriptionHistory(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, closeSubscriptionHistoryQuery, id)
+	return err
+}
+
+const openSubscriptionHistoryQuery = `
+	INSERT INTO subscription_history (
+			id,
+			service_name,
+			price,
+			user_id,
+			start_date,
+			end_date,
+			service_id,
+			transferred_from,
+			version,
+			billing_unit,
+			billing_count,
+			billing_anchor_day,
+			currency,
+			status,
+			status_changed_at,
+			trial_end,
+			trial_flagged_at,
+			cancel_at,
+			valid_from
+	)
+	VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,
+		(SELECT status FROM subscriptions WHERE id = $1),$14,$15,$16,$17,now())
+`
+
+// OpenSubscriptionHistory records s as the state valid from now on. The status
+// is copied as stored rather than as read, so that reading the history derives
+// it for the moment asked about.
+func (q *Queries) OpenSubscriptionHistory(ctx context.Context, s model.Subscription) error {
+	_, err := q.db.Exec(ctx, openSubscriptionHistoryQuery,
+		s.ID,
+		s.Service,
+		s.Price,
+		s.UserID,
+		s.StartDate,
+		s.EndDate,
+		s.ServiceID,
+		s.TransferredFrom,
+		s.Version,
+		s.Billing.Unit,
+		s.Billing.Count,
+		s.Billing.AnchorDay,
+		s.Currency,
+		s.StatusChangedAt,
+		s.TrialEnd,
+		s.TrialFlaggedAt,
+		s.CancelAt,
+	)
+	return err
+}
+
+// subscriptionSource returns the relation subscriptions are read from: the
+// table itself, or when asOf is a placeholder, the history rows valid at that
+// moment under the same name and columns.
+func subscriptionSource(asOf string) string {
+	if asOf == "" {
+		return "subscriptions"
+	}
+	return `(
+		SELECT subscription_history.*, NULL::timestamp AS deleted_at
+		FROM subscription_history
+		WHERE valid_from <= ` + asOf + `::timestamptz
+			AND (valid_to IS NULL OR valid_to > ` + asOf + `::timestamptz)
+	) subscriptions`
+}
+
+// subscriptionColumnsAt returns subscriptionColumns reading the status as of
+// the placeholder asOf instead of now, or unchanged when asOf is empty.
+func subscriptionColumnsAt(asOf string) string {
+	if asOf == "" {
+		return subscriptionColumns
+	}
+	return strings.ReplaceAll(subscriptionColumns, "localtimestamp", "("+asOf+"::timestamptz)::timestamp")
+}
+
+// subscriptionSourceFor returns subscriptionSource and subscriptionColumnsAt
+// for asOf, adding it to b's arguments.
+func subscriptionSourceFor(b *queryBuilder, asOf *time.Time) (source, columns string) {
+	if asOf == nil {
+		return subscriptionSource(""), subscriptionColumns
+	}
+	arg := b.arg(*asOf)
+	return subscriptionSource(arg), subscriptionColumnsAt(arg)
+}
+
+// GetSubscriptionAsOf returns subscription id as it was stored at asOf.
+func (q *Queries) GetSubscriptionAsOf(ctx context.Context, id int64, asOf time.Time) (model.Subscription, error) {
+	query := `
+	SELECT ` + subscriptionColumnsAt("$2") + `
+	FROM ` + subscriptionSource("$2") + `
+	WHERE id = $1
+`
+	var s model.Subscription
+	err := q.db.QueryRow(ctx, query, id, asOf).Scan(subscriptionFields(&s)...)
+	return s, err
+}
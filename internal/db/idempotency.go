@@ -0,0 +1,119 @@
+package db
+
+import (
+	"context"
+
+	"github.com/morphlinkk/subscriptions/internal/model"
+)
+
+const lockIdempotencyKeyQuery = `
+	SELECT set_config('lock_timeout', $2, true), pg_advisory_xact_lock(hashtextextended($1, 0))
+`
+
+// LockIdempotencyKey takes a transaction-scoped advisory lock on lockKey,
+// waiting at most timeout (a Postgres interval such as "5s"). Waiting longer
+// fails with lock_not_available.
+func (q *Queries) LockIdempotencyKey(ctx context.Context, lockKey, timeout string) error {
+	_, err := q.db.Exec(ctx, lockIdempotencyKeyQuery, lockKey, timeout)
+	return err
+}
+
+const getIdempotencyRecordQuery = `
+	SELECT route, key, request_hash, status, content_type, body, expires_at
+	FROM idempotency_keys
+	WHERE route = $1 AND key = $2 AND expires_at > now()
+`
+
+// GetIdempotencyRecord returns the unexpired record stored for key on route.
+func (q *Queries) GetIdempotencyRecord(ctx context.Context, route, key string) (model.IdempotencyRecord, error) {
+	var r model.IdempotencyRecord
+	err := q.db.QueryRow(ctx, getIdempotencyRecordQuery, route, key).Scan(
+		&r.Route,
+		&r.Key,
+		&r.RequestHash,
+		&r.Status,
+		&r.ContentType,
+		&r.Body,
+		&r.ExpiresAt,
+	)
+	return r, err
+}
+
+const saveIdempotencyRecordQuery = `
+	INSERT INTO idempotency_keys (
+			route,
+			key,
+			request_hash,
+			status,
+			content_type,
+			body,
+			expires_at
+	)
+	VALUES ($1,$2,$3,$4,$5,$6,$7)
+	ON CONFLICT (route, key) DO UPDATE SET
+			request_hash = EXCLUDED.request_hash,
+			status       = EXCLUDED.status,
+			content_type = EXCLUDED.content_type,
+			body         = EXCLUDED.body,
+			created_at   = now(),
+			expires_at   = EXCLUDED.expires_at
+`
+
+// SaveIdempotencyRecord stores r, replacing an expired record for the same key.
+func (q *Queries) SaveIdempotencyRecord(ctx context.Context, r model.IdempotencyRecord) error {
+	_, err := q.db.Exec(ctx, saveIdempotencyRecordQuery,
+		r.Route,
+		r.Key,
+		r.RequestHash,
+		r.Status,
+		r.ContentType,
+		r.Body,
+		r.ExpiresAt,
+	)
+	return err
+}
+
+const completeIdempotencyRecordQuery = `
+	UPDATE idempotency_keys
+	SET
+			status       = $3,
+			content_type = $4,
+			body         = $5,
+			expires_at   = $6
+	WHERE route = $1 AND key = $2
+`
+
+// CompleteIdempotencyRecord stores the response of the pending record r.
+func (q *Queries) CompleteIdempotencyRecord(ctx context.Context, r model.IdempotencyRecord) error {
+	_, err := q.db.Exec(ctx, completeIdempotencyRecordQuery,
+		r.Route,
+		r.Key,
+		r.Status,
+		r.ContentType,
+		r.Body,
+		r.ExpiresAt,
+	)
+	return err
+}
+
+const deletePendingIdempotencyRecordQuery = `
+	DELETE FROM idempotency_keys
+	WHERE route = $1 AND key = $2 AND status = 0
+`
+
+// DeletePendingIdempotencyRecord frees key on route while its record is
+// still pending.
+func (q *Queries) DeletePendingIdempotencyRecord(ctx context.Context, route, key string) error {
+	_, err := q.db.Exec(ctx, deletePendingIdempotencyRecordQuery, route, key)
+	return err
+}
+
+const deleteExpiredIdempotencyRecordsQuery = `
+	DELETE FROM idempotency_keys
+	WHERE expires_at <= now()
+`
+
+func (q *Queries) DeleteExpiredIdempotencyRecords(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, deleteExpiredIdempotencyRecordsQuery)
+	return err
+}
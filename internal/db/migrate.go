@@ -26,6 +26,28 @@ type migration struct {
 
 var migrationList = []migration{
 	{1, migrations.Init001},
+	{2, migrations.SoftDelete002},
+	{3, migrations.ListIndex003},
+	{4, migrations.ServiceNameTrgm004},
+	{5, migrations.Services005},
+	{6, migrations.Users006},
+	{7, migrations.Transfers007},
+	{8, migrations.Version008},
+	{9, migrations.IdempotencyKeys009},
+	{10, migrations.SubscriptionAudit010},
+	{11, migrations.SubscriptionHistory011},
+	{12, migrations.SubscriptionPrices012},
+	{13, migrations.BillingInterval013},
+	{14, migrations.Currency014},
+	{15, migrations.SubscriptionStatus015},
+	{16, migrations.Trials016},
+	{17, migrations.CancelAt017},
+	{18, migrations.CalendarToken018},
+	{19, migrations.ServiceNames019},
+	{20, migrations.PriceBilling020},
+	{21, migrations.DropServicesLowerNameKey021},
+	{22, migrations.SubscriptionForeignKeys022},
+	{23, migrations.SubscriptionPaidFrom023},
 }
 
 func (s *Migrator) Run(ctx context.Context) error {
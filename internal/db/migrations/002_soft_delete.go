@@ -0,0 +1,18 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func SoftDelete002(tx pgx.Tx) error {
+	query := `ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS deleted_at timestamp;`
+
+	if _, err := tx.Exec(context.Background(), query); err != nil {
+		return err
+	}
+
+	return nil
+}
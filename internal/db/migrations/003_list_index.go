@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func ListIndex003(tx pgx.Tx) error {
+	query := `CREATE INDEX IF NOT EXISTS subscriptions_start_date_id_idx
+    ON subscriptions (start_date DESC, id DESC)
+    WHERE deleted_at IS NULL;`
+
+	if _, err := tx.Exec(context.Background(), query); err != nil {
+		return err
+	}
+
+	return nil
+}
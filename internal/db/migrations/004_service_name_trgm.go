@@ -0,0 +1,23 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func ServiceNameTrgm004(tx pgx.Tx) error {
+	queries := []string{
+		`CREATE EXTENSION IF NOT EXISTS pg_trgm;`,
+		`CREATE INDEX IF NOT EXISTS subscriptions_service_name_trgm_idx
+    ON subscriptions USING gin (lower(service_name) gin_trgm_ops);`,
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(context.Background(), query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
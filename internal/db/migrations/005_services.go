@@ -0,0 +1,58 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Services005 creates the services catalog and backfills it from the distinct
+// service names already in use. Spellings that only differ in case or
+// surrounding whitespace become one entry: the most used spelling is the
+// canonical name and the others become its aliases.
+func Services005(tx pgx.Tx) error {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS services(
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR NOT NULL,
+    aliases TEXT[] NOT NULL DEFAULT '{}',
+    category VARCHAR,
+    website VARCHAR,
+    default_price INTEGER,
+    created_at timestamp NOT NULL DEFAULT now()
+  );`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS services_lower_name_key
+    ON services (lower(name));`,
+		`ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS service_id BIGINT REFERENCES services(id) ON DELETE SET NULL;`,
+		`CREATE INDEX IF NOT EXISTS subscriptions_service_id_idx
+    ON subscriptions (service_id);`,
+		`WITH names AS (
+    SELECT btrim(service_name) AS name, count(*) AS uses
+    FROM subscriptions
+    GROUP BY btrim(service_name)
+  ), spellings AS (
+    SELECT array_agg(name ORDER BY uses DESC, name) AS names
+    FROM names
+    GROUP BY lower(name)
+  )
+  INSERT INTO services (name, aliases)
+  SELECT names[1], names[2:]
+  FROM spellings
+  ON CONFLICT DO NOTHING;`,
+		`UPDATE subscriptions s
+  SET service_id = sv.id,
+      service_name = sv.name
+  FROM services sv
+  WHERE s.service_id IS NULL
+    AND lower(btrim(s.service_name)) = lower(sv.name);`,
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(context.Background(), query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Users006 creates the users table, backfills it with every user id already
+// referenced by a subscription and makes subscriptions.user_id a foreign key.
+func Users006(tx pgx.Tx) error {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS users(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR,
+    email VARCHAR,
+    created_at timestamp NOT NULL DEFAULT now()
+  );`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS users_lower_email_key
+    ON users (lower(email));`,
+		`INSERT INTO users (id)
+  SELECT DISTINCT user_id FROM subscriptions
+  ON CONFLICT DO NOTHING;`,
+		`DO $$
+  BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'subscriptions_user_id_fkey') THEN
+      ALTER TABLE subscriptions
+        ADD CONSTRAINT subscriptions_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id);
+    END IF;
+  END $$;`,
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(context.Background(), query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
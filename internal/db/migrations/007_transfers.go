@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Transfers007 links a subscription created by an ownership transfer to the
+// subscription it continues.
+func Transfers007(tx pgx.Tx) error {
+	queries := []string{
+		`ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS transferred_from BIGINT REFERENCES subscriptions(id) ON DELETE SET NULL;`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS subscriptions_transferred_from_key
+    ON subscriptions (transferred_from);`,
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(context.Background(), query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
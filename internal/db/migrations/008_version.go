@@ -0,0 +1,20 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Version008 adds the row version used for optimistic concurrency. Every
+// update of a subscription increments it.
+func Version008(tx pgx.Tx) error {
+	query := `ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;`
+
+	if _, err := tx.Exec(context.Background(), query); err != nil {
+		return err
+	}
+
+	return nil
+}
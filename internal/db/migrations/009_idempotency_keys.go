@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// IdempotencyKeys009 stores the responses of requests sent with an
+// Idempotency-Key header so that retries can be answered without repeating
+// them.
+func IdempotencyKeys009(tx pgx.Tx) error {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS idempotency_keys(
+    route VARCHAR NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    request_hash VARCHAR NOT NULL,
+    status INTEGER NOT NULL,
+    content_type VARCHAR NOT NULL,
+    body BYTEA NOT NULL,
+    created_at timestamp NOT NULL DEFAULT now(),
+    expires_at timestamp NOT NULL,
+    PRIMARY KEY (route, key)
+  );`,
+		`CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx
+    ON idempotency_keys (expires_at);`,
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(context.Background(), query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SubscriptionAudit010 creates the audit log of subscription changes. It has
+// no foreign key so that entries outlive purged subscriptions.
+func SubscriptionAudit010(tx pgx.Tx) error {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS subscription_audit(
+    id BIGSERIAL PRIMARY KEY,
+    subscription_id BIGINT NOT NULL,
+    operation VARCHAR NOT NULL,
+    actor VARCHAR NOT NULL,
+    request_id VARCHAR,
+    before JSONB,
+    after JSONB,
+    created_at timestamp NOT NULL DEFAULT now()
+  );`,
+		`CREATE INDEX IF NOT EXISTS subscription_audit_subscription_id_idx
+    ON subscription_audit (subscription_id, id);`,
+		`CREATE INDEX IF NOT EXISTS subscription_audit_created_at_idx
+    ON subscription_audit (created_at);`,
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(context.Background(), query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
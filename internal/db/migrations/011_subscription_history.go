@@ -0,0 +1,52 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SubscriptionHistory011 creates the temporal history of subscriptions: one
+// row per stored state, valid in [valid_from, valid_to). Soft-deleted and
+// purged subscriptions have no open row. Subscriptions existing before the
+// history are backfilled as valid since -infinity, their state being the best
+// known for any earlier moment.
+func SubscriptionHistory011(tx pgx.Tx) error {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS subscription_history(
+    history_id BIGSERIAL PRIMARY KEY,
+    id BIGINT NOT NULL,
+    service_name VARCHAR NOT NULL,
+    price INTEGER NOT NULL,
+    user_id UUID NOT NULL,
+    start_date timestamp NOT NULL,
+    end_date timestamp,
+    service_id BIGINT,
+    transferred_from BIGINT,
+    version INTEGER NOT NULL,
+    valid_from timestamptz NOT NULL,
+    valid_to timestamptz
+  );`,
+		`CREATE INDEX IF NOT EXISTS subscription_history_id_idx
+    ON subscription_history (id, valid_from);`,
+		`CREATE INDEX IF NOT EXISTS subscription_history_validity_idx
+    ON subscription_history (valid_from, valid_to);`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS subscription_history_open_key
+    ON subscription_history (id) WHERE valid_to IS NULL;`,
+		`INSERT INTO subscription_history (
+      id, service_name, price, user_id, start_date, end_date, service_id, transferred_from, version, valid_from
+  )
+  SELECT id, service_name, price, user_id, start_date, end_date, service_id, transferred_from, version, '-infinity'
+  FROM subscriptions
+  WHERE deleted_at IS NULL
+    AND NOT EXISTS (SELECT 1 FROM subscription_history h WHERE h.id = subscriptions.id);`,
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(context.Background(), query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SubscriptionPrices012 creates the price history of subscriptions: each row
+// is the price from its effective month until the next row's. Replaced rows
+// are kept with removed_at set, so point-in-time reads see the prices as they
+// were recorded. Existing subscriptions start with their current price from
+// their start month; reads as of an earlier moment find no prices and fall
+// back to the subscription's price.
+func SubscriptionPrices012(tx pgx.Tx) error {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS subscription_prices(
+    id BIGSERIAL PRIMARY KEY,
+    subscription_id BIGINT NOT NULL,
+    price INTEGER NOT NULL,
+    effective_from timestamp NOT NULL,
+    recorded_at timestamptz NOT NULL DEFAULT now(),
+    removed_at timestamptz
+  );`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS subscription_prices_current_key
+    ON subscription_prices (subscription_id, effective_from) WHERE removed_at IS NULL;`,
+		`CREATE INDEX IF NOT EXISTS subscription_prices_subscription_idx
+    ON subscription_prices (subscription_id, recorded_at);`,
+		`INSERT INTO subscription_prices (subscription_id, price, effective_from)
+  SELECT id, price, date_trunc('month', start_date)
+  FROM subscriptions
+  WHERE NOT EXISTS (SELECT 1 FROM subscription_prices p WHERE p.subscription_id = subscriptions.id);`,
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(context.Background(), query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
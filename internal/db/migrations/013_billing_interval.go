@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// BillingInterval013 adds how often a subscription is billed: every
+// billing_count weeks, months, quarters or years, on billing_anchor_day (the
+// ISO weekday for weekly billing, the day of the month otherwise). Existing
+// subscriptions are billed monthly on the 1st. The history gets the same
+// columns.
+func BillingInterval013(tx pgx.Tx) error {
+	queries := []string{
+		`ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS billing_unit VARCHAR NOT NULL DEFAULT 'month',
+    ADD COLUMN IF NOT EXISTS billing_count INTEGER NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS billing_anchor_day SMALLINT NOT NULL DEFAULT 1;`,
+		`DO $$
+  BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'subscriptions_billing_check') THEN
+      ALTER TABLE subscriptions ADD CONSTRAINT subscriptions_billing_check CHECK (
+        billing_unit IN ('week', 'month', 'quarter', 'year')
+        AND billing_count > 0
+        AND billing_anchor_day BETWEEN 1 AND CASE WHEN billing_unit = 'week' THEN 7 ELSE 31 END
+      );
+    END IF;
+  END $$;`,
+		`ALTER TABLE subscription_history
+    ADD COLUMN IF NOT EXISTS billing_unit VARCHAR NOT NULL DEFAULT 'month',
+    ADD COLUMN IF NOT EXISTS billing_count INTEGER NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS billing_anchor_day SMALLINT NOT NULL DEFAULT 1;`,
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(context.Background(), query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
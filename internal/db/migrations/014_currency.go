@@ -0,0 +1,56 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Currency014 gives subscriptions and catalog default prices an ISO 4217
+// currency and moves amounts to minor units. Existing amounts were whole
+// rubles; they become kopeks, in columns widened to BIGINT first so that they
+// cannot overflow. Audit entries are converted too, and stored idempotent
+// responses, which hold the old amounts, are dropped. It also creates the
+// exchange rates used to convert between currencies: one unit of base is
+// worth rate units of quote on rate_date.
+func Currency014(tx pgx.Tx) error {
+	queries := []string{
+		`ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'RUB';`,
+		`ALTER TABLE subscription_history
+    ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'RUB';`,
+		`ALTER TABLE services
+    ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'RUB';`,
+		`ALTER TABLE subscriptions ALTER COLUMN price TYPE BIGINT;`,
+		`ALTER TABLE subscription_history ALTER COLUMN price TYPE BIGINT;`,
+		`ALTER TABLE subscription_prices ALTER COLUMN price TYPE BIGINT;`,
+		`ALTER TABLE services ALTER COLUMN default_price TYPE BIGINT;`,
+		`UPDATE subscriptions SET price = price * 100;`,
+		`UPDATE subscription_history SET price = price * 100;`,
+		`UPDATE subscription_prices SET price = price * 100;`,
+		`UPDATE services SET default_price = default_price * 100 WHERE default_price IS NOT NULL;`,
+		`UPDATE subscription_audit
+  SET before = CASE WHEN before ? 'price'
+        THEN before || jsonb_build_object('price', (before->>'price')::bigint * 100, 'currency', 'RUB')
+        ELSE before END,
+      after = CASE WHEN after ? 'price'
+        THEN after || jsonb_build_object('price', (after->>'price')::bigint * 100, 'currency', 'RUB')
+        ELSE after END;`,
+		`DELETE FROM idempotency_keys;`,
+		`CREATE TABLE IF NOT EXISTS exchange_rates(
+    rate_date DATE NOT NULL,
+    base CHAR(3) NOT NULL,
+    quote CHAR(3) NOT NULL,
+    rate NUMERIC(20, 10) NOT NULL CHECK (rate > 0),
+    PRIMARY KEY (base, quote, rate_date)
+  );`,
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(context.Background(), query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
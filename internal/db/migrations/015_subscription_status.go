@@ -0,0 +1,60 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SubscriptionStatus015 adds the lifecycle status of subscriptions and when
+// it last changed, the pauses that exclude months from spend, and the log of
+// status changes. Existing subscriptions are active; whether they have
+// expired follows from their end date when they are read. The history gets
+// the same columns.
+func SubscriptionStatus015(tx pgx.Tx) error {
+	queries := []string{
+		`ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS status VARCHAR NOT NULL DEFAULT 'active',
+    ADD COLUMN IF NOT EXISTS status_changed_at timestamptz NOT NULL DEFAULT now();`,
+		`DO $$
+  BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'subscriptions_status_check') THEN
+      ALTER TABLE subscriptions ADD CONSTRAINT subscriptions_status_check CHECK (
+        status IN ('trial', 'active', 'paused', 'pending_cancel', 'canceled', 'expired')
+      );
+    END IF;
+  END $$;`,
+		`ALTER TABLE subscription_history
+    ADD COLUMN IF NOT EXISTS status VARCHAR NOT NULL DEFAULT 'active',
+    ADD COLUMN IF NOT EXISTS status_changed_at timestamptz NOT NULL DEFAULT now();`,
+		`CREATE TABLE IF NOT EXISTS subscription_pauses(
+    id BIGSERIAL PRIMARY KEY,
+    subscription_id BIGINT NOT NULL,
+    paused_from timestamp NOT NULL,
+    resumed_from timestamp,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    resumed_at timestamptz
+  );`,
+		`CREATE INDEX IF NOT EXISTS subscription_pauses_subscription_idx
+    ON subscription_pauses (subscription_id, paused_from);`,
+		`CREATE TABLE IF NOT EXISTS subscription_status_changes(
+    id BIGSERIAL PRIMARY KEY,
+    subscription_id BIGINT NOT NULL,
+    action VARCHAR NOT NULL,
+    from_status VARCHAR NOT NULL,
+    to_status VARCHAR NOT NULL,
+    effective_month timestamp NOT NULL,
+    changed_at timestamptz NOT NULL DEFAULT now()
+  );`,
+		`CREATE INDEX IF NOT EXISTS subscription_status_changes_subscription_idx
+    ON subscription_status_changes (subscription_id, changed_at);`,
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(context.Background(), query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
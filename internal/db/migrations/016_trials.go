@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Trials016 adds the last day of a subscription's trial and when the trial
+// was flagged as about to convert to paid. The history gets the same columns.
+func Trials016(tx pgx.Tx) error {
+	queries := []string{
+		`ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS trial_end DATE,
+    ADD COLUMN IF NOT EXISTS trial_flagged_at timestamptz;`,
+		`CREATE INDEX IF NOT EXISTS subscriptions_trial_end_idx
+    ON subscriptions (trial_end) WHERE status = 'trial';`,
+		`ALTER TABLE subscription_history
+    ADD COLUMN IF NOT EXISTS trial_end DATE,
+    ADD COLUMN IF NOT EXISTS trial_flagged_at timestamptz;`,
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(context.Background(), query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
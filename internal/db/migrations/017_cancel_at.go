@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CancelAt017 adds the last day of the billing period a subscription was
+// canceled at the end of. The history gets the same column.
+func CancelAt017(tx pgx.Tx) error {
+	queries := []string{
+		`ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS cancel_at DATE;`,
+		`CREATE INDEX IF NOT EXISTS subscriptions_cancel_at_idx
+    ON subscriptions (cancel_at) WHERE cancel_at IS NOT NULL;`,
+		`ALTER TABLE subscription_history
+    ADD COLUMN IF NOT EXISTS cancel_at DATE;`,
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(context.Background(), query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
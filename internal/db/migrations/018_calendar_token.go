@@ -0,0 +1,24 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CalendarToken018 adds the SHA-256 hash of the secret token that grants
+// access to a user's renewals calendar feed.
+func CalendarToken018(tx pgx.Tx) error {
+	queries := []string{
+		`ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS calendar_token_hash BYTEA;`,
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(context.Background(), query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
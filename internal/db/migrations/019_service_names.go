@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ServiceNames019 adds service_names, which holds every service name and
+// alias in lower case under a primary key, so that no two services can claim
+// the same name even when they are written concurrently. Names the existing
+// catalog already shares stay with the service that has the lowest id.
+func ServiceNames019(tx pgx.Tx) error {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS service_names(
+    name TEXT PRIMARY KEY,
+    service_id BIGINT NOT NULL REFERENCES services(id) ON DELETE CASCADE
+  );`,
+		`CREATE INDEX IF NOT EXISTS service_names_service_id_idx
+    ON service_names (service_id);`,
+		`INSERT INTO service_names (name, service_id)
+  SELECT DISTINCT ON (lower(n)) lower(n), id
+  FROM services, unnest(array_prepend(name, aliases)) n
+  ORDER BY lower(n), id
+  ON CONFLICT DO NOTHING;`,
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(context.Background(), query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
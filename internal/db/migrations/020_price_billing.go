@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// PriceBilling020 records the billing interval each price is paid per, so
+// that a change of interval applies from its effective month like a change
+// of price. Existing prices take the current interval of their subscription,
+// or monthly billing on the 1st when it was purged.
+func PriceBilling020(tx pgx.Tx) error {
+	queries := []string{
+		`ALTER TABLE subscription_prices
+    ADD COLUMN IF NOT EXISTS billing_unit VARCHAR NOT NULL DEFAULT 'month',
+    ADD COLUMN IF NOT EXISTS billing_count INTEGER NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS billing_anchor_day INTEGER NOT NULL DEFAULT 1;`,
+		`UPDATE subscription_prices p
+  SET billing_unit = s.billing_unit,
+      billing_count = s.billing_count,
+      billing_anchor_day = s.billing_anchor_day
+  FROM subscriptions s
+  WHERE s.id = p.subscription_id;`,
+		`ALTER TABLE subscription_prices
+    ALTER COLUMN billing_unit DROP DEFAULT,
+    ALTER COLUMN billing_count DROP DEFAULT,
+    ALTER COLUMN billing_anchor_day DROP DEFAULT;`,
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(context.Background(), query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,24 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// DropServicesLowerNameKey021 drops the unique index on service names.
+// service_names already keeps names and aliases unique across services, and
+// lookups by name go through it.
+func DropServicesLowerNameKey021(tx pgx.Tx) error {
+	queries := []string{
+		`DROP INDEX IF EXISTS services_lower_name_key;`,
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(context.Background(), query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SubscriptionForeignKeys022 ties the prices, pauses and status changes of a
+// subscription to it, so that purging the subscription removes them too.
+// Rows left behind by earlier purges are deleted first.
+func SubscriptionForeignKeys022(tx pgx.Tx) error {
+	queries := []string{
+		`DELETE FROM subscription_prices t
+  WHERE NOT EXISTS (SELECT 1 FROM subscriptions s WHERE s.id = t.subscription_id);`,
+		`DELETE FROM subscription_pauses t
+  WHERE NOT EXISTS (SELECT 1 FROM subscriptions s WHERE s.id = t.subscription_id);`,
+		`DELETE FROM subscription_status_changes t
+  WHERE NOT EXISTS (SELECT 1 FROM subscriptions s WHERE s.id = t.subscription_id);`,
+		`DO $$
+  BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'subscription_prices_subscription_id_fkey') THEN
+      ALTER TABLE subscription_prices ADD CONSTRAINT subscription_prices_subscription_id_fkey
+        FOREIGN KEY (subscription_id) REFERENCES subscriptions(id) ON DELETE CASCADE;
+    END IF;
+  END $$;`,
+		`DO $$
+  BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'subscription_pauses_subscription_id_fkey') THEN
+      ALTER TABLE subscription_pauses ADD CONSTRAINT subscription_pauses_subscription_id_fkey
+        FOREIGN KEY (subscription_id) REFERENCES subscriptions(id) ON DELETE CASCADE;
+    END IF;
+  END $$;`,
+		`DO $$
+  BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'subscription_status_changes_subscription_id_fkey') THEN
+      ALTER TABLE subscription_status_changes ADD CONSTRAINT subscription_status_changes_subscription_id_fkey
+        FOREIGN KEY (subscription_id) REFERENCES subscriptions(id) ON DELETE CASCADE;
+    END IF;
+  END $$;`,
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(context.Background(), query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SubscriptionPaidFrom023 adds subscription_paid_from, the first month a
+// subscription pays its price after its trial, as Subscription.PaidFrom
+// computes it: the month of its first billing day after trial_end, or its
+// start month without a trial.
+func SubscriptionPaidFrom023(tx pgx.Tx) error {
+	queries := []string{
+		`CREATE OR REPLACE FUNCTION subscription_paid_from(
+    start_date timestamp,
+    trial_end date,
+    billing_unit varchar,
+    billing_count integer,
+    billing_anchor_day integer
+  ) RETURNS timestamp
+  LANGUAGE plpgsql IMMUTABLE AS $$
+  DECLARE
+    start_day date := start_date::date;
+    start_month timestamp := date_trunc('month', start_date);
+    first_day date;
+    step integer;
+    months integer;
+    k integer;
+    last_day integer;
+  BEGIN
+    IF trial_end IS NULL THEN
+      RETURN start_month;
+    END IF;
+
+    IF billing_unit = 'week' THEN
+      first_day := start_day + (billing_anchor_day - extract(isodow FROM start_day)::integer + 7) % 7;
+      step := 7 * billing_count;
+      IF trial_end >= first_day THEN
+        first_day := first_day + ((trial_end - first_day) / step + 1) * step;
+      END IF;
+      RETURN date_trunc('month', first_day::timestamp);
+    END IF;
+
+    step := billing_count * CASE billing_unit WHEN 'quarter' THEN 3 WHEN 'year' THEN 12 ELSE 1 END;
+    months := (extract(year FROM trial_end)::integer - extract(year FROM start_day)::integer) * 12
+      + extract(month FROM trial_end)::integer - extract(month FROM start_day)::integer;
+    k := greatest(months / step, 0) * step;
+    last_day := extract(day FROM date_trunc('month', trial_end::timestamp) + interval '1 month - 1 day')::integer;
+    IF months < 0 OR (k = months AND least(billing_anchor_day, last_day) > extract(day FROM trial_end)) THEN
+      RETURN start_month + make_interval(months => k);
+    END IF;
+    RETURN start_month + make_interval(months => k + step);
+  END $$;`,
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(context.Background(), query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
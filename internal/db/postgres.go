@@ -63,6 +63,15 @@ func (s *Store) ExecTx(ctx context.Context, fn func(*Queries) error) error {
 		return err
 	}
 
+	defer func() {
+		// A panic in fn must not leak the open transaction and its
+		// connection.
+		if p := recover(); p != nil {
+			_ = tx.Rollback(ctx)
+			panic(p)
+		}
+	}()
+
 	q := s.Queries.WithTx(tx)
 	err = fn(q)
 	if err != nil {
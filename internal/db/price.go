@@ -0,0 +1,207 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/morphlinkk/subscriptions/internal/model"
+)
+
+const addSubscriptionPriceQuery = `
+	INSERT INTO subscription_prices (
+			subscription_id,
+			price,
+			billing_unit,
+			billing_count,
+			billing_anchor_day,
+			effective_from
+	)
+	VALUES ($1, $2, $3, $4, $5, $6)
+`
+
+// AddSubscriptionPrice records price, paid per billing, as the price of
+// subscription id from effectiveFrom on. No current price may take effect in
+// the same month.
+func (q *Queries) AddSubscriptionPrice(ctx context.Context, id int64, price int, billing model.BillingInterval, effectiveFrom time.Time) error {
+	_, err := q.db.Exec(ctx, addSubscriptionPriceQuery,
+		id,
+		price,
+		billing.Unit,
+		billing.Count,
+		billing.AnchorDay,
+		effectiveFrom,
+	)
+	return err
+}
+
+const removeSubscriptionPricesFromQuery = `
+	UPDATE subscription_prices
+	SET removed_at = now()
+	WHERE subscription_id = $1 AND removed_at IS NULL AND effective_from >= $2
+`
+
+// RemoveSubscriptionPricesFrom removes the prices of subscription id that
+// take effect in month from or later. They stay visible to reads as of an
+// earlier moment.
+func (q *Queries) RemoveSubscriptionPricesFrom(ctx context.Context, id int64, from time.Time) error {
+	_, err := q.db.Exec(ctx, removeSubscriptionPricesFromQuery, id, from)
+	return err
+}
+
+const listSubscriptionPricesQuery = `
+	SELECT subscription_id, price, billing_unit, billing_count, billing_anchor_day, effective_from, recorded_at
+	FROM subscription_prices
+	WHERE subscription_id = ANY($1)
+		AND CASE
+			WHEN $2::timestamptz IS NULL THEN removed_at IS NULL
+			ELSE recorded_at <= $2 AND (removed_at IS NULL OR removed_at > $2)
+		END
+	ORDER BY subscription_id, effective_from
+`
+
+// ListSubscriptionPrices returns the price history of the subscriptions ids,
+// ordered by subscription and effective month, as recorded at asOf when set.
+func (q *Queries) ListSubscriptionPrices(ctx context.Context, ids []int64, asOf *time.Time) ([]model.SubscriptionPrice, error) {
+	rows, err := q.db.Query(ctx, listSubscriptionPricesQuery, ids, asOf)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prices []model.SubscriptionPrice
+
+	for rows.Next() {
+		var p model.SubscriptionPrice
+		err := rows.Scan(
+			&p.SubscriptionID,
+			&p.Price,
+			&p.Billing.Unit,
+			&p.Billing.Count,
+			&p.Billing.AnchorDay,
+			&p.EffectiveFrom,
+			&p.RecordedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		prices = append(prices, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return prices, nil
+}
+
+// subscriptionPaidFromExpr is the first paid month of subscription s, as
+// Subscription.PaidFrom returns it.
+const subscriptionPaidFromExpr = `subscription_paid_from(s.start_date, s.trial_end, s.billing_unit, s.billing_count, s.billing_anchor_day)`
+
+const listSubscriptionsForPriceChangeQuery = `
+	SELECT ` + subscriptionColumns + `
+	FROM subscriptions s
+	WHERE deleted_at IS NULL
+		AND service_name = $1
+		AND currency = $5
+		AND ($2::uuid IS NULL OR user_id = $2)
+		AND (end_date IS NULL OR end_date >= $3)
+		AND COALESCE((
+			SELECT p.price
+			FROM subscription_prices p
+			WHERE p.subscription_id = s.id
+				AND p.removed_at IS NULL
+				AND p.effective_from <= GREATEST(` + subscriptionPaidFromExpr + `, $3)
+			ORDER BY p.effective_from DESC
+			LIMIT 1
+		), s.price) = $4
+	ORDER BY id
+	FOR UPDATE
+`
+
+// ListSubscriptionsForPriceChange returns and locks the subscriptions to
+// service, of userID when set, that pay price in currency in month or, when
+// they start or end their trial later, in their first paid month. Their
+// status is not checked: paused subscriptions pay the new price once resumed,
+// and canceled or expired ones are left out by ending before month.
+func (q *Queries) ListSubscriptionsForPriceChange(ctx context.Context, service string, userID *uuid.UUID, month time.Time, price int, currency string) ([]model.Subscription, error) {
+	rows, err := q.db.Query(ctx, listSubscriptionsForPriceChangeQuery, service, userID, month, price, currency)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []model.Subscription
+
+	for rows.Next() {
+		var s model.Subscription
+		if err := rows.Scan(subscriptionFields(&s)...); err != nil {
+			return nil, err
+		}
+		subs = append(subs, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return subs, nil
+}
+
+const listSubscriptionsWithDueTermsQuery = `
+	SELECT ` + subscriptionColumns + `, t.price, t.billing_unit, t.billing_count, t.billing_anchor_day
+	FROM subscriptions s
+	JOIN LATERAL (
+		SELECT p.price, p.billing_unit, p.billing_count, p.billing_anchor_day
+		FROM subscription_prices p
+		WHERE p.subscription_id = s.id
+			AND p.removed_at IS NULL
+			AND p.effective_from <= GREATEST(` + subscriptionPaidFromExpr + `, $1)
+		ORDER BY p.effective_from DESC
+		LIMIT 1
+	) t ON true
+	WHERE s.deleted_at IS NULL
+		AND (t.price, t.billing_unit, t.billing_count, t.billing_anchor_day)
+			IS DISTINCT FROM (s.price, s.billing_unit, s.billing_count, s.billing_anchor_day)
+	ORDER BY s.id
+	FOR UPDATE OF s SKIP LOCKED
+`
+
+// ListSubscriptionsWithDueTerms returns and locks the subscriptions whose
+// price history has price or billing terms in month, or in their first paid
+// month when that is later, other than the ones they list, together with
+// those terms. Subscriptions locked by another transaction are skipped.
+func (q *Queries) ListSubscriptionsWithDueTerms(ctx context.Context, month time.Time) ([]model.Subscription, []model.SubscriptionPrice, error) {
+	rows, err := q.db.Query(ctx, listSubscriptionsWithDueTermsQuery, month)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var subs []model.Subscription
+	var terms []model.SubscriptionPrice
+
+	for rows.Next() {
+		var s model.Subscription
+		var t model.SubscriptionPrice
+		err := rows.Scan(append(subscriptionFields(&s),
+			&t.Price,
+			&t.Billing.Unit,
+			&t.Billing.Count,
+			&t.Billing.AnchorDay,
+		)...)
+		if err != nil {
+			return nil, nil, err
+		}
+		t.SubscriptionID = s.ID
+		subs = append(subs, s)
+		terms = append(terms, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return subs, terms, nil
+}
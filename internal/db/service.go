@@ -0,0 +1,258 @@
+package db
+
+import (
+	"context"
+
+	"github.com/morphlinkk/subscriptions/internal/model"
+)
+
+const serviceColumns = `id, name, aliases, category, website, default_price, currency, created_at`
+
+// serviceFields returns the scan destinations matching serviceColumns.
+func serviceFields(s *model.Service) []any {
+	return []any{
+		&s.ID,
+		&s.Name,
+		&s.Aliases,
+		&s.Category,
+		&s.Website,
+		&s.DefaultPrice,
+		&s.Currency,
+		&s.CreatedAt,
+	}
+}
+
+const getServiceByIdQuery = `
+	SELECT ` + serviceColumns + `
+	FROM services
+	WHERE id = $1
+`
+
+func (q *Queries) GetServiceById(ctx context.Context, id int64) (model.Service, error) {
+	var s model.Service
+	err := q.db.QueryRow(ctx, getServiceByIdQuery, id).Scan(serviceFields(&s)...)
+	return s, err
+}
+
+const findServiceByNameQuery = `
+	SELECT ` + serviceColumns + `
+	FROM services
+	WHERE id = (SELECT service_id FROM service_names WHERE name = lower(btrim($1)))
+`
+
+// FindServiceByName returns the service that claims name as its name or one
+// of its aliases, ignoring case and surrounding whitespace.
+func (q *Queries) FindServiceByName(ctx context.Context, name string) (model.Service, error) {
+	var s model.Service
+	err := q.db.QueryRow(ctx, findServiceByNameQuery, name).Scan(serviceFields(&s)...)
+	return s, err
+}
+
+const addServiceQuery = `
+	INSERT INTO services (
+			name,
+			aliases,
+			category,
+			website,
+			default_price,
+			currency
+	)
+	VALUES ($1,$2,$3,$4,$5,$6)
+	RETURNING ` + serviceColumns + `
+`
+
+func (q *Queries) AddService(ctx context.Context, params model.AddServiceParams) (model.Service, error) {
+	aliases := params.Aliases
+	if aliases == nil {
+		aliases = []string{}
+	}
+
+	var s model.Service
+	err := q.db.QueryRow(ctx, addServiceQuery,
+		params.Name,
+		aliases,
+		params.Category,
+		params.Website,
+		params.DefaultPrice,
+		params.Currency,
+	).Scan(serviceFields(&s)...)
+	return s, err
+}
+
+const updateServiceQuery = `
+	UPDATE services
+	SET
+			name          = COALESCE($1, name),
+			aliases       = COALESCE($2, aliases),
+			category      = CASE WHEN $3::boolean THEN $4 ELSE category END,
+			website       = CASE WHEN $5::boolean THEN $6 ELSE website END,
+			default_price = CASE WHEN $7::boolean THEN $8 ELSE default_price END,
+			currency      = COALESCE($9, currency)
+	WHERE id = $10
+	RETURNING ` + serviceColumns + `
+`
+
+func (q *Queries) UpdateService(ctx context.Context, id int64, params model.UpdateServiceParams) (model.Service, error) {
+	var s model.Service
+	err := q.db.QueryRow(ctx, updateServiceQuery,
+		params.Name,
+		params.Aliases,
+		params.Category.Set,
+		params.Category.Value,
+		params.Website.Set,
+		params.Website.Value,
+		params.DefaultPrice.Set,
+		params.DefaultPrice.Value,
+		params.Currency,
+		id,
+	).Scan(serviceFields(&s)...)
+	return s, err
+}
+
+const deleteServiceNamesQuery = `
+	DELETE FROM service_names
+	WHERE service_id = $1
+`
+
+const addServiceNamesQuery = `
+	INSERT INTO service_names (name, service_id)
+	SELECT DISTINCT lower(n), $1
+	FROM unnest($2::text[]) n
+`
+
+// SetServiceNames replaces the names claimed by service id with names,
+// compared case-insensitively. It fails with a unique violation when another
+// service already claims one of them.
+func (q *Queries) SetServiceNames(ctx context.Context, id int64, names []string) error {
+	if _, err := q.db.Exec(ctx, deleteServiceNamesQuery, id); err != nil {
+		return err
+	}
+	_, err := q.db.Exec(ctx, addServiceNamesQuery, id, names)
+	return err
+}
+
+const renameServiceSubscriptionsQuery = `
+	UPDATE subscriptions
+	SET service_name = $2, version = version + 1
+	FROM (
+		SELECT id AS old_id, service_name AS old_service_name
+		FROM subscriptions
+		WHERE service_id = $1 AND service_name <> $2
+		FOR UPDATE
+	) old
+	WHERE id = old_id
+	RETURNING ` + subscriptionColumns + `, old_service_name
+`
+
+// RenameServiceSubscriptions copies a new canonical service name onto the
+// subscriptions that reference the service. It returns the renamed
+// subscriptions together with their previous names.
+func (q *Queries) RenameServiceSubscriptions(ctx context.Context, id int64, name string) ([]model.Subscription, []string, error) {
+	rows, err := q.db.Query(ctx, renameServiceSubscriptionsQuery, id, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var subs []model.Subscription
+	var oldNames []string
+
+	for rows.Next() {
+		var s model.Subscription
+		var oldName string
+		if err := rows.Scan(append(subscriptionFields(&s), &oldName)...); err != nil {
+			return nil, nil, err
+		}
+		subs = append(subs, s)
+		oldNames = append(oldNames, oldName)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return subs, oldNames, nil
+}
+
+const listServiceSubscriptionsForUpdateQuery = `
+	SELECT ` + subscriptionColumns + `
+	FROM subscriptions
+	WHERE service_id = $1 AND deleted_at IS NULL
+	ORDER BY id
+	FOR UPDATE
+`
+
+// ListServiceSubscriptionsForUpdate returns the live subscriptions linked to
+// service id and locks them until the end of the transaction.
+func (q *Queries) ListServiceSubscriptionsForUpdate(ctx context.Context, id int64) ([]model.Subscription, error) {
+	rows, err := q.db.Query(ctx, listServiceSubscriptionsForUpdateQuery, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []model.Subscription
+
+	for rows.Next() {
+		var s model.Subscription
+		if err := rows.Scan(subscriptionFields(&s)...); err != nil {
+			return nil, err
+		}
+		subs = append(subs, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return subs, nil
+}
+
+const deleteServiceQuery = `
+	DELETE FROM services
+	WHERE id = $1
+	RETURNING id
+`
+
+// DeleteService removes a catalog entry. Soft-deleted subscriptions still
+// referencing it lose the reference through the foreign key.
+func (q *Queries) DeleteService(ctx context.Context, id int64) error {
+	var deleted int64
+	return q.db.QueryRow(ctx, deleteServiceQuery, id).Scan(&deleted)
+}
+
+const listServicesQuery = `
+	SELECT ` + serviceColumns + `
+	FROM services
+	WHERE ($1::text IS NULL OR category = $1)
+	ORDER BY name
+	LIMIT $2 OFFSET $3
+`
+
+func (q *Queries) ListServices(ctx context.Context, params model.ListServicesParams) ([]model.Service, error) {
+	rows, err := q.db.Query(ctx, listServicesQuery,
+		params.Category,
+		params.Limit,
+		params.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var services []model.Service
+
+	for rows.Next() {
+		var s model.Service
+		if err := rows.Scan(serviceFields(&s)...); err != nil {
+			return nil, err
+		}
+		services = append(services, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return services, nil
+}
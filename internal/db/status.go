@@ -0,0 +1,203 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/morphlinkk/subscriptions/internal/model"
+)
+
+const addSubscriptionPauseQuery = `
+	INSERT INTO subscription_pauses (subscription_id, paused_from, resumed_from, resumed_at)
+	VALUES ($1, $2, $3, CASE WHEN $3::timestamp IS NULL THEN NULL ELSE now() END)
+`
+
+// AddSubscriptionPause records p. A pause with ResumedFrom set is recorded as
+// already resumed.
+func (q *Queries) AddSubscriptionPause(ctx context.Context, p model.SubscriptionPause) error {
+	_, err := q.db.Exec(ctx, addSubscriptionPauseQuery, p.SubscriptionID, p.PausedFrom, p.ResumedFrom)
+	return err
+}
+
+const resumeSubscriptionPauseQuery = `
+	UPDATE subscription_pauses
+	SET resumed_from = $2, resumed_at = now()
+	WHERE subscription_id = $1 AND resumed_from IS NULL
+`
+
+// ResumeSubscriptionPause ends the open pause of subscription id, if any, so
+// that month from is billed again.
+func (q *Queries) ResumeSubscriptionPause(ctx context.Context, id int64, from time.Time) error {
+	_, err := q.db.Exec(ctx, resumeSubscriptionPauseQuery, id, from)
+	return err
+}
+
+const listSubscriptionPausesQuery = `
+	SELECT subscription_id, paused_from,
+		CASE WHEN $2::timestamptz IS NULL OR resumed_at <= $2 THEN resumed_from END
+	FROM subscription_pauses
+	WHERE subscription_id = ANY($1)
+		AND ($2::timestamptz IS NULL OR created_at <= $2)
+	ORDER BY subscription_id, paused_from
+`
+
+// ListSubscriptionPauses returns the pauses of the subscriptions ids, ordered
+// by subscription and first paused month, as recorded at asOf when set.
+func (q *Queries) ListSubscriptionPauses(ctx context.Context, ids []int64, asOf *time.Time) ([]model.SubscriptionPause, error) {
+	rows, err := q.db.Query(ctx, listSubscriptionPausesQuery, ids, asOf)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pauses []model.SubscriptionPause
+
+	for rows.Next() {
+		var p model.SubscriptionPause
+		if err := rows.Scan(&p.SubscriptionID, &p.PausedFrom, &p.ResumedFrom); err != nil {
+			return nil, err
+		}
+		pauses = append(pauses, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return pauses, nil
+}
+
+const addSubscriptionStatusChangeQuery = `
+	INSERT INTO subscription_status_changes (subscription_id, action, from_status, to_status, effective_month)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING changed_at
+`
+
+// AddSubscriptionStatusChange records c and returns it with the moment it
+// was recorded.
+func (q *Queries) AddSubscriptionStatusChange(ctx context.Context, c model.StatusChange) (model.StatusChange, error) {
+	err := q.db.QueryRow(ctx, addSubscriptionStatusChangeQuery,
+		c.SubscriptionID,
+		c.Action,
+		c.From,
+		c.To,
+		c.EffectiveMonth,
+	).Scan(&c.ChangedAt)
+	return c, err
+}
+
+const listSubscriptionStatusChangesQuery = `
+	SELECT subscription_id, action, from_status, to_status, effective_month, changed_at
+	FROM subscription_status_changes
+	WHERE subscription_id = $1
+	ORDER BY changed_at, id
+`
+
+// ListSubscriptionStatusChanges returns the status changes of subscription
+// id, oldest first.
+func (q *Queries) ListSubscriptionStatusChanges(ctx context.Context, id int64) ([]model.StatusChange, error) {
+	rows, err := q.db.Query(ctx, listSubscriptionStatusChangesQuery, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []model.StatusChange
+
+	for rows.Next() {
+		var c model.StatusChange
+		if err := rows.Scan(&c.SubscriptionID, &c.Action, &c.From, &c.To, &c.EffectiveMonth, &c.ChangedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+const listEndedTrialsForUpdateQuery = `
+	SELECT ` + subscriptionColumns + `
+	FROM subscriptions
+	WHERE status = 'trial' AND trial_end < $1 AND deleted_at IS NULL
+	ORDER BY id
+	FOR UPDATE SKIP LOCKED
+`
+
+// ListEndedTrialsForUpdate returns and locks the trials whose last day is
+// before today. Trials locked by another transaction are skipped.
+func (q *Queries) ListEndedTrialsForUpdate(ctx context.Context, today time.Time) ([]model.Subscription, error) {
+	rows, err := q.db.Query(ctx, listEndedTrialsForUpdateQuery, today)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []model.Subscription
+
+	for rows.Next() {
+		var s model.Subscription
+		if err := rows.Scan(subscriptionFields(&s)...); err != nil {
+			return nil, err
+		}
+		subs = append(subs, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return subs, nil
+}
+
+const listEndingTrialsForUpdateQuery = `
+	SELECT ` + subscriptionColumns + `
+	FROM subscriptions
+	WHERE status = 'trial' AND trial_end <= $1 AND trial_flagged_at IS NULL AND deleted_at IS NULL
+	ORDER BY id
+	FOR UPDATE SKIP LOCKED
+`
+
+// ListEndingTrialsForUpdate returns and locks the trials whose last day is
+// until or earlier and that were not flagged yet. Trials locked by another
+// transaction are skipped.
+func (q *Queries) ListEndingTrialsForUpdate(ctx context.Context, until time.Time) ([]model.Subscription, error) {
+	rows, err := q.db.Query(ctx, listEndingTrialsForUpdateQuery, until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []model.Subscription
+
+	for rows.Next() {
+		var s model.Subscription
+		if err := rows.Scan(subscriptionFields(&s)...); err != nil {
+			return nil, err
+		}
+		subs = append(subs, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return subs, nil
+}
+
+const flagTrialQuery = `
+	UPDATE subscriptions
+	SET trial_flagged_at = now(), version = version + 1
+	WHERE id = $1
+	RETURNING ` + subscriptionColumns + `
+`
+
+// FlagTrial marks the trial of subscription id as reported to end soon.
+func (q *Queries) FlagTrial(ctx context.Context, id int64) (model.Subscription, error) {
+	var s model.Subscription
+	err := q.db.QueryRow(ctx, flagTrialQuery, id).Scan(subscriptionFields(&s)...)
+	return s, err
+}
@@ -2,27 +2,86 @@ package db
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/morphlinkk/subscriptions/internal/model"
 )
 
-const getSubscriptionByIdQuery = `
-	SELECT id,service_name,price,user_id,start_date,end_date
-	FROM subscriptions 
-	WHERE id = $1
-`
+const subscriptionColumns = `id, service_name, price, user_id, start_date, end_date, service_id, transferred_from, version,
+	billing_unit, billing_count, billing_anchor_day, currency, ` + subscriptionStatusExpr + ` AS status, status_changed_at,
+	trial_end, trial_flagged_at, cancel_at`
 
-func (q *Queries) GetSubscriptionById(ctx context.Context, id int64) (model.Subscription, error) {
-	row := q.db.QueryRow(ctx, getSubscriptionByIdQuery, id)
-	var s model.Subscription
-	err := row.Scan(
+// subscriptionStatusExpr reads the stored status as of localtimestamp, which
+// subscriptionColumnsAt replaces to read it as of another moment. Whether a
+// canceled subscription is still pending follows from its end date, or the
+// day it cancels at, and a subscription whose end month has passed without
+// being canceled is expired.
+const subscriptionStatusExpr = `CASE
+		WHEN status IN ('pending_cancel', 'canceled') THEN CASE
+			WHEN end_date IS NULL THEN 'active'
+			WHEN cancel_at >= localtimestamp::date THEN 'pending_cancel'
+			WHEN cancel_at IS NOT NULL THEN 'canceled'
+			WHEN end_date > date_trunc('month', localtimestamp) THEN 'pending_cancel'
+			ELSE 'canceled'
+		END
+		WHEN end_date < date_trunc('month', localtimestamp) THEN 'expired'
+		WHEN status = 'expired' THEN 'active'
+		ELSE status
+	END`
+
+// subscriptionFields returns the scan destinations matching subscriptionColumns.
+func subscriptionFields(s *model.Subscription) []any {
+	return []any{
 		&s.ID,
 		&s.Service,
 		&s.Price,
 		&s.UserID,
 		&s.StartDate,
 		&s.EndDate,
-	)
+		&s.ServiceID,
+		&s.TransferredFrom,
+		&s.Version,
+		&s.Billing.Unit,
+		&s.Billing.Count,
+		&s.Billing.AnchorDay,
+		&s.Currency,
+		&s.Status,
+		&s.StatusChangedAt,
+		&s.TrialEnd,
+		&s.TrialFlaggedAt,
+		&s.CancelAt,
+	}
+}
+
+const getSubscriptionByIdQuery = `
+	SELECT ` + subscriptionColumns + `
+	FROM subscriptions
+	WHERE id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) GetSubscriptionById(ctx context.Context, id int64) (model.Subscription, error) {
+	row := q.db.QueryRow(ctx, getSubscriptionByIdQuery, id)
+	var s model.Subscription
+	err := row.Scan(subscriptionFields(&s)...)
+	return s, err
+}
+
+const getSubscriptionForUpdateQuery = `
+	SELECT ` + subscriptionColumns + `
+	FROM subscriptions
+	WHERE id = $1 AND deleted_at IS NULL
+	FOR UPDATE
+`
+
+// GetSubscriptionForUpdate returns an active subscription and locks it until
+// the end of the transaction.
+func (q *Queries) GetSubscriptionForUpdate(ctx context.Context, id int64) (model.Subscription, error) {
+	row := q.db.QueryRow(ctx, getSubscriptionForUpdateQuery, id)
+	var s model.Subscription
+	err := row.Scan(subscriptionFields(&s)...)
 	return s, err
 }
 
@@ -32,10 +91,18 @@ const addSubscriptionQuery = `
 			price,
 			user_id,
 			start_date,
-			end_date
+			end_date,
+			service_id,
+			transferred_from,
+			billing_unit,
+			billing_count,
+			billing_anchor_day,
+			currency,
+			status,
+			trial_end
 	)
-	VALUES ($1,$2,$3,$4,$5)
-	RETURNING id, service_name, price, user_id, start_date, end_date
+	VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)
+	RETURNING ` + subscriptionColumns + `
 `
 
 func (q *Queries) AddSubscription(ctx context.Context, sub model.AddSubscriptionParams) (model.Subscription, error) {
@@ -45,64 +112,168 @@ func (q *Queries) AddSubscription(ctx context.Context, sub model.AddSubscription
 		sub.UserID,
 		sub.StartDate,
 		sub.EndDate,
+		sub.ServiceID,
+		sub.TransferredFrom,
+		sub.Billing.Unit,
+		sub.Billing.Count,
+		sub.Billing.AnchorDay,
+		sub.Currency,
+		sub.Status,
+		sub.TrialEnd,
 	)
 	var s model.Subscription
-	err := row.Scan(
-		&s.ID,
-		&s.Service,
-		&s.Price,
-		&s.UserID,
-		&s.StartDate,
-		&s.EndDate,
-	)
+	err := row.Scan(subscriptionFields(&s)...)
 	return s, err
 }
 
 const updateSubscriptionQuery = `
 	UPDATE subscriptions
 	SET 
-			service_name = COALESCE($1, service_name),
-			price        = COALESCE($2, price),
-			end_date     = COALESCE($3, end_date)
-	WHERE id = $4
-	RETURNING id, service_name, price, user_id, start_date, end_date
+			service_name       = COALESCE($1, service_name),
+			price              = COALESCE($2, price),
+			end_date           = CASE WHEN $6::boolean THEN $3::timestamp ELSE end_date END,
+			cancel_at          = CASE WHEN $6::boolean THEN $12::date ELSE cancel_at END,
+			service_id         = CASE WHEN $1::varchar IS NULL THEN service_id ELSE $5 END,
+			billing_unit       = COALESCE($8, billing_unit),
+			billing_count      = COALESCE($9, billing_count),
+			billing_anchor_day = COALESCE($10, billing_anchor_day),
+			status             = COALESCE($11, status),
+			status_changed_at  = CASE WHEN $11::varchar IS NULL THEN status_changed_at ELSE now() END,
+			version            = version + 1
+	WHERE id = $4 AND deleted_at IS NULL
+		AND ($7::integer[] IS NULL OR version = ANY($7))
+	RETURNING ` + subscriptionColumns + `
 `
 
 func (q *Queries) UpdateSubscription(ctx context.Context, id int64, params model.UpdateSubscriptionParams) (model.Subscription, error) {
 	row := q.db.QueryRow(ctx, updateSubscriptionQuery,
 		params.Service,
 		params.Price,
-		params.EndDate,
+		params.EndDate.Value,
 		id,
+		params.ServiceID,
+		params.EndDate.Set,
+		params.ExpectedVersions,
+		params.BillingUnit,
+		params.BillingCount,
+		params.BillingAnchorDay,
+		params.Status,
+		params.CancelAt,
 	)
 
 	var s model.Subscription
-	err := row.Scan(
-		&s.ID,
-		&s.Service,
-		&s.Price,
-		&s.UserID,
-		&s.StartDate,
-		&s.EndDate,
-	)
+	err := row.Scan(subscriptionFields(&s)...)
 
 	return s, err
 }
 
-const listSubscriptionsPaginatedQuery = `
-	SELECT id, service_name, price, user_id, start_date, end_date
-	FROM subscriptions
-	WHERE (user_id = $1 OR $1 IS NULL)
-	ORDER BY start_date DESC
-	LIMIT $2 OFFSET $3
+const softDeleteSubscriptionQuery = `
+	UPDATE subscriptions
+	SET deleted_at = now(), version = version + 1
+	WHERE id = $1 AND deleted_at IS NULL
+	RETURNING ` + subscriptionColumns + `
+`
+
+func (q *Queries) SoftDeleteSubscription(ctx context.Context, id int64) (model.Subscription, error) {
+	row := q.db.QueryRow(ctx, softDeleteSubscriptionQuery, id)
+
+	var s model.Subscription
+	err := row.Scan(subscriptionFields(&s)...)
+
+	return s, err
+}
+
+const restoreSubscriptionQuery = `
+	UPDATE subscriptions
+	SET deleted_at = NULL, version = version + 1
+	WHERE id = $1 AND deleted_at IS NOT NULL
+	RETURNING ` + subscriptionColumns + `
+`
+
+func (q *Queries) RestoreSubscription(ctx context.Context, id int64) (model.Subscription, error) {
+	row := q.db.QueryRow(ctx, restoreSubscriptionQuery, id)
+
+	var s model.Subscription
+	err := row.Scan(subscriptionFields(&s)...)
+
+	return s, err
+}
+
+const closeSubscriptionForTransferQuery = `
+	UPDATE subscriptions
+	SET end_date = $2, version = version + 1
+	FROM (
+		SELECT id AS old_id, end_date AS old_end_date
+		FROM subscriptions
+		WHERE id = $1
+		FOR UPDATE
+	) old
+	WHERE id = old_id
+		AND deleted_at IS NULL
+		AND user_id <> $3
+		AND status = 'active'
+		AND start_date <= $2
+		AND (end_date IS NULL OR end_date > $2)
+	RETURNING ` + subscriptionColumns + `, old_end_date
+`
+
+// CloseSubscriptionForTransfer ends subscription id at lastMonth, the last
+// month of its current owner, and returns it with its end date from before
+// the update. It matches no row unless id is not deleted, has the active
+// status, is not owned by newOwner and runs past lastMonth.
+func (q *Queries) CloseSubscriptionForTransfer(ctx context.Context, id int64, lastMonth time.Time, newOwner uuid.UUID) (model.Subscription, *time.Time, error) {
+	var s model.Subscription
+	var oldEnd *time.Time
+	err := q.db.QueryRow(ctx, closeSubscriptionForTransferQuery, id, lastMonth, newOwner).
+		Scan(append(subscriptionFields(&s), &oldEnd)...)
+	return s, oldEnd, err
+}
+
+const purgeSubscriptionQuery = `
+	DELETE FROM subscriptions
+	WHERE id = $1
+	RETURNING ` + subscriptionColumns + `
 `
 
+// PurgeSubscription permanently removes a subscription, whether it was
+// soft-deleted or not, and returns its last state. Its prices, pauses and
+// status changes go with it.
+func (q *Queries) PurgeSubscription(ctx context.Context, id int64) (model.Subscription, error) {
+	var s model.Subscription
+	err := q.db.QueryRow(ctx, purgeSubscriptionQuery, id).Scan(subscriptionFields(&s)...)
+	return s, err
+}
+
+// ListSubscriptions returns up to params.Limit subscriptions matching the
+// filters of params in params.Sort order, starting after params.After when set.
 func (q *Queries) ListSubscriptions(ctx context.Context, params model.ListSubscriptionsParams) ([]model.Subscription, error) {
-	rows, err := q.db.Query(ctx, listSubscriptionsPaginatedQuery,
-		params.UserID,
-		params.Limit,
-		params.Offset,
-	)
+	b := &queryBuilder{}
+	source, columns := subscriptionSourceFor(b, params.AsOf)
+	addSubscriptionFilters(b, params)
+
+	keys, err := resolveSort(params.Sort, b.search)
+	if err != nil {
+		return nil, err
+	}
+	if params.After != nil {
+		if len(params.After.Values) != len(keys) {
+			return nil, fmt.Errorf("cursor has %d values, sort needs %d", len(params.After.Values), len(keys))
+		}
+		b.where(keysetCondition(b, keys, params.After.Values))
+	}
+
+	relevance := "NULL::real"
+	if b.search != "" {
+		relevance = withSearch(searchRankExpr, b.search)
+	}
+
+	query := `
+	SELECT ` + columns + `, ` + relevance + `
+	FROM ` + source + b.whereClause() + `
+	ORDER BY ` + orderByClause(keys) + `
+	LIMIT ` + b.arg(params.Limit) + ` OFFSET ` + b.arg(params.Offset)
+
+	rows, err := q.db.Query(ctx, query, b.args...)
 	if err != nil {
 		return nil, err
 	}
@@ -112,14 +283,7 @@ func (q *Queries) ListSubscriptions(ctx context.Context, params model.ListSubscr
 
 	for rows.Next() {
 		var s model.Subscription
-		if err := rows.Scan(
-			&s.ID,
-			&s.Service,
-			&s.Price,
-			&s.UserID,
-			&s.StartDate,
-			&s.EndDate,
-		); err != nil {
+		if err := rows.Scan(append(subscriptionFields(&s), &s.Relevance)...); err != nil {
 			return nil, err
 		}
 		subs = append(subs, s)
@@ -132,25 +296,109 @@ func (q *Queries) ListSubscriptions(ctx context.Context, params model.ListSubscr
 	return subs, nil
 }
 
-const SumOfSubscriptionPricesQuery = `
-	SELECT 
-			COALESCE(SUM(price), 0) AS total_price
-	FROM subscriptions
+// CountSubscriptions returns the number of rows matching the filters of
+// params, ignoring its sorting and pagination fields.
+func (q *Queries) CountSubscriptions(ctx context.Context, params model.ListSubscriptionsParams) (int64, error) {
+	b := &queryBuilder{}
+	source, _ := subscriptionSourceFor(b, params.AsOf)
+	addSubscriptionFilters(b, params)
+
+	var total int64
+	err := q.db.QueryRow(ctx, `SELECT count(*) FROM `+source+b.whereClause(), b.args...).Scan(&total)
+	return total, err
+}
+
+const listSubscriptionsInPeriodQuery = `
+	SELECT %s
+	FROM %s
 	WHERE ($1::uuid IS NULL OR user_id = $1)
 		AND ($2::text IS NULL OR service_name = $2)
-		AND start_date >= $3
-		AND (end_date IS NULL OR end_date <= $4)
+		AND start_date <= $4
+		AND (end_date IS NULL OR end_date >= $3)
+		AND deleted_at IS NULL
+	ORDER BY start_date, id
 `
 
-func (q *Queries) SumOfSubscriptionPrices(ctx context.Context, params model.SumOfSubscriptionPricesParams) (int64, error) {
-	row := q.db.QueryRow(ctx, SumOfSubscriptionPricesQuery,
+// ListSubscriptionsInPeriod returns subscriptions that are active in at least
+// one month of [PeriodStart, PeriodEnd], both months inclusive, as stored at
+// params.AsOf when set.
+func (q *Queries) ListSubscriptionsInPeriod(ctx context.Context, params model.SumOfSubscriptionPricesParams) ([]model.Subscription, error) {
+	args := []any{
 		params.UserID,
 		params.ServiceName,
 		params.PeriodStart,
 		params.PeriodEnd,
+	}
+	asOf := ""
+	if params.AsOf != nil {
+		args = append(args, *params.AsOf)
+		asOf = "$5"
+	}
+	query := fmt.Sprintf(listSubscriptionsInPeriodQuery, subscriptionColumnsAt(asOf), subscriptionSource(asOf))
+
+	rows, err := q.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []model.Subscription
+
+	for rows.Next() {
+		var s model.Subscription
+		if err := rows.Scan(subscriptionFields(&s)...); err != nil {
+			return nil, err
+		}
+		subs = append(subs, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return subs, nil
+}
+
+const suggestServiceNamesQuery = `
+	SELECT
+			service_name,
+			count(*) AS subscriptions,
+			GREATEST(similarity(lower(service_name), $1), word_similarity($1, lower(service_name))) AS score
+	FROM subscriptions
+	WHERE deleted_at IS NULL
+		AND (lower(service_name) % $1 OR $1 <% lower(service_name) OR lower(service_name) LIKE $2 ESCAPE '\')
+	GROUP BY service_name
+	ORDER BY score DESC, subscriptions DESC, service_name
+	LIMIT $3
+`
+
+// SuggestServiceNames returns distinct service names that fuzzy-match or
+// start with params.Query, best matches first.
+func (q *Queries) SuggestServiceNames(ctx context.Context, params model.SuggestServicesParams) ([]model.ServiceSuggestion, error) {
+	query := strings.ToLower(strings.TrimSpace(params.Query))
+	rows, err := q.db.Query(ctx, suggestServiceNamesQuery,
+		query,
+		escapeLike(query)+"%",
+		params.Limit,
 	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var suggestions []model.ServiceSuggestion
+
+	for rows.Next() {
+		var s model.ServiceSuggestion
+		if err := rows.Scan(&s.Name, &s.Subscriptions, &s.Score); err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	var totalPrice int64
-	err := row.Scan(&totalPrice)
-	return totalPrice, err
+	return suggestions, nil
 }
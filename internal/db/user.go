@@ -0,0 +1,145 @@
+package db
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/morphlinkk/subscriptions/internal/model"
+)
+
+const userColumns = `id, name, email, created_at`
+
+// userFields returns the scan destinations matching userColumns.
+func userFields(u *model.User) []any {
+	return []any{
+		&u.ID,
+		&u.Name,
+		&u.Email,
+		&u.CreatedAt,
+	}
+}
+
+const getUserByIdQuery = `
+	SELECT ` + userColumns + `
+	FROM users
+	WHERE id = $1
+`
+
+func (q *Queries) GetUserById(ctx context.Context, id uuid.UUID) (model.User, error) {
+	var u model.User
+	err := q.db.QueryRow(ctx, getUserByIdQuery, id).Scan(userFields(&u)...)
+	return u, err
+}
+
+const addUserQuery = `
+	INSERT INTO users (
+			id,
+			name,
+			email
+	)
+	VALUES (COALESCE($1, gen_random_uuid()),$2,$3)
+	RETURNING ` + userColumns + `
+`
+
+func (q *Queries) AddUser(ctx context.Context, params model.AddUserParams) (model.User, error) {
+	var u model.User
+	err := q.db.QueryRow(ctx, addUserQuery,
+		params.ID,
+		params.Name,
+		params.Email,
+	).Scan(userFields(&u)...)
+	return u, err
+}
+
+const updateUserQuery = `
+	UPDATE users
+	SET
+			name  = COALESCE($1, name),
+			email = COALESCE($2, email)
+	WHERE id = $3
+	RETURNING ` + userColumns + `
+`
+
+func (q *Queries) UpdateUser(ctx context.Context, id uuid.UUID, params model.UpdateUserParams) (model.User, error) {
+	var u model.User
+	err := q.db.QueryRow(ctx, updateUserQuery,
+		params.Name,
+		params.Email,
+		id,
+	).Scan(userFields(&u)...)
+	return u, err
+}
+
+const deleteUserQuery = `
+	DELETE FROM users
+	WHERE id = $1
+	RETURNING id
+`
+
+// DeleteUser removes a user. It fails with a foreign key violation while
+// subscriptions, including soft-deleted ones, still reference the user.
+func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	var deleted uuid.UUID
+	return q.db.QueryRow(ctx, deleteUserQuery, id).Scan(&deleted)
+}
+
+const listUsersQuery = `
+	SELECT ` + userColumns + `
+	FROM users
+	ORDER BY created_at, id
+	LIMIT $1 OFFSET $2
+`
+
+func (q *Queries) ListUsers(ctx context.Context, params model.ListUsersParams) ([]model.User, error) {
+	rows, err := q.db.Query(ctx, listUsersQuery,
+		params.Limit,
+		params.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []model.User
+
+	for rows.Next() {
+		var u model.User
+		if err := rows.Scan(userFields(&u)...); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+const setUserCalendarTokenQuery = `
+	UPDATE users
+	SET calendar_token_hash = $2
+	WHERE id = $1
+	RETURNING id
+`
+
+// SetUserCalendarToken replaces the calendar token hash of user id.
+func (q *Queries) SetUserCalendarToken(ctx context.Context, id uuid.UUID, hash []byte) error {
+	var updated uuid.UUID
+	return q.db.QueryRow(ctx, setUserCalendarTokenQuery, id, hash).Scan(&updated)
+}
+
+const getUserCalendarTokenQuery = `
+	SELECT calendar_token_hash
+	FROM users
+	WHERE id = $1
+`
+
+// GetUserCalendarToken returns the calendar token hash of user id, nil when
+// no token was issued.
+func (q *Queries) GetUserCalendarToken(ctx context.Context, id uuid.UUID) ([]byte, error) {
+	var hash []byte
+	err := q.db.QueryRow(ctx, getUserCalendarTokenQuery, id).Scan(&hash)
+	return hash, err
+}
@@ -0,0 +1,133 @@
+// Package ical writes RFC 5545 iCalendar data with all-day events.
+package ical
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ContentType is the media type of iCalendar data.
+const ContentType = "text/calendar; charset=utf-8"
+
+const (
+	dateLayout  = "20060102"
+	stampLayout = "20060102T150405Z"
+	// maxLineOctets is the longest a content line may be before it is folded.
+	maxLineOctets = 75
+)
+
+type Calendar struct {
+	ProdID string
+	// Name is shown by calendar apps that support X-WR-CALNAME.
+	Name   string
+	Events []Event
+}
+
+// Event is an all-day event on Start, recurring by RRule when set, except on
+// ExDates.
+type Event struct {
+	// UID identifies the event across updates of the calendar, and Sequence
+	// tells its revisions apart.
+	UID         string
+	Sequence    int
+	Stamp       time.Time
+	Start       time.Time
+	Summary     string
+	Description string
+	RRule       string
+	ExDates     []time.Time
+	Alarms      []Alarm
+}
+
+// Alarm displays Description at Trigger, an RFC 5545 duration relative to the
+// start of the event such as -P1D.
+type Alarm struct {
+	Trigger     string
+	Description string
+}
+
+// Marshal returns c as iCalendar data.
+func (c Calendar) Marshal() []byte {
+	var w writer
+	w.line("BEGIN", "VCALENDAR")
+	w.line("VERSION", "2.0")
+	w.line("PRODID", c.ProdID)
+	w.line("CALSCALE", "GREGORIAN")
+	w.line("METHOD", "PUBLISH")
+	if c.Name != "" {
+		w.line("X-WR-CALNAME", escape(c.Name))
+	}
+	for _, e := range c.Events {
+		e.write(&w)
+	}
+	w.line("END", "VCALENDAR")
+	return []byte(w.String())
+}
+
+func (e Event) write(w *writer) {
+	w.line("BEGIN", "VEVENT")
+	w.line("UID", escape(e.UID))
+	w.line("SEQUENCE", strconv.Itoa(e.Sequence))
+	w.line("DTSTAMP", e.Stamp.UTC().Format(stampLayout))
+	w.line("DTSTART;VALUE=DATE", e.Start.Format(dateLayout))
+	w.line("DTEND;VALUE=DATE", e.Start.AddDate(0, 0, 1).Format(dateLayout))
+	if e.RRule != "" {
+		w.line("RRULE", e.RRule)
+	}
+	if len(e.ExDates) > 0 {
+		dates := make([]string, len(e.ExDates))
+		for i, d := range e.ExDates {
+			dates[i] = d.Format(dateLayout)
+		}
+		w.line("EXDATE;VALUE=DATE", strings.Join(dates, ","))
+	}
+	w.line("SUMMARY", escape(e.Summary))
+	if e.Description != "" {
+		w.line("DESCRIPTION", escape(e.Description))
+	}
+	w.line("TRANSP", "TRANSPARENT")
+	for _, a := range e.Alarms {
+		w.line("BEGIN", "VALARM")
+		w.line("ACTION", "DISPLAY")
+		w.line("TRIGGER", a.Trigger)
+		w.line("DESCRIPTION", escape(a.Description))
+		w.line("END", "VALARM")
+	}
+	w.line("END", "VEVENT")
+}
+
+// Date returns the UNTIL value of an RRULE ending on the all-day date t.
+func Date(t time.Time) string {
+	return t.Format(dateLayout)
+}
+
+type writer struct {
+	strings.Builder
+}
+
+// line writes a content line, folded after maxLineOctets octets without
+// splitting a UTF-8 sequence.
+func (w *writer) line(name, value string) {
+	s := name + ":" + value
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		// The leading space of a continuation line counts towards its length.
+		limit = maxLineOctets - 1
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
+
+// escape escapes a TEXT value.
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
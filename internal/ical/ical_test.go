@@ -0,0 +1,85 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestWriterLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{
+			name:  "short line",
+			value: "FREQ=MONTHLY;INTERVAL=1;BYMONTHDAY=15",
+			want:  "RRULE:FREQ=MONTHLY;INTERVAL=1;BYMONTHDAY=15\r\n",
+		},
+		{
+			name:  "exactly 75 octets",
+			value: strings.Repeat("a", 69),
+			want:  "RRULE:" + strings.Repeat("a", 69) + "\r\n",
+		},
+		{
+			name:  "76 octets",
+			value: strings.Repeat("a", 70),
+			want:  "RRULE:" + strings.Repeat("a", 69) + "\r\n a\r\n",
+		},
+		{
+			name:  "continuation lines hold 74 octets",
+			value: strings.Repeat("a", 69+74+1),
+			want:  "RRULE:" + strings.Repeat("a", 69) + "\r\n " + strings.Repeat("a", 74) + "\r\n a\r\n",
+		},
+		{
+			name:  "multi-byte rune is not split",
+			value: strings.Repeat("a", 68) + "é",
+			want:  "RRULE:" + strings.Repeat("a", 68) + "\r\n é\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var w writer
+			w.line("RRULE", tt.value)
+			got := w.String()
+			if got != tt.want {
+				t.Errorf("line() = %q, want %q", got, tt.want)
+			}
+			for _, l := range strings.Split(strings.TrimSuffix(got, "\r\n"), "\r\n") {
+				if len(l) > maxLineOctets {
+					t.Errorf("line %q is %d octets long", l, len(l))
+				}
+				if !utf8.ValidString(l) {
+					t.Errorf("line %q splits a UTF-8 sequence", l)
+				}
+			}
+			if unfolded := strings.ReplaceAll(got, "\r\n ", ""); unfolded != "RRULE:"+tt.value+"\r\n" {
+				t.Errorf("unfolded line = %q, want %q", unfolded, "RRULE:"+tt.value+"\r\n")
+			}
+		})
+	}
+}
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Netflix renewal", "Netflix renewal"},
+		{"Yandex Plus; family", `Yandex Plus\; family`},
+		{"799.00 RUB, monthly", `799.00 RUB\, monthly`},
+		{`C:\path`, `C:\\path`},
+		{"one\ntwo", `one\ntwo`},
+		{"one\r\ntwo", `one\ntwo`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := escape(tt.in); got != tt.want {
+				t.Errorf("escape(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+// Package jobs runs background work of the API on a fixed interval.
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Every runs job right away and then once per interval until ctx is done.
+// A failed run is logged and retried on the next tick.
+func Every(ctx context.Context, name string, interval time.Duration, job func(context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		slog.Debug("Running job", "job", name)
+		if err := job(ctx); err != nil {
+			slog.Error("Job failed", "job", name, "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
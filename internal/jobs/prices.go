@@ -0,0 +1,33 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/morphlinkk/subscriptions/internal/reqctx"
+	"github.com/morphlinkk/subscriptions/internal/server/service"
+)
+
+// PricesActor is the audit actor of the changes made by the price job.
+const PricesActor = "job:prices"
+
+// Prices returns a job that lists on each subscription the price and billing
+// interval recorded for the current month, so that changes scheduled for a
+// later month show once it comes.
+func Prices(subs service.SubscriptionService) func(context.Context) error {
+	return func(ctx context.Context) error {
+		ctx = reqctx.WithActor(ctx, PricesActor)
+
+		changed, err := subs.ApplyDueTerms(ctx, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		for _, sub := range changed {
+			slog.Info("Applied scheduled price",
+				"subscription_id", sub.ID, "price", sub.Price, "currency", sub.Currency,
+				"billing_interval", sub.Billing.Unit, "billing_interval_count", sub.Billing.Count)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,43 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/morphlinkk/subscriptions/internal/reqctx"
+	"github.com/morphlinkk/subscriptions/internal/server/service"
+)
+
+// TrialActor is the audit actor of the changes made by the trial job.
+const TrialActor = "job:trials"
+
+// Trials returns a job that converts the trials that ended before today to
+// paid subscriptions and flags the trials that end within reminderDays.
+func Trials(subs service.SubscriptionService, reminderDays int) func(context.Context) error {
+	return func(ctx context.Context) error {
+		ctx = reqctx.WithActor(ctx, TrialActor)
+		now := time.Now().UTC()
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+		converted, err := subs.ConvertEndedTrials(ctx, today)
+		if err != nil {
+			return err
+		}
+		for _, sub := range converted {
+			slog.Info("Converted trial to paid subscription",
+				"subscription_id", sub.ID, "user_id", sub.UserID, "service_name", sub.Service, "trial_end", sub.TrialEnd.Format(time.DateOnly))
+		}
+
+		flagged, err := subs.FlagEndingTrials(ctx, today.AddDate(0, 0, reminderDays))
+		if err != nil {
+			return err
+		}
+		for _, sub := range flagged {
+			slog.Info("Trial ends soon",
+				"subscription_id", sub.ID, "user_id", sub.UserID, "service_name", sub.Service,
+				"trial_end", sub.TrialEnd.Format(time.DateOnly), "price", sub.Price, "currency", sub.Currency)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditOperation is the kind of change an audit entry records.
+type AuditOperation string
+
+const (
+	AuditCreate      AuditOperation = "create"
+	AuditUpdate      AuditOperation = "update"
+	AuditDelete      AuditOperation = "delete"
+	AuditRestore     AuditOperation = "restore"
+	AuditPurge       AuditOperation = "purge"
+	AuditTransferOut AuditOperation = "transfer_out"
+	AuditTransferIn  AuditOperation = "transfer_in"
+)
+
+func IsAuditOperation(op string) bool {
+	switch AuditOperation(op) {
+	case AuditCreate, AuditUpdate, AuditDelete, AuditRestore, AuditPurge, AuditTransferOut, AuditTransferIn:
+		return true
+	}
+	return false
+}
+
+// SubscriptionAuditEntry records one change of a subscription. Before is
+// null for creations and After for deletions.
+type SubscriptionAuditEntry struct {
+	ID             int64
+	SubscriptionID int64
+	Operation      AuditOperation
+	Actor          string
+	RequestID      *string
+	Before         json.RawMessage
+	After          json.RawMessage
+	CreatedAt      time.Time
+}
+
+type AddSubscriptionAuditParams struct {
+	SubscriptionID int64
+	Operation      AuditOperation
+	Actor          string
+	RequestID      *string
+	Before         json.RawMessage
+	After          json.RawMessage
+}
+
+type ListAuditParams struct {
+	SubscriptionID *int64
+	Actor          *string
+	Operation      *AuditOperation
+	RequestID      *string
+	From           *time.Time
+	To             *time.Time
+	Limit          int
+	Offset         int
+}
@@ -0,0 +1,122 @@
+package model
+
+import "time"
+
+// BillingUnit is the calendar unit a subscription is billed in.
+type BillingUnit string
+
+const (
+	BillingWeek    BillingUnit = "week"
+	BillingMonth   BillingUnit = "month"
+	BillingQuarter BillingUnit = "quarter"
+	BillingYear    BillingUnit = "year"
+)
+
+// weeksPerMonth is the average number of weeks in a month over a Julian year.
+const weeksPerMonth = 365.25 / 7 / 12
+
+func IsBillingUnit(u BillingUnit) bool {
+	switch u {
+	case BillingWeek, BillingMonth, BillingQuarter, BillingYear:
+		return true
+	}
+	return false
+}
+
+// BillingInterval bills a subscription every Count units. AnchorDay is the
+// ISO weekday (1 is Monday) the bill falls on for weekly billing and the day
+// of the month otherwise, the last day in months that are shorter.
+type BillingInterval struct {
+	Unit      BillingUnit
+	Count     int
+	AnchorDay int
+}
+
+// MonthlyBilling is the interval of subscriptions that do not specify one.
+var MonthlyBilling = BillingInterval{Unit: BillingMonth, Count: 1, AnchorDay: 1}
+
+// Months returns the length of the interval in months, fractional for weeks.
+func (b BillingInterval) Months() float64 {
+	n := float64(b.Count)
+	switch b.Unit {
+	case BillingWeek:
+		return n / weeksPerMonth
+	case BillingQuarter:
+		return n * 3
+	case BillingYear:
+		return n * 12
+	default:
+		return n
+	}
+}
+
+// MonthlyCost normalizes price, paid once per interval, to a month.
+func (b BillingInterval) MonthlyCost(price int) float64 {
+	return float64(price) / b.Months()
+}
+
+// AnnualCost normalizes price, paid once per interval, to a year.
+func (b BillingInterval) AnnualCost(price int) float64 {
+	return b.MonthlyCost(price) * 12
+}
+
+// unitMonths returns the number of months in one unit, 0 for weeks.
+func (b BillingInterval) unitMonths() int {
+	switch b.Unit {
+	case BillingMonth:
+		return 1
+	case BillingQuarter:
+		return 3
+	case BillingYear:
+		return 12
+	default:
+		return 0
+	}
+}
+
+// NextBilling returns the first billing day after on of a subscription that
+// started on start. The first bill falls on the anchor day in, or for weekly
+// billing after, start.
+func (b BillingInterval) NextBilling(start, on time.Time) time.Time {
+	start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+
+	if b.Unit == BillingWeek {
+		first := start.AddDate(0, 0, (b.AnchorDay-isoWeekday(start)+7)%7)
+		if on.Before(first) {
+			return first
+		}
+		step := 7 * b.Count
+		days := int(on.Sub(first).Hours() / 24)
+		return first.AddDate(0, 0, (days/step+1)*step)
+	}
+
+	step := b.Count * b.unitMonths()
+	months := (on.Year()-start.Year())*12 + int(on.Month()) - int(start.Month())
+	for k := max(months/step, 0); ; k++ {
+		if day := b.billingDay(start, k*step); day.After(on) {
+			return day
+		}
+	}
+}
+
+// PeriodEnd returns the last day of the billing period on falls in, for a
+// subscription that started on start.
+func (b BillingInterval) PeriodEnd(start, on time.Time) time.Time {
+	return b.NextBilling(start, on).AddDate(0, 0, -1)
+}
+
+// billingDay returns the anchor day of the month months after the month of
+// start, or the last day of that month when it is shorter.
+func (b BillingInterval) billingDay(start time.Time, months int) time.Time {
+	first := time.Date(start.Year(), start.Month()+time.Month(months), 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(b.AnchorDay, last)-1)
+}
+
+// isoWeekday returns the ISO weekday of t, 1 for Monday to 7 for Sunday.
+func isoWeekday(t time.Time) int {
+	if wd := int(t.Weekday()); wd != 0 {
+		return wd
+	}
+	return 7
+}
@@ -0,0 +1,235 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func day(year int, month time.Month, d int) time.Time {
+	return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestIsBillingUnit(t *testing.T) {
+	tests := []struct {
+		unit BillingUnit
+		want bool
+	}{
+		{BillingWeek, true},
+		{BillingMonth, true},
+		{BillingQuarter, true},
+		{BillingYear, true},
+		{"day", false},
+		{"", false},
+		{"Month", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.unit), func(t *testing.T) {
+			if got := IsBillingUnit(tt.unit); got != tt.want {
+				t.Errorf("IsBillingUnit(%q) = %v, want %v", tt.unit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMonthlyCost(t *testing.T) {
+	tests := []struct {
+		name       string
+		billing    BillingInterval
+		price      int
+		wantMonth  float64
+		wantAnnual float64
+	}{
+		{"monthly", BillingInterval{Unit: BillingMonth, Count: 1}, 1200, 1200, 14400},
+		{"every three months", BillingInterval{Unit: BillingMonth, Count: 3}, 1200, 400, 4800},
+		{"quarterly", BillingInterval{Unit: BillingQuarter, Count: 1}, 1200, 400, 4800},
+		{"every two quarters", BillingInterval{Unit: BillingQuarter, Count: 2}, 1200, 200, 2400},
+		{"yearly", BillingInterval{Unit: BillingYear, Count: 1}, 1200, 100, 1200},
+		{"weekly", BillingInterval{Unit: BillingWeek, Count: 1}, 700, 700 * weeksPerMonth, 700 * weeksPerMonth * 12},
+		{"every two weeks", BillingInterval{Unit: BillingWeek, Count: 2}, 700, 350 * weeksPerMonth, 350 * weeksPerMonth * 12},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.billing.MonthlyCost(tt.price); got != tt.wantMonth {
+				t.Errorf("MonthlyCost(%d) = %v, want %v", tt.price, got, tt.wantMonth)
+			}
+			if got := tt.billing.AnnualCost(tt.price); got != tt.wantAnnual {
+				t.Errorf("AnnualCost(%d) = %v, want %v", tt.price, got, tt.wantAnnual)
+			}
+		})
+	}
+}
+
+func TestNextBilling(t *testing.T) {
+	tests := []struct {
+		name    string
+		billing BillingInterval
+		start   time.Time
+		on      time.Time
+		want    time.Time
+	}{
+		{
+			name:    "monthly before the anchor day",
+			billing: BillingInterval{Unit: BillingMonth, Count: 1, AnchorDay: 15},
+			start:   day(2026, time.January, 1),
+			on:      day(2026, time.October, 10),
+			want:    day(2026, time.October, 15),
+		},
+		{
+			name:    "monthly on the anchor day moves to the next month",
+			billing: BillingInterval{Unit: BillingMonth, Count: 1, AnchorDay: 15},
+			start:   day(2026, time.January, 1),
+			on:      day(2026, time.October, 15),
+			want:    day(2026, time.November, 15),
+		},
+		{
+			name:    "anchor 31 falls on the last day of February",
+			billing: BillingInterval{Unit: BillingMonth, Count: 1, AnchorDay: 31},
+			start:   day(2026, time.January, 31),
+			on:      day(2026, time.February, 10),
+			want:    day(2026, time.February, 28),
+		},
+		{
+			name:    "anchor 31 in a leap February",
+			billing: BillingInterval{Unit: BillingMonth, Count: 1, AnchorDay: 31},
+			start:   day(2028, time.January, 31),
+			on:      day(2028, time.January, 31),
+			want:    day(2028, time.February, 29),
+		},
+		{
+			name:    "anchor 31 returns to the 31st after February",
+			billing: BillingInterval{Unit: BillingMonth, Count: 1, AnchorDay: 31},
+			start:   day(2026, time.January, 31),
+			on:      day(2026, time.February, 28),
+			want:    day(2026, time.March, 31),
+		},
+		{
+			name:    "first bill on the anchor day of the start month",
+			billing: BillingInterval{Unit: BillingMonth, Count: 1, AnchorDay: 20},
+			start:   day(2026, time.March, 5),
+			on:      day(2026, time.March, 4),
+			want:    day(2026, time.March, 20),
+		},
+		{
+			name:    "every two months counts from the start month",
+			billing: BillingInterval{Unit: BillingMonth, Count: 2, AnchorDay: 1},
+			start:   day(2026, time.January, 1),
+			on:      day(2026, time.February, 1),
+			want:    day(2026, time.March, 1),
+		},
+		{
+			name:    "quarterly",
+			billing: BillingInterval{Unit: BillingQuarter, Count: 1, AnchorDay: 1},
+			start:   day(2026, time.January, 1),
+			on:      day(2026, time.October, 1),
+			want:    day(2027, time.January, 1),
+		},
+		{
+			name:    "yearly in the start month",
+			billing: BillingInterval{Unit: BillingYear, Count: 1, AnchorDay: 5},
+			start:   day(2024, time.March, 1),
+			on:      day(2026, time.October, 18),
+			want:    day(2027, time.March, 5),
+		},
+		{
+			name:    "yearly anchor 29 in a common year",
+			billing: BillingInterval{Unit: BillingYear, Count: 1, AnchorDay: 29},
+			start:   day(2024, time.February, 29),
+			on:      day(2025, time.January, 1),
+			want:    day(2025, time.February, 28),
+		},
+		{
+			name:    "weekly on the anchor weekday",
+			billing: BillingInterval{Unit: BillingWeek, Count: 1, AnchorDay: 7},
+			start:   day(2026, time.October, 1),
+			on:      day(2026, time.October, 3),
+			want:    day(2026, time.October, 4),
+		},
+		{
+			name:    "weekly first bill after a start on a later weekday",
+			billing: BillingInterval{Unit: BillingWeek, Count: 1, AnchorDay: 1},
+			start:   day(2026, time.October, 1),
+			on:      day(2026, time.September, 30),
+			want:    day(2026, time.October, 5),
+		},
+		{
+			name:    "weekly on the start weekday bills on the start day",
+			billing: BillingInterval{Unit: BillingWeek, Count: 1, AnchorDay: 4},
+			start:   day(2026, time.October, 1),
+			on:      day(2026, time.September, 30),
+			want:    day(2026, time.October, 1),
+		},
+		{
+			name:    "every two weeks skips a week",
+			billing: BillingInterval{Unit: BillingWeek, Count: 2, AnchorDay: 1},
+			start:   day(2026, time.October, 1),
+			on:      day(2026, time.October, 18),
+			want:    day(2026, time.October, 19),
+		},
+		{
+			name:    "every two weeks on a billing day",
+			billing: BillingInterval{Unit: BillingWeek, Count: 2, AnchorDay: 1},
+			start:   day(2026, time.October, 1),
+			on:      day(2026, time.October, 19),
+			want:    day(2026, time.November, 2),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.billing.NextBilling(tt.start, tt.on); !got.Equal(tt.want) {
+				t.Errorf("NextBilling(%s, %s) = %s, want %s",
+					tt.start.Format(time.DateOnly), tt.on.Format(time.DateOnly), got.Format(time.DateOnly), tt.want.Format(time.DateOnly))
+			}
+		})
+	}
+}
+
+func TestPeriodEnd(t *testing.T) {
+	tests := []struct {
+		name    string
+		billing BillingInterval
+		start   time.Time
+		on      time.Time
+		want    time.Time
+	}{
+		{
+			name:    "monthly ends the day before the next bill",
+			billing: BillingInterval{Unit: BillingMonth, Count: 1, AnchorDay: 15},
+			start:   day(2026, time.January, 15),
+			on:      day(2026, time.October, 18),
+			want:    day(2026, time.November, 14),
+		},
+		{
+			name:    "anchor 31 ends on the 27th of February",
+			billing: BillingInterval{Unit: BillingMonth, Count: 1, AnchorDay: 31},
+			start:   day(2026, time.January, 31),
+			on:      day(2026, time.February, 1),
+			want:    day(2026, time.February, 27),
+		},
+		{
+			name:    "yearly",
+			billing: BillingInterval{Unit: BillingYear, Count: 1, AnchorDay: 1},
+			start:   day(2025, time.June, 1),
+			on:      day(2026, time.October, 18),
+			want:    day(2027, time.May, 31),
+		},
+		{
+			name:    "weekly ends the day before the anchor weekday",
+			billing: BillingInterval{Unit: BillingWeek, Count: 1, AnchorDay: 1},
+			start:   day(2026, time.October, 5),
+			on:      day(2026, time.October, 18),
+			want:    day(2026, time.October, 18),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.billing.PeriodEnd(tt.start, tt.on); !got.Equal(tt.want) {
+				t.Errorf("PeriodEnd(%s, %s) = %s, want %s",
+					tt.start.Format(time.DateOnly), tt.on.Format(time.DateOnly), got.Format(time.DateOnly), tt.want.Format(time.DateOnly))
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package model
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+// DefaultCurrency is the currency of subscriptions and catalog prices that do
+// not specify one.
+const DefaultCurrency = "RUB"
+
+// currencyExponents maps the supported ISO 4217 codes to the number of
+// decimal places of their minor unit.
+var currencyExponents = map[string]int{
+	"AED": 2, "AMD": 2, "AUD": 2, "AZN": 2, "BGN": 2, "BHD": 3, "BRL": 2, "BYN": 2,
+	"CAD": 2, "CHF": 2, "CLP": 0, "CNY": 2, "CZK": 2, "DKK": 2, "EUR": 2, "GBP": 2,
+	"GEL": 2, "HKD": 2, "HUF": 2, "IDR": 2, "ILS": 2, "INR": 2, "ISK": 0, "JPY": 0,
+	"KGS": 2, "KRW": 0, "KWD": 3, "KZT": 2, "MDL": 2, "MXN": 2, "MYR": 2, "NOK": 2,
+	"NZD": 2, "OMR": 3, "PHP": 2, "PLN": 2, "RON": 2, "RSD": 2, "RUB": 2, "SAR": 2,
+	"SEK": 2, "SGD": 2, "THB": 2, "TJS": 2, "TRY": 2, "UAH": 2, "USD": 2, "UZS": 2,
+	"VND": 0, "ZAR": 2,
+}
+
+func IsCurrency(code string) bool {
+	_, ok := currencyExponents[code]
+	return ok
+}
+
+// MinorUnits returns how many minor units one unit of currency is worth, e.g.
+// 100 for RUB and 1 for JPY.
+func MinorUnits(currency string) float64 {
+	return math.Pow10(currencyExponents[currency])
+}
+
+// FormatAmount formats amount, in minor units of currency, as a decimal
+// number of units followed by the currency code, e.g. "799.00 RUB".
+func FormatAmount(amount int, currency string) string {
+	exp := currencyExponents[currency]
+	return strconv.FormatFloat(float64(amount)/MinorUnits(currency), 'f', exp, 64) + " " + currency
+}
+
+// ExchangeRate says that one unit of Base was worth Rate units of Quote on
+// Date.
+type ExchangeRate struct {
+	Date  time.Time
+	Base  string
+	Quote string
+	Rate  float64
+}
+
+// AppliedRate is a rate used to convert the amounts of Month.
+type AppliedRate struct {
+	Month time.Time
+	ExchangeRate
+}
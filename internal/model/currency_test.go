@@ -0,0 +1,56 @@
+package model
+
+import "testing"
+
+func TestMinorUnits(t *testing.T) {
+	tests := []struct {
+		currency string
+		want     float64
+	}{
+		{"RUB", 100},
+		{"USD", 100},
+		{"JPY", 1},
+		{"KWD", 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.currency, func(t *testing.T) {
+			if !IsCurrency(tt.currency) {
+				t.Errorf("IsCurrency(%q) = false, want true", tt.currency)
+			}
+			if got := MinorUnits(tt.currency); got != tt.want {
+				t.Errorf("MinorUnits(%q) = %v, want %v", tt.currency, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsCurrencyUnsupported(t *testing.T) {
+	for _, code := range []string{"", "rub", "XXX", "RUR"} {
+		if IsCurrency(code) {
+			t.Errorf("IsCurrency(%q) = true, want false", code)
+		}
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		amount   int
+		currency string
+		want     string
+	}{
+		{79900, "RUB", "799.00 RUB"},
+		{5, "USD", "0.05 USD"},
+		{0, "EUR", "0.00 EUR"},
+		{1500, "JPY", "1500 JPY"},
+		{12345, "KWD", "12.345 KWD"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := FormatAmount(tt.amount, tt.currency); got != tt.want {
+				t.Errorf("FormatAmount(%d, %s) = %q, want %q", tt.amount, tt.currency, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// IdempotencyRecord is the stored outcome of a request sent with an
+// Idempotency-Key header. Route and Key identify it; RequestHash tells
+// whether a retry carries the same request. A record without a Status is
+// pending: its request is still running, or stopped without an answer.
+type IdempotencyRecord struct {
+	Route       string
+	Key         string
+	RequestHash string
+	Status      int
+	ContentType string
+	Body        []byte
+	ExpiresAt   time.Time
+}
+
+// Pending reports whether the request that stored r has not answered yet.
+func (r IdempotencyRecord) Pending() bool {
+	return r.Status == 0
+}
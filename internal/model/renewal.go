@@ -0,0 +1,67 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type RenewalsParams struct {
+	UserID uuid.UUID
+	// From and To bound the renewal dates, both days inclusive. From defaults
+	// to today and To to DefaultRenewalDays days later.
+	From *time.Time
+	To   *time.Time
+	// Currency is the currency of the running total. It can be left out when
+	// all subscriptions share one currency.
+	Currency *string
+}
+
+// DefaultRenewalDays is the length of the default renewals window.
+const DefaultRenewalDays = 30
+
+// Renewal is a day a subscription is billed Amount in Currency.
+type Renewal struct {
+	Date           time.Time
+	SubscriptionID int64
+	Service        string
+	Amount         int
+	Currency       string
+	Billing        BillingInterval
+	// RunningTotal is the sum of the amounts of this and every earlier
+	// renewal of the calendar, in the currency of the calendar.
+	RunningTotal int64
+}
+
+type RenewalCalendar struct {
+	From     time.Time
+	To       time.Time
+	Currency string
+	Total    int64
+	Renewals []Renewal
+	// Rates are the exchange rates the running total used.
+	Rates []AppliedRate
+}
+
+// LastBilledDay returns the last day s is billed for: the day it cancels at,
+// the last day of its end month, or nil when it is open-ended.
+func (s Subscription) LastBilledDay() *time.Time {
+	if s.CancelAt != nil {
+		return s.CancelAt
+	}
+	if s.EndDate == nil {
+		return nil
+	}
+	last := time.Date(s.EndDate.Year(), s.EndDate.Month()+1, 0, 0, 0, 0, 0, time.UTC)
+	return &last
+}
+
+// RenewalSchedule is how a subscription recurs: on every billing day of its
+// interval from First on, until Until when set, except the Skipped days that
+// fell in a pause.
+type RenewalSchedule struct {
+	Subscription Subscription
+	First        time.Time
+	Until        *time.Time
+	Skipped      []time.Time
+}
@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+// Service is a catalog entry. Subscriptions may reference it through
+// Subscription.ServiceID.
+type Service struct {
+	ID           int64
+	Name         string
+	Aliases      []string
+	Category     *string
+	Website      *string
+	DefaultPrice *int
+	// Currency is the currency of DefaultPrice, which is in its minor units.
+	Currency  string
+	CreatedAt time.Time
+}
+
+type AddServiceParams struct {
+	Name         string
+	Aliases      []string
+	Category     *string
+	Website      *string
+	DefaultPrice *int
+	Currency     string
+}
+
+type UpdateServiceParams struct {
+	Name         *string
+	Aliases      []string // nil leaves the aliases unchanged
+	Category     Nullable[string]
+	Website      Nullable[string]
+	DefaultPrice Nullable[int]
+	Currency     *string
+}
+
+type ListServicesParams struct {
+	Category *string
+	Limit    int
+	Offset   int
+}
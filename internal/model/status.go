@@ -0,0 +1,120 @@
+package model
+
+import "time"
+
+// SubscriptionStatus is where a subscription is in its lifecycle.
+type SubscriptionStatus string
+
+const (
+	StatusTrial  SubscriptionStatus = "trial"
+	StatusActive SubscriptionStatus = "active"
+	StatusPaused SubscriptionStatus = "paused"
+	// StatusPendingCancel is a canceled subscription that is still billed
+	// until its end month.
+	StatusPendingCancel SubscriptionStatus = "pending_cancel"
+	StatusCanceled      SubscriptionStatus = "canceled"
+	// StatusExpired is a subscription whose end month passed without it being
+	// canceled.
+	StatusExpired SubscriptionStatus = "expired"
+)
+
+// StatusAction is a transition between statuses.
+type StatusAction string
+
+const (
+	ActionPause      StatusAction = "pause"
+	ActionResume     StatusAction = "resume"
+	ActionCancel     StatusAction = "cancel"
+	ActionReactivate StatusAction = "reactivate"
+	// ActionConvert ends a trial once its last day has passed.
+	ActionConvert StatusAction = "convert"
+)
+
+// statusTransitions lists the statuses each action can be taken from.
+var statusTransitions = map[StatusAction][]SubscriptionStatus{
+	ActionPause:      {StatusActive},
+	ActionResume:     {StatusPaused},
+	ActionCancel:     {StatusTrial, StatusActive, StatusPaused},
+	ActionReactivate: {StatusPendingCancel, StatusCanceled, StatusExpired},
+	ActionConvert:    {StatusTrial},
+}
+
+// CanTransition reports whether action can be taken on a subscription in
+// status from.
+func CanTransition(action StatusAction, from SubscriptionStatus) bool {
+	for _, s := range statusTransitions[action] {
+		if s == from {
+			return true
+		}
+	}
+	return false
+}
+
+type ChangeStatusParams struct {
+	Action StatusAction
+	// EffectiveMonth defaults to the current month.
+	EffectiveMonth *time.Time
+	// AtPeriodEnd cancels at the end of the current billing period instead of
+	// in EffectiveMonth.
+	AtPeriodEnd bool
+}
+
+// CancelAtPeriodEnd reports whether s was canceled at the end of a billing
+// period.
+func (s Subscription) CancelAtPeriodEnd() bool {
+	return s.CancelAt != nil
+}
+
+// PaidFrom returns the first month s is charged its price after the trial:
+// the month of its first billing day after TrialEnd, or the start month
+// without a trial. The months before it, including the rest of the month the
+// trial ends in, are trial months.
+func (s Subscription) PaidFrom() time.Time {
+	day := s.StartDate
+	if s.TrialEnd != nil {
+		day = s.Billing.NextBilling(s.StartDate, *s.TrialEnd)
+	}
+	return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// InTrial reports whether month falls within the trial of s.
+func (s Subscription) InTrial(month time.Time) bool {
+	return s.TrialEnd != nil && month.Before(s.PaidFrom())
+}
+
+// SubscriptionPause excludes the months from PausedFrom up to, but not
+// including, ResumedFrom from spend. An open pause has no ResumedFrom.
+type SubscriptionPause struct {
+	SubscriptionID int64
+	PausedFrom     time.Time
+	ResumedFrom    *time.Time
+}
+
+// Covers reports whether month falls within the pause.
+func (p SubscriptionPause) Covers(month time.Time) bool {
+	return !month.Before(p.PausedFrom) && (p.ResumedFrom == nil || month.Before(*p.ResumedFrom))
+}
+
+// StatusChange records a transition of a subscription, taking effect in
+// EffectiveMonth.
+type StatusChange struct {
+	SubscriptionID int64
+	Action         StatusAction
+	From           SubscriptionStatus
+	To             SubscriptionStatus
+	EffectiveMonth time.Time
+	ChangedAt      time.Time
+}
+
+// StatusTransitionParams are the writes of a status change: the new status,
+// an end date to set or clear along with the day it cancels at, a pause to add
+// and the month the open pause ends. Version is the version of the
+// subscription the change was decided on.
+type StatusTransitionParams struct {
+	StatusChange
+	Version    int
+	EndDate    Nullable[time.Time]
+	CancelAt   *time.Time
+	Pause      *SubscriptionPause
+	ResumeFrom *time.Time
+}
@@ -0,0 +1,111 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCanTransition(t *testing.T) {
+	statuses := []SubscriptionStatus{StatusTrial, StatusActive, StatusPaused, StatusPendingCancel, StatusCanceled, StatusExpired}
+	allowed := map[StatusAction]map[SubscriptionStatus]bool{
+		ActionPause:      {StatusActive: true},
+		ActionResume:     {StatusPaused: true},
+		ActionCancel:     {StatusTrial: true, StatusActive: true, StatusPaused: true},
+		ActionReactivate: {StatusPendingCancel: true, StatusCanceled: true, StatusExpired: true},
+	}
+
+	for action, from := range allowed {
+		for _, status := range statuses {
+			t.Run(string(action)+" "+string(status), func(t *testing.T) {
+				if got := CanTransition(action, status); got != from[status] {
+					t.Errorf("CanTransition(%s, %s) = %v, want %v", action, status, got, from[status])
+				}
+			})
+		}
+	}
+	if CanTransition("archive", StatusActive) {
+		t.Error("CanTransition(archive, active) = true, want false")
+	}
+}
+
+func TestSubscriptionPauseCovers(t *testing.T) {
+	month := func(m time.Month) time.Time {
+		return time.Date(2026, m, 1, 0, 0, 0, 0, time.UTC)
+	}
+	resumed := month(time.May)
+	closed := SubscriptionPause{PausedFrom: month(time.March), ResumedFrom: &resumed}
+	open := SubscriptionPause{PausedFrom: month(time.March)}
+
+	tests := []struct {
+		name  string
+		pause SubscriptionPause
+		month time.Time
+		want  bool
+	}{
+		{"before the pause", closed, month(time.February), false},
+		{"first paused month", closed, month(time.March), true},
+		{"last paused month", closed, month(time.April), true},
+		{"resumed month", closed, month(time.May), false},
+		{"open pause before", open, month(time.February), false},
+		{"open pause later on", open, month(time.December), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.pause.Covers(tt.month); got != tt.want {
+				t.Errorf("Covers(%s) = %v, want %v", tt.month.Format("01-2006"), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaidFrom(t *testing.T) {
+	day := func(m time.Month, d int) time.Time {
+		return time.Date(2026, m, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	monthly := func(anchor int) BillingInterval {
+		return BillingInterval{Unit: BillingMonth, Count: 1, AnchorDay: anchor}
+	}
+
+	tests := []struct {
+		name      string
+		start     time.Time
+		billing   BillingInterval
+		trialEnd  *time.Time
+		wantPaid  time.Time
+		wantTrial []time.Month
+	}{
+		{"no trial", day(time.January, 10), monthly(10), nil, day(time.January, 1), nil},
+		{"trial ending before the billing day", day(time.January, 10), monthly(20), ptrTo(day(time.February, 14)), day(time.February, 1), []time.Month{time.January}},
+		{"trial ending after the billing day", day(time.January, 10), monthly(1), ptrTo(day(time.February, 14)), day(time.March, 1), []time.Month{time.January, time.February}},
+		{"trial ending on the billing day", day(time.January, 10), monthly(14), ptrTo(day(time.February, 14)), day(time.March, 1), []time.Month{time.January, time.February}},
+		{"trial ending on the last day of a month", day(time.January, 10), monthly(1), ptrTo(day(time.February, 28)), day(time.March, 1), []time.Month{time.January, time.February}},
+		{"trial ending on the start day", day(time.January, 10), monthly(10), ptrTo(day(time.January, 10)), day(time.February, 1), []time.Month{time.January}},
+		{"weekly", day(time.January, 10), BillingInterval{Unit: BillingWeek, Count: 1, AnchorDay: 1}, ptrTo(day(time.January, 31)), day(time.February, 1), []time.Month{time.January}},
+		{"quarterly", day(time.January, 10), BillingInterval{Unit: BillingQuarter, Count: 1, AnchorDay: 10}, ptrTo(day(time.February, 14)), day(time.April, 1), []time.Month{time.January, time.February, time.March}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Subscription{StartDate: tt.start, Billing: tt.billing, TrialEnd: tt.trialEnd}
+			if got := s.PaidFrom(); !got.Equal(tt.wantPaid) {
+				t.Errorf("PaidFrom() = %s, want %s", got.Format(time.DateOnly), tt.wantPaid.Format(time.DateOnly))
+			}
+			var trial []time.Month
+			for m := time.January; m <= time.April; m++ {
+				if s.InTrial(day(m, 1)) {
+					trial = append(trial, m)
+				}
+			}
+			if !reflect.DeepEqual(trial, tt.wantTrial) {
+				t.Errorf("InTrial months = %v, want %v", trial, tt.wantTrial)
+			}
+		})
+	}
+}
+
+func ptrTo[T any](v T) *T {
+	return &v
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,31 +10,262 @@ import (
 type Subscription struct {
 	ID        int64
 	Service   string
-	Price     int
+	ServiceID *int64
+	// Price is the latest price of the subscription. The price of each month
+	// is found in its SubscriptionPrice history.
+	Price int
+	// Currency is the ISO 4217 code of Price, which is in its minor units.
+	Currency string
+	// Billing is how often Price is paid.
+	Billing   BillingInterval
 	UserID    uuid.UUID
 	StartDate time.Time
 	EndDate   *time.Time
+	// Status is read as of the current month, so that a subscription whose
+	// end month has passed is canceled or expired.
+	Status          SubscriptionStatus
+	StatusChangedAt time.Time
+	// TrialEnd is the last day of the trial, after which Price is charged.
+	// TrialFlaggedAt is when the trial was flagged as about to convert.
+	TrialEnd       *time.Time
+	TrialFlaggedAt *time.Time
+	// CancelAt is the last day of the billing period the subscription was
+	// canceled at the end of. EndDate is then its month.
+	CancelAt *time.Time
+	// TransferredFrom is the subscription this one continues after an
+	// ownership transfer.
+	TransferredFrom *int64
+	// Version is incremented by every update.
+	Version int
+	// Relevance is the service name similarity to the search query. It is
+	// only set by searches.
+	Relevance *float32
 }
 
 type AddSubscriptionParams struct {
 	Service   string
+	ServiceID *int64
 	Price     int
+	Currency  string
+	Billing   BillingInterval
 	UserID    uuid.UUID
 	StartDate time.Time
 	EndDate   *time.Time
+	Status    SubscriptionStatus
+	// TrialEnd starts the subscription with a trial until that day, charged
+	// TrialPrice; Price takes effect afterwards.
+	TrialEnd        *time.Time
+	TrialPrice      int
+	TransferredFrom *int64
 }
 
 type UpdateSubscriptionParams struct {
 	Service *string
-	Price   *int
-	UserID  *uuid.UUID
-	EndDate *time.Time
+	// ServiceID is the catalog entry of Service. It is only written when
+	// Service is set, and a nil value then unlinks the catalog entry.
+	ServiceID *int64
+	Price     *int
+	// PriceEffectiveFrom is the first month Price and the billing fields apply
+	// to; they replace the price and billing of that month and every later
+	// one. nil applies them from the first month after the trial, replacing
+	// the price history from then on.
+	PriceEffectiveFrom *time.Time
+	BillingUnit        *BillingUnit
+	BillingCount       *int
+	BillingAnchorDay   *int
+	// UserID is rejected: owners change through TransferSubscription.
+	UserID *uuid.UUID
+	// EndDate can be cleared to reopen the subscription. Setting it also sets
+	// CancelAt, which is cleared unless given.
+	EndDate  Nullable[time.Time]
+	CancelAt *time.Time
+	// Status is only changed through status transitions.
+	Status *SubscriptionStatus
+	// ExpectedVersions makes the update conditional on the current version
+	// being one of them. nil updates unconditionally.
+	ExpectedVersions []int
+	// CurrentMonth decides whether Price and the billing fields are listed
+	// on the subscription right away: when PriceEffectiveFrom is later than
+	// its first month to list, they are only recorded in the price history.
+	CurrentMonth time.Time
+}
+
+// Nullable is an update field that can be left alone, set to a value or set
+// to NULL. Set reports whether the field is updated at all; Value is nil when
+// it is cleared.
+type Nullable[T any] struct {
+	Set   bool
+	Value *T
+}
+
+// NullableOf returns a Nullable that sets the field to v.
+func NullableOf[T any](v T) Nullable[T] {
+	return Nullable[T]{Set: true, Value: &v}
+}
+
+// SubscriptionPrice is the price of a subscription, paid once per Billing
+// interval, from EffectiveFrom until the month before the next price takes
+// effect.
+type SubscriptionPrice struct {
+	SubscriptionID int64
+	Price          int
+	Billing        BillingInterval
+	EffectiveFrom  time.Time
+	RecordedAt     time.Time
+}
+
+// ServicePriceChangeParams changes the price of the subscriptions to Service
+// in Currency that pay OldPrice in EffectiveMonth, or in their start month when they
+// start later, to NewPrice from that month on. UserID limits the change to
+// one user. DryRun only finds the subscriptions. A change from a month after
+// CurrentMonth is only recorded in the price history until that month comes.
+type ServicePriceChangeParams struct {
+	Service        string
+	Currency       string
+	OldPrice       int
+	NewPrice       int
+	EffectiveMonth time.Time
+	UserID         *uuid.UUID
+	DryRun         bool
+	CurrentMonth   time.Time
+}
+
+// ServicePriceChange is the result of a price change. Subscriptions are the
+// affected subscriptions after the change, or as they are when it was a dry
+// run.
+type ServicePriceChange struct {
+	Params        ServicePriceChangeParams
+	Subscriptions []Subscription
+	// MonthlyDelta is the change in monthly spend once the new price applies
+	// to every affected subscription, normalized from their billing intervals.
+	MonthlyDelta int64
+}
+
+// EffectiveFrom returns the first month a price change from month applies to
+// sub, which leaves its trial alone. EffectiveFrom(sub, current month) is
+// the month whose price and billing interval sub lists.
+func EffectiveFrom(sub Subscription, month time.Time) time.Time {
+	if paid := sub.PaidFrom(); paid.After(month) {
+		return paid
+	}
+	return month
+}
+
+// MergeBilling returns b with the billing fields set in params applied.
+func MergeBilling(b BillingInterval, params UpdateSubscriptionParams) BillingInterval {
+	if params.BillingUnit != nil {
+		b.Unit = *params.BillingUnit
+	}
+	if params.BillingCount != nil {
+		b.Count = *params.BillingCount
+	}
+	if params.BillingAnchorDay != nil {
+		b.AnchorDay = *params.BillingAnchorDay
+	}
+	return b
+}
+
+// TransferSubscriptionParams moves a subscription to UserID from
+// EffectiveMonth on. The current owner keeps the months before it.
+type TransferSubscriptionParams struct {
+	UserID         uuid.UUID
+	EffectiveMonth time.Time
+}
+
+// SubscriptionTransfer is the result of a transfer: From is the closed
+// subscription of the previous owner, To the one opened for the new owner.
+type SubscriptionTransfer struct {
+	From Subscription
+	To   Subscription
 }
 
 type ListSubscriptionsParams struct {
-	UserID *uuid.UUID
-	Limit  int
-	Offset int
+	// Query fuzzy-matches service names, case-insensitively.
+	Query         *string
+	UserID        *uuid.UUID
+	ServiceName   *string
+	ServicePrefix *string
+	PriceMin      *int
+	PriceMax      *int
+	ActiveOn      *time.Time
+	ActiveIn      *time.Time
+	StartedFrom   *time.Time
+	StartedTo     *time.Time
+	EndedFrom     *time.Time
+	EndedTo       *time.Time
+	HasEndDate    *bool
+	// EndingWithin keeps the subscriptions whose last billed day, the day
+	// they cancel at or the end of their end month, is within that many days
+	// from Today, the same UTC day cancel_at is computed from.
+	EndingWithin *int
+	Today        time.Time
+	Sort         SubscriptionSort
+	Limit        int
+	Offset       int
+	After        *SubscriptionCursor
+	IncludeTotal bool
+	// AsOf reads subscriptions as they were stored at that moment.
+	AsOf *time.Time
+}
+
+// Fields subscriptions can be sorted by.
+const (
+	SortByID          = "id"
+	SortByServiceName = "service_name"
+	SortByPrice       = "price"
+	SortByStartDate   = "start_date"
+	SortByEndDate     = "end_date"
+	// SortByRelevance orders by similarity to the search query.
+	SortByRelevance = "relevance"
+)
+
+func IsSubscriptionSortField(field string) bool {
+	switch field {
+	case SortByID, SortByServiceName, SortByPrice, SortByStartDate, SortByEndDate, SortByRelevance:
+		return true
+	}
+	return false
+}
+
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+type SubscriptionSort []SortField
+
+// DefaultSubscriptionSort lists the newest subscriptions first.
+var DefaultSubscriptionSort = SubscriptionSort{{Field: SortByStartDate, Desc: true}}
+
+// DefaultSearchSort lists the best matches of a search first.
+var DefaultSearchSort = SubscriptionSort{{Field: SortByRelevance, Desc: true}, {Field: SortByStartDate, Desc: true}}
+
+// String renders the sort in its query form, e.g. "-price,service_name".
+func (s SubscriptionSort) String() string {
+	parts := make([]string, len(s))
+	for i, f := range s {
+		if f.Desc {
+			parts[i] = "-" + f.Field
+		} else {
+			parts[i] = f.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// SubscriptionCursor is the keyset position of the last row of a page. Values
+// hold that row's sort key values, in Sort order, followed by its id. A cursor
+// is only valid for the sort it was created with.
+type SubscriptionCursor struct {
+	Sort   string
+	Values []string
+}
+
+type SubscriptionPage struct {
+	Items []Subscription
+	Limit int
+	Next  *SubscriptionCursor
+	Total *int64
 }
 
 type SumOfSubscriptionPricesParams struct {
@@ -41,4 +273,93 @@ type SumOfSubscriptionPricesParams struct {
 	ServiceName *string
 	PeriodStart *time.Time
 	PeriodEnd   *time.Time
+	// AsOf computes the sum from subscriptions as they were stored at that
+	// moment.
+	AsOf *time.Time
+	// Currency converts amounts to that currency. It can be left out when all
+	// subscriptions share one currency.
+	Currency *string
+}
+
+type SubscriptionSpend struct {
+	SubscriptionID int64
+	Service        string
+	UserID         uuid.UUID
+	// Price is the price of the last overlapped month in Currency, paid once
+	// per Billing interval, and MonthlyCost that price normalized to a month.
+	Price       int
+	Currency    string
+	Billing     BillingInterval
+	MonthlyCost float64
+	// Months is the number of overlapped months that were billed, leaving out
+	// the PausedMonths. TrialMonths of them were within the trial.
+	Months       int
+	PausedMonths int
+	TrialMonths  int
+	// Subtotal is the sum of the monthly costs of the overlapped months,
+	// converted to the currency of the summary. TrialSubtotal is the part of it
+	// spent on trial months.
+	Subtotal      int64
+	TrialSubtotal int64
+}
+
+type SpendSummary struct {
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	Currency    string
+	Total       int64
+	// TrialTotal is the part of Total spent on trial months.
+	TrialTotal int64
+	Items      []SubscriptionSpend
+	// Rates are the exchange rates the conversion used.
+	Rates []AppliedRate
+}
+
+type SpendGroupBy string
+
+const (
+	SpendGroupByNone    SpendGroupBy = ""
+	SpendGroupByService SpendGroupBy = "service_name"
+	SpendGroupByUser    SpendGroupBy = "user_id"
+)
+
+type SpendTimeSeriesParams struct {
+	UserID      *uuid.UUID
+	ServiceName *string
+	PeriodStart *time.Time
+	PeriodEnd   *time.Time
+	GroupBy     SpendGroupBy
+	AsOf        *time.Time
+	Currency    *string
+}
+
+type SpendGroup struct {
+	Key                 string
+	Total               int64
+	ActiveSubscriptions int
+}
+
+type SpendBucket struct {
+	Month               time.Time
+	Currency            string
+	Total               int64
+	ActiveSubscriptions int
+	// TrialTotal and TrialSubscriptions are the part of Total and
+	// ActiveSubscriptions that were in a trial.
+	TrialTotal         int64
+	TrialSubscriptions int
+	Groups             []SpendGroup
+	// Rates are the exchange rates the month was converted with.
+	Rates []ExchangeRate
+}
+
+type SuggestServicesParams struct {
+	Query string
+	Limit int
+}
+
+type ServiceSuggestion struct {
+	Name          string
+	Subscriptions int64
+	Score         float32
 }
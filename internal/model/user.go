@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// User owns subscriptions through Subscription.UserID. Users created before
+// the users table existed have neither a name nor an email.
+type User struct {
+	ID        uuid.UUID
+	Name      *string
+	Email     *string
+	CreatedAt time.Time
+}
+
+type AddUserParams struct {
+	ID    *uuid.UUID // generated when nil
+	Name  string
+	Email *string
+}
+
+type UpdateUserParams struct {
+	Name  *string
+	Email *string
+}
+
+type ListUsersParams struct {
+	Limit  int
+	Offset int
+}
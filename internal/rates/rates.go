@@ -0,0 +1,125 @@
+// Package rates parses exchange-rate files for import into the
+// exchange_rates table.
+package rates
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/morphlinkk/subscriptions/internal/model"
+)
+
+// ecbBase is the currency every rate of the ECB reference feed is quoted
+// against.
+const ecbBase = "EUR"
+
+var csvHeader = []string{"date", "base", "quote", "rate"}
+
+// ParseCSV reads rates from CSV with the header date,base,quote,rate, e.g.
+// "2024-01-31,USD,RUB,89.6883". Dates are YYYY-MM-DD and currencies ISO 4217
+// codes.
+func ParseCSV(r io.Reader) ([]model.ExchangeRate, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = len(csvHeader)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("empty file")
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i, name := range csvHeader {
+		if !strings.EqualFold(strings.TrimSpace(header[i]), name) {
+			return nil, fmt.Errorf("header must be %s", strings.Join(csvHeader, ","))
+		}
+	}
+
+	var rates []model.ExchangeRate
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := cr.FieldPos(0)
+		rate, err := parseRate(record[0], record[1], record[2], record[3])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rates = append(rates, rate)
+	}
+	return rates, nil
+}
+
+type ecbEnvelope struct {
+	Days []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string `xml:"currency,attr"`
+			Rate     string `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+// ParseECB reads rates from the euro foreign exchange reference rates XML
+// published by the European Central Bank, daily or historical. Every rate is
+// quoted against EUR. Currencies that are not supported, such as retired
+// ones in the historical feed, are skipped.
+func ParseECB(r io.Reader) ([]model.ExchangeRate, error) {
+	var env ecbEnvelope
+	if err := xml.NewDecoder(r).Decode(&env); err != nil {
+		return nil, err
+	}
+
+	var rates []model.ExchangeRate
+	for _, day := range env.Days {
+		for _, cube := range day.Rates {
+			if !model.IsCurrency(cube.Currency) {
+				continue
+			}
+			rate, err := parseRate(day.Time, ecbBase, cube.Currency, cube.Rate)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", day.Time, cube.Currency, err)
+			}
+			rates = append(rates, rate)
+		}
+	}
+	return rates, nil
+}
+
+func parseRate(date, base, quote, value string) (model.ExchangeRate, error) {
+	d, err := time.Parse(time.DateOnly, strings.TrimSpace(date))
+	if err != nil {
+		return model.ExchangeRate{}, fmt.Errorf("invalid date %q, use YYYY-MM-DD", date)
+	}
+
+	base = strings.ToUpper(strings.TrimSpace(base))
+	quote = strings.ToUpper(strings.TrimSpace(quote))
+	if !model.IsCurrency(base) {
+		return model.ExchangeRate{}, fmt.Errorf("unsupported currency %q", base)
+	}
+	if !model.IsCurrency(quote) {
+		return model.ExchangeRate{}, fmt.Errorf("unsupported currency %q", quote)
+	}
+	if base == quote {
+		return model.ExchangeRate{}, fmt.Errorf("base and quote are both %s", base)
+	}
+
+	rate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || rate <= 0 {
+		return model.ExchangeRate{}, fmt.Errorf("rate %q must be a positive number", value)
+	}
+
+	return model.ExchangeRate{Date: d, Base: base, Quote: quote, Rate: rate}, nil
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/morphlinkk/subscriptions/internal/db"
+	"github.com/morphlinkk/subscriptions/internal/model"
+	"github.com/morphlinkk/subscriptions/internal/reqctx"
+)
+
+type AuditRepository interface {
+	ListAudit(ctx context.Context, params *model.ListAuditParams) ([]model.SubscriptionAuditEntry, error)
+}
+
+type auditRepository struct {
+	store *db.Store
+}
+
+func NewAuditRepository(store *db.Store) AuditRepository {
+	return &auditRepository{
+		store,
+	}
+}
+
+func (r *auditRepository) ListAudit(ctx context.Context, params *model.ListAuditParams) ([]model.SubscriptionAuditEntry, error) {
+	e, err := r.store.ListSubscriptionAudit(ctx, *params)
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// subscriptionSnapshot is the form subscriptions take in the before and after
+// documents of audit entries.
+type subscriptionSnapshot struct {
+	ID              int64      `json:"id"`
+	Service         string     `json:"service_name"`
+	ServiceID       *int64     `json:"service_id"`
+	Price           int        `json:"price"`
+	Currency        string     `json:"currency"`
+	BillingUnit     string     `json:"billing_interval"`
+	BillingCount    int        `json:"billing_interval_count"`
+	AnchorDay       int        `json:"billing_anchor_day"`
+	UserID          uuid.UUID  `json:"user_id"`
+	StartDate       time.Time  `json:"start_date"`
+	EndDate         *time.Time `json:"end_date"`
+	Status          string     `json:"status"`
+	TrialEnd        *time.Time `json:"trial_end"`
+	TrialFlaggedAt  *time.Time `json:"trial_flagged_at"`
+	CancelAt        *time.Time `json:"cancel_at"`
+	TransferredFrom *int64     `json:"transferred_from"`
+	Version         int        `json:"version"`
+}
+
+func snapshot(s *model.Subscription) (json.RawMessage, error) {
+	if s == nil {
+		return nil, nil
+	}
+	return json.Marshal(subscriptionSnapshot{
+		ID:              s.ID,
+		Service:         s.Service,
+		ServiceID:       s.ServiceID,
+		Price:           s.Price,
+		Currency:        s.Currency,
+		BillingUnit:     string(s.Billing.Unit),
+		BillingCount:    s.Billing.Count,
+		AnchorDay:       s.Billing.AnchorDay,
+		UserID:          s.UserID,
+		StartDate:       s.StartDate,
+		EndDate:         s.EndDate,
+		Status:          string(s.Status),
+		TrialEnd:        s.TrialEnd,
+		TrialFlaggedAt:  s.TrialFlaggedAt,
+		CancelAt:        s.CancelAt,
+		TransferredFrom: s.TransferredFrom,
+		Version:         s.Version,
+	})
+}
+
+// recordChange records a change of a subscription with q, so that it commits
+// or rolls back with the change itself: it writes the audit entry and moves
+// the temporal history to the new state. before is nil for creations and
+// after for deletions.
+func recordChange(ctx context.Context, q *db.Queries, op model.AuditOperation, before, after *model.Subscription) error {
+	if err := writeAudit(ctx, q, op, before, after); err != nil {
+		return err
+	}
+
+	if before != nil {
+		if err := q.CloseSubscriptionHistory(ctx, before.ID); err != nil {
+			return err
+		}
+	}
+	if after != nil {
+		return q.OpenSubscriptionHistory(ctx, *after)
+	}
+	return nil
+}
+
+// writeAudit writes the audit entry of a change. The actor and request ID
+// come from ctx.
+func writeAudit(ctx context.Context, q *db.Queries, op model.AuditOperation, before, after *model.Subscription) error {
+	params := model.AddSubscriptionAuditParams{
+		Operation: op,
+		Actor:     reqctx.Actor(ctx),
+	}
+	if id := reqctx.RequestID(ctx); id != "" {
+		params.RequestID = &id
+	}
+
+	if after != nil {
+		params.SubscriptionID = after.ID
+	} else {
+		params.SubscriptionID = before.ID
+	}
+
+	var err error
+	if params.Before, err = snapshot(before); err != nil {
+		return err
+	}
+	if params.After, err = snapshot(after); err != nil {
+		return err
+	}
+	return q.AddSubscriptionAudit(ctx, params)
+}
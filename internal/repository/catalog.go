@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/morphlinkk/subscriptions/internal/apperr"
+	"github.com/morphlinkk/subscriptions/internal/db"
+	"github.com/morphlinkk/subscriptions/internal/model"
+)
+
+type CatalogRepository interface {
+	GetById(ctx context.Context, id int64) (*model.Service, error)
+	FindByName(ctx context.Context, name string) (*model.Service, error)
+	AddService(ctx context.Context, params *model.AddServiceParams) (*model.Service, error)
+	UpdateService(ctx context.Context, id int64, params *model.UpdateServiceParams) (*model.Service, error)
+	DeleteService(ctx context.Context, id int64) error
+	ListServices(ctx context.Context, params *model.ListServicesParams) ([]model.Service, error)
+}
+
+type catalogRepository struct {
+	store *db.Store
+}
+
+func NewCatalogRepository(store *db.Store) CatalogRepository {
+	return &catalogRepository{
+		store,
+	}
+}
+
+func (r *catalogRepository) GetById(ctx context.Context, id int64) (*model.Service, error) {
+	s, err := r.store.GetServiceById(ctx, id)
+	if err != nil {
+		return nil, mapError(err, "service")
+	}
+	return &s, nil
+}
+
+func (r *catalogRepository) FindByName(ctx context.Context, name string) (*model.Service, error) {
+	s, err := r.store.FindServiceByName(ctx, name)
+	if err != nil {
+		return nil, mapError(err, "service")
+	}
+	return &s, nil
+}
+
+func (r *catalogRepository) AddService(ctx context.Context, params *model.AddServiceParams) (*model.Service, error) {
+	var s model.Service
+	err := r.store.ExecTx(ctx, func(q *db.Queries) error {
+		var err error
+		s, err = q.AddService(ctx, *params)
+		if err != nil {
+			return err
+		}
+		return q.SetServiceNames(ctx, s.ID, serviceNames(s))
+	})
+	if err != nil {
+		return nil, mapServiceError(err)
+	}
+	return &s, nil
+}
+
+// UpdateService also renames the subscriptions linked to the service when its
+// canonical name changes, auditing each of them.
+func (r *catalogRepository) UpdateService(ctx context.Context, id int64, params *model.UpdateServiceParams) (*model.Service, error) {
+	var s model.Service
+	err := r.store.ExecTx(ctx, func(q *db.Queries) error {
+		var err error
+		s, err = q.UpdateService(ctx, id, *params)
+		if err != nil {
+			return err
+		}
+		if params.Name == nil && params.Aliases == nil {
+			return nil
+		}
+		if err := q.SetServiceNames(ctx, id, serviceNames(s)); err != nil {
+			return err
+		}
+		if params.Name == nil {
+			return nil
+		}
+
+		renamed, oldNames, err := q.RenameServiceSubscriptions(ctx, id, s.Name)
+		if err != nil {
+			return err
+		}
+		for i := range renamed {
+			before := renamed[i]
+			before.Service = oldNames[i]
+			before.Version--
+			if err := recordChange(ctx, q, model.AuditUpdate, &before, &renamed[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, mapServiceError(err)
+	}
+	return &s, nil
+}
+
+// serviceNames returns the name and aliases a service claims.
+func serviceNames(s model.Service) []string {
+	return append([]string{s.Name}, s.Aliases...)
+}
+
+// mapServiceError reports a name or alias that another service claimed in
+// the meantime as a conflict.
+func mapServiceError(err error) error {
+	if pgErrorCode(err) == pgUniqueViolation {
+		return apperr.Wrap(err, apperr.KindConflict, "name or alias is already used by another service")
+	}
+	return mapError(err, "service")
+}
+
+// DeleteService unlinks the subscriptions of the service before removing it,
+// so that each of them gets a new version, an audit entry and a history row.
+// They keep their service name.
+func (r *catalogRepository) DeleteService(ctx context.Context, id int64) error {
+	err := r.store.ExecTx(ctx, func(q *db.Queries) error {
+		linked, err := q.ListServiceSubscriptionsForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		for i := range linked {
+			before := linked[i]
+			after, err := q.UpdateSubscription(ctx, before.ID, model.UpdateSubscriptionParams{
+				Service: &before.Service,
+			})
+			if err != nil {
+				return err
+			}
+			if err := recordChange(ctx, q, model.AuditUpdate, &before, &after); err != nil {
+				return err
+			}
+		}
+		return q.DeleteService(ctx, id)
+	})
+	return mapError(err, "service")
+}
+
+func (r *catalogRepository) ListServices(ctx context.Context, params *model.ListServicesParams) ([]model.Service, error) {
+	s, err := r.store.ListServices(ctx, *params)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/morphlinkk/subscriptions/internal/apperr"
+)
+
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+	pgCheckViolation      = "23514"
+)
+
+// mapError translates storage errors into domain errors. entity names the
+// resource in not-found messages, e.g. "subscription".
+func mapError(err error, entity string) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return apperr.NotFound("%s not found", entity)
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case pgUniqueViolation:
+			return apperr.Wrap(err, apperr.KindConflict, entity+" already exists")
+		case pgForeignKeyViolation:
+			return apperr.Wrap(err, apperr.KindValidation, entity+" references a missing record")
+		case pgCheckViolation:
+			return apperr.Wrap(err, apperr.KindValidation, entity+" violates constraint "+pgErr.ConstraintName)
+		}
+	}
+
+	return err
+}
+
+// pgErrorCode returns the Postgres error code of err, or "" when err does not
+// come from Postgres.
+func pgErrorCode(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
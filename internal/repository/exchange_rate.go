@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/morphlinkk/subscriptions/internal/db"
+	"github.com/morphlinkk/subscriptions/internal/model"
+)
+
+// exchangeRateBatch bounds the number of rates sent in one statement.
+const exchangeRateBatch = 5000
+
+type ExchangeRateRepository interface {
+	// ImportRates stores rates in one transaction, replacing stored rates of
+	// the same date and currency pair. Later duplicates in rates win.
+	ImportRates(ctx context.Context, rates []model.ExchangeRate) (int, error)
+	ListMonthlyRates(ctx context.Context, first, last time.Time, currencies []string) ([]model.AppliedRate, error)
+}
+
+type exchangeRateRepository struct {
+	store *db.Store
+}
+
+func NewExchangeRateRepository(store *db.Store) ExchangeRateRepository {
+	return &exchangeRateRepository{
+		store,
+	}
+}
+
+func (r *exchangeRateRepository) ImportRates(ctx context.Context, rates []model.ExchangeRate) (int, error) {
+	type key struct {
+		date        time.Time
+		base, quote string
+	}
+	index := make(map[key]int, len(rates))
+	unique := make([]model.ExchangeRate, 0, len(rates))
+	for _, rate := range rates {
+		k := key{rate.Date, rate.Base, rate.Quote}
+		if i, ok := index[k]; ok {
+			unique[i] = rate
+			continue
+		}
+		index[k] = len(unique)
+		unique = append(unique, rate)
+	}
+
+	err := r.store.ExecTx(ctx, func(q *db.Queries) error {
+		for start := 0; start < len(unique); start += exchangeRateBatch {
+			end := min(start+exchangeRateBatch, len(unique))
+			if err := q.UpsertExchangeRates(ctx, unique[start:end]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, mapError(err, "exchange rate")
+	}
+	return len(unique), nil
+}
+
+func (r *exchangeRateRepository) ListMonthlyRates(ctx context.Context, first, last time.Time, currencies []string) ([]model.AppliedRate, error) {
+	rates, err := r.store.ListMonthlyExchangeRates(ctx, first, last, currencies)
+	if err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/morphlinkk/subscriptions/internal/apperr"
+	"github.com/morphlinkk/subscriptions/internal/db"
+	"github.com/morphlinkk/subscriptions/internal/model"
+)
+
+const (
+	pgLockNotAvailable = "55P03"
+
+	// idempotencyLockTimeout bounds how long a request waits for another one
+	// claiming the same key.
+	idempotencyLockTimeout = "10s"
+)
+
+type IdempotencyRepository interface {
+	// Claim returns the unexpired record stored for pending's key. When there
+	// is none, it stores pending, so that retries find the request running,
+	// and returns nil.
+	Claim(ctx context.Context, pending model.IdempotencyRecord) (*model.IdempotencyRecord, error)
+	// Complete stores the response of a claimed request and keeps it until
+	// rec.ExpiresAt instead of the shorter expiry of the pending claim.
+	Complete(ctx context.Context, rec model.IdempotencyRecord) error
+	// Release frees a claimed key whose request may be retried.
+	Release(ctx context.Context, route, key string) error
+}
+
+type idempotencyRepository struct {
+	store *db.Store
+}
+
+func NewIdempotencyRepository(store *db.Store) IdempotencyRepository {
+	return &idempotencyRepository{
+		store,
+	}
+}
+
+// Claim holds a lock on the key only while it reads and stores the record,
+// so that two requests cannot both claim it.
+func (r *idempotencyRepository) Claim(ctx context.Context, pending model.IdempotencyRecord) (*model.IdempotencyRecord, error) {
+	var stored *model.IdempotencyRecord
+	err := r.store.ExecTx(ctx, func(q *db.Queries) error {
+		if err := q.LockIdempotencyKey(ctx, pending.Route+" "+pending.Key, idempotencyLockTimeout); err != nil {
+			return err
+		}
+
+		rec, err := q.GetIdempotencyRecord(ctx, pending.Route, pending.Key)
+		switch {
+		case err == nil:
+			stored = &rec
+			return nil
+		case !errors.Is(err, pgx.ErrNoRows):
+			return err
+		}
+
+		if err := q.DeleteExpiredIdempotencyRecords(ctx); err != nil {
+			return err
+		}
+		return q.SaveIdempotencyRecord(ctx, pending)
+	})
+	if pgErrorCode(err) == pgLockNotAvailable {
+		return nil, apperr.Wrap(err, apperr.KindConflict, "a request with this idempotency key is still in progress")
+	}
+	return stored, err
+}
+
+func (r *idempotencyRepository) Complete(ctx context.Context, rec model.IdempotencyRecord) error {
+	return r.store.CompleteIdempotencyRecord(ctx, rec)
+}
+
+func (r *idempotencyRepository) Release(ctx context.Context, route, key string) error {
+	return r.store.DeletePendingIdempotencyRecord(ctx, route, key)
+}
@@ -2,17 +2,35 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/morphlinkk/subscriptions/internal/apperr"
 	"github.com/morphlinkk/subscriptions/internal/db"
 	"github.com/morphlinkk/subscriptions/internal/model"
 )
 
 type SubscriptionRepository interface {
 	GetById(ctx context.Context, id int64) (*model.Subscription, error)
+	GetByIdAsOf(ctx context.Context, id int64, asOf time.Time) (*model.Subscription, error)
 	AddSubscription(ctx context.Context, params *model.AddSubscriptionParams) (*model.Subscription, error)
 	UpdateSubscription(ctx context.Context, id int64, params *model.UpdateSubscriptionParams) (*model.Subscription, error)
-	ListSubscriptions(ctx context.Context, params *model.ListSubscriptionsParams) ([]model.Subscription, error)
-	GetSumOfSubscriptionPrices(ctx context.Context, params *model.SumOfSubscriptionPricesParams) (int64, error)
+	DeleteSubscription(ctx context.Context, id int64) error
+	RestoreSubscription(ctx context.Context, id int64) (*model.Subscription, error)
+	PurgeSubscription(ctx context.Context, id int64) error
+	TransferSubscription(ctx context.Context, id int64, params *model.TransferSubscriptionParams) (*model.SubscriptionTransfer, error)
+	ListSubscriptions(ctx context.Context, params *model.ListSubscriptionsParams) (*model.SubscriptionPage, error)
+	SuggestServiceNames(ctx context.Context, params *model.SuggestServicesParams) ([]model.ServiceSuggestion, error)
+	ListSubscriptionsInPeriod(ctx context.Context, params *model.SumOfSubscriptionPricesParams) ([]model.Subscription, error)
+	ListPrices(ctx context.Context, ids []int64, asOf *time.Time) (map[int64][]model.SubscriptionPrice, error)
+	ChangeServicePrice(ctx context.Context, params *model.ServicePriceChangeParams) ([]model.Subscription, error)
+	ChangeStatus(ctx context.Context, id int64, params *model.StatusTransitionParams) (*model.Subscription, error)
+	ListPauses(ctx context.Context, ids []int64, asOf *time.Time) (map[int64][]model.SubscriptionPause, error)
+	ListStatusChanges(ctx context.Context, id int64) ([]model.StatusChange, error)
+	ConvertEndedTrials(ctx context.Context, today time.Time) ([]model.Subscription, error)
+	FlagEndingTrials(ctx context.Context, until time.Time) ([]model.Subscription, error)
+	ApplyDueTerms(ctx context.Context, month time.Time) ([]model.Subscription, error)
 }
 
 type subscriptionRepository struct {
@@ -28,39 +46,562 @@ func NewSubscriptionRepository(store *db.Store) SubscriptionRepository {
 func (r *subscriptionRepository) GetById(ctx context.Context, id int64) (*model.Subscription, error) {
 	s, err := r.store.GetSubscriptionById(ctx, id)
 	if err != nil {
-		return nil, err
+		return nil, mapError(err, "subscription")
 	}
 	return &s, nil
 }
 
+func (r *subscriptionRepository) GetByIdAsOf(ctx context.Context, id int64, asOf time.Time) (*model.Subscription, error) {
+	s, err := r.store.GetSubscriptionAsOf(ctx, id, asOf)
+	if err != nil {
+		return nil, mapError(err, "subscription")
+	}
+	return &s, nil
+}
+
+// AddSubscription records the price history along with the subscription: a
+// trial is charged params.TrialPrice until it ends and the price afterwards.
 func (r *subscriptionRepository) AddSubscription(ctx context.Context, params *model.AddSubscriptionParams) (*model.Subscription, error) {
-	s, err := r.store.AddSubscription(ctx, *params)
+	var s model.Subscription
+	err := r.store.ExecTx(ctx, func(q *db.Queries) error {
+		var err error
+		if s, err = q.AddSubscription(ctx, *params); err != nil {
+			return err
+		}
+		if s.InTrial(s.StartDate) {
+			if err := q.AddSubscriptionPrice(ctx, s.ID, params.TrialPrice, s.Billing, s.StartDate); err != nil {
+				return err
+			}
+		}
+		if err := q.AddSubscriptionPrice(ctx, s.ID, s.Price, s.Billing, s.PaidFrom()); err != nil {
+			return err
+		}
+		return recordChange(ctx, q, model.AuditCreate, nil, &s)
+	})
 	if err != nil {
-		return nil, err
+		return nil, mapError(err, "subscription")
 	}
 	return &s, nil
 }
 
+// UpdateSubscription locks the subscription before updating it so that the
+// audit entry holds the exact previous state. A current version outside
+// params.ExpectedVersions fails the update. A new price replaces the price
+// history from params.PriceEffectiveFrom, or from the first month after the
+// trial, on.
 func (r *subscriptionRepository) UpdateSubscription(ctx context.Context, id int64, params *model.UpdateSubscriptionParams) (*model.Subscription, error) {
-	s, err := r.store.UpdateSubscription(ctx, id, *params)
+	var s model.Subscription
+	err := r.store.ExecTx(ctx, func(q *db.Queries) error {
+		before, err := q.GetSubscriptionForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		update := *params
+		var billing *model.BillingInterval
+		if params.BillingUnit != nil || params.BillingCount != nil || params.BillingAnchorDay != nil {
+			b := model.MergeBilling(before.Billing, *params)
+			billing = &b
+		}
+		from := before.PaidFrom()
+		if params.PriceEffectiveFrom != nil {
+			from = *params.PriceEffectiveFrom
+		}
+		if from.After(model.EffectiveFrom(before, params.CurrentMonth)) {
+			// Terms of a later month are listed once it comes.
+			update.Price = nil
+			update.BillingUnit, update.BillingCount, update.BillingAnchorDay = nil, nil, nil
+		}
+
+		s, err = q.UpdateSubscription(ctx, id, update)
+		if errors.Is(err, pgx.ErrNoRows) && params.ExpectedVersions != nil {
+			return apperr.PreconditionFailed("subscription %d has changed, its current version is %d", id, before.Version)
+		}
+		if err != nil {
+			return err
+		}
+
+		if params.Price != nil || billing != nil {
+			if err := setTermsFrom(ctx, q, s, params.Price, billing, from); err != nil {
+				return err
+			}
+		}
+		return recordChange(ctx, q, model.AuditUpdate, &before, &s)
+	})
 	if err != nil {
-		return nil, err
+		return nil, mapError(err, "subscription")
+	}
+	return &s, nil
+}
+
+func (r *subscriptionRepository) DeleteSubscription(ctx context.Context, id int64) error {
+	err := r.store.ExecTx(ctx, func(q *db.Queries) error {
+		s, err := q.SoftDeleteSubscription(ctx, id)
+		if err != nil {
+			return err
+		}
+		// The returned row already has its version bumped; the audit entry
+		// shows the subscription as it was before the delete.
+		s.Version--
+		return recordChange(ctx, q, model.AuditDelete, &s, nil)
+	})
+	return mapError(err, "subscription")
+}
+
+func (r *subscriptionRepository) RestoreSubscription(ctx context.Context, id int64) (*model.Subscription, error) {
+	var s model.Subscription
+	err := r.store.ExecTx(ctx, func(q *db.Queries) error {
+		var err error
+		if s, err = q.RestoreSubscription(ctx, id); err != nil {
+			return err
+		}
+		return recordChange(ctx, q, model.AuditRestore, nil, &s)
+	})
+	if err != nil {
+		return nil, mapError(err, "deleted subscription")
 	}
 	return &s, nil
 }
 
-func (r *subscriptionRepository) ListSubscriptions(ctx context.Context, params *model.ListSubscriptionsParams) ([]model.Subscription, error) {
-	s, err := r.store.ListSubscriptions(ctx, *params)
+func (r *subscriptionRepository) PurgeSubscription(ctx context.Context, id int64) error {
+	err := r.store.ExecTx(ctx, func(q *db.Queries) error {
+		s, err := q.PurgeSubscription(ctx, id)
+		if err != nil {
+			return err
+		}
+		return recordChange(ctx, q, model.AuditPurge, &s, nil)
+	})
+	return mapError(err, "subscription")
+}
+
+// TransferSubscription closes subscription id at the month before
+// params.EffectiveMonth and opens a copy for params.UserID from that month to
+// the original end date, in one transaction. The copy takes over the prices
+// from params.EffectiveMonth on.
+func (r *subscriptionRepository) TransferSubscription(ctx context.Context, id int64, params *model.TransferSubscriptionParams) (*model.SubscriptionTransfer, error) {
+	var t model.SubscriptionTransfer
+	err := r.store.ExecTx(ctx, func(q *db.Queries) error {
+		lastMonth := params.EffectiveMonth.AddDate(0, -1, 0)
+		from, oldEnd, err := q.CloseSubscriptionForTransfer(ctx, id, lastMonth, params.UserID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return apperr.Conflict("subscription %d cannot be transferred from %s", id, params.EffectiveMonth.Format("01-2006"))
+		}
+		if err != nil {
+			return err
+		}
+
+		to, err := q.AddSubscription(ctx, model.AddSubscriptionParams{
+			Service:         from.Service,
+			ServiceID:       from.ServiceID,
+			Price:           from.Price,
+			Currency:        from.Currency,
+			Billing:         from.Billing,
+			UserID:          params.UserID,
+			StartDate:       params.EffectiveMonth,
+			EndDate:         oldEnd,
+			Status:          model.StatusActive,
+			TransferredFrom: &from.ID,
+		})
+		if err != nil {
+			return err
+		}
+		if err := copyPricesFrom(ctx, q, from.ID, to.ID, params.EffectiveMonth); err != nil {
+			return err
+		}
+		if err := copyPausesFrom(ctx, q, from.ID, to.ID, params.EffectiveMonth); err != nil {
+			return err
+		}
+
+		before := from
+		before.EndDate = oldEnd
+		before.Version--
+		if err := recordChange(ctx, q, model.AuditTransferOut, &before, &from); err != nil {
+			return err
+		}
+		if err := recordChange(ctx, q, model.AuditTransferIn, nil, &to); err != nil {
+			return err
+		}
+
+		t = model.SubscriptionTransfer{From: from, To: to}
+		return nil
+	})
+	if err != nil {
+		return nil, mapError(err, "subscription")
+	}
+	return &t, nil
+}
+
+// setTermsFrom replaces the price of subscription s in month from and every
+// later month when price is set, and likewise its billing interval when
+// billing is set. The terms left unset keep following the price history.
+func setTermsFrom(ctx context.Context, q *db.Queries, s model.Subscription, price *int, billing *model.BillingInterval, from time.Time) error {
+	prices, err := q.ListSubscriptionPrices(ctx, []int64{s.ID}, nil)
+	if err != nil {
+		return err
+	}
+	if len(prices) == 0 {
+		prices = []model.SubscriptionPrice{{Price: s.Price, Billing: s.Billing, EffectiveFrom: from}}
+	}
+	if err := q.RemoveSubscriptionPricesFrom(ctx, s.ID, from); err != nil {
+		return err
+	}
+
+	var last model.SubscriptionPrice
+	added := false
+	for i, p := range prices {
+		if i+1 < len(prices) && !prices[i+1].EffectiveFrom.After(from) {
+			continue
+		}
+		if p.EffectiveFrom.Before(from) {
+			p.EffectiveFrom = from
+		}
+		if price != nil {
+			p.Price = *price
+		}
+		if billing != nil {
+			p.Billing = *billing
+		}
+		if added && last.Price == p.Price && last.Billing == p.Billing {
+			continue
+		}
+		if err := q.AddSubscriptionPrice(ctx, s.ID, p.Price, p.Billing, p.EffectiveFrom); err != nil {
+			return err
+		}
+		last, added = p, true
+	}
+	return nil
+}
+
+// copyPricesFrom gives subscription to the prices subscription from has in
+// month and later, starting with the price that applies in month.
+func copyPricesFrom(ctx context.Context, q *db.Queries, from, to int64, month time.Time) error {
+	prices, err := q.ListSubscriptionPrices(ctx, []int64{from}, nil)
+	if err != nil {
+		return err
+	}
+
+	for i, p := range prices {
+		if i+1 < len(prices) && !prices[i+1].EffectiveFrom.After(month) {
+			continue
+		}
+		effectiveFrom := p.EffectiveFrom
+		if effectiveFrom.Before(month) {
+			effectiveFrom = month
+		}
+		if err := q.AddSubscriptionPrice(ctx, to, p.Price, p.Billing, effectiveFrom); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyPausesFrom gives subscription to the pauses subscription from has in
+// month and later, clipped to start in month.
+func copyPausesFrom(ctx context.Context, q *db.Queries, from, to int64, month time.Time) error {
+	pauses, err := q.ListSubscriptionPauses(ctx, []int64{from}, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range pauses {
+		if p.ResumedFrom != nil && !p.ResumedFrom.After(month) {
+			continue
+		}
+		if p.PausedFrom.Before(month) {
+			p.PausedFrom = month
+		}
+		p.SubscriptionID = to
+		if err := q.AddSubscriptionPause(ctx, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListSubscriptions fetches one row past the limit to tell whether another
+// page follows.
+func (r *subscriptionRepository) ListSubscriptions(ctx context.Context, params *model.ListSubscriptionsParams) (*model.SubscriptionPage, error) {
+	if params.After != nil {
+		if db.ValidateCursor(*params.After, params.Sort) != nil {
+			return nil, apperr.InvalidField("cursor", apperr.CodeInvalid, "cursor is invalid")
+		}
+	}
+
+	query := *params
+	query.Limit = params.Limit + 1
+
+	s, err := r.store.ListSubscriptions(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &model.SubscriptionPage{Items: s, Limit: params.Limit}
+	if len(s) > params.Limit {
+		page.Items = s[:params.Limit]
+		next, err := db.NewSubscriptionCursor(page.Items[len(page.Items)-1], params.Sort)
+		if err != nil {
+			return nil, err
+		}
+		page.Next = next
+	}
+
+	if params.IncludeTotal {
+		total, err := r.store.CountSubscriptions(ctx, *params)
+		if err != nil {
+			return nil, err
+		}
+		page.Total = &total
+	}
+
+	return page, nil
+}
+
+func (r *subscriptionRepository) SuggestServiceNames(ctx context.Context, params *model.SuggestServicesParams) ([]model.ServiceSuggestion, error) {
+	s, err := r.store.SuggestServiceNames(ctx, *params)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (r *subscriptionRepository) ListSubscriptionsInPeriod(ctx context.Context, params *model.SumOfSubscriptionPricesParams) ([]model.Subscription, error) {
+	s, err := r.store.ListSubscriptionsInPeriod(ctx, *params)
 	if err != nil {
 		return nil, err
 	}
 	return s, nil
 }
 
-func (r *subscriptionRepository) GetSumOfSubscriptionPrices(ctx context.Context, params *model.SumOfSubscriptionPricesParams) (int64, error) {
-	s, err := r.store.SumOfSubscriptionPrices(ctx, *params)
+// ListPrices returns the price history of each of the subscriptions ids, as
+// recorded at asOf when set. Subscriptions without prices are left out.
+func (r *subscriptionRepository) ListPrices(ctx context.Context, ids []int64, asOf *time.Time) (map[int64][]model.SubscriptionPrice, error) {
+	prices, err := r.store.ListSubscriptionPrices(ctx, ids, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int64][]model.SubscriptionPrice, len(ids))
+	for _, p := range prices {
+		byID[p.SubscriptionID] = append(byID[p.SubscriptionID], p)
+	}
+	return byID, nil
+}
+
+// ChangeServicePrice applies params.NewPrice to every matching subscription
+// in one transaction, auditing each of them, and returns them updated. A dry
+// run returns them unchanged. Subscriptions that only pay the new price after
+// params.CurrentMonth keep listing their current one until ApplyDueTerms.
+func (r *subscriptionRepository) ChangeServicePrice(ctx context.Context, params *model.ServicePriceChangeParams) ([]model.Subscription, error) {
+	var changed []model.Subscription
+	err := r.store.ExecTx(ctx, func(q *db.Queries) error {
+		subs, err := q.ListSubscriptionsForPriceChange(ctx, params.Service, params.UserID, params.EffectiveMonth, params.OldPrice, params.Currency)
+		if err != nil {
+			return err
+		}
+		if params.DryRun {
+			changed = subs
+			return nil
+		}
+
+		changed = make([]model.Subscription, 0, len(subs))
+		for i := range subs {
+			var update model.UpdateSubscriptionParams
+			from := model.EffectiveFrom(subs[i], params.EffectiveMonth)
+			if !from.After(model.EffectiveFrom(subs[i], params.CurrentMonth)) {
+				update.Price = &params.NewPrice
+			}
+			s, err := q.UpdateSubscription(ctx, subs[i].ID, update)
+			if err != nil {
+				return err
+			}
+			if err := setTermsFrom(ctx, q, s, &params.NewPrice, nil, from); err != nil {
+				return err
+			}
+			if err := recordChange(ctx, q, model.AuditUpdate, &subs[i], &s); err != nil {
+				return err
+			}
+			changed = append(changed, s)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, mapError(err, "subscription")
+	}
+	return changed, nil
+}
+
+// ApplyDueTerms lists on each subscription the price and billing interval its
+// price history has for month, or for its first paid month when that is
+// later, in one transaction, auditing each change, and returns the changed
+// subscriptions.
+func (r *subscriptionRepository) ApplyDueTerms(ctx context.Context, month time.Time) ([]model.Subscription, error) {
+	var changed []model.Subscription
+	err := r.store.ExecTx(ctx, func(q *db.Queries) error {
+		subs, terms, err := q.ListSubscriptionsWithDueTerms(ctx, month)
+		if err != nil {
+			return err
+		}
+
+		changed = make([]model.Subscription, 0, len(subs))
+		for i := range subs {
+			s, err := q.UpdateSubscription(ctx, subs[i].ID, model.UpdateSubscriptionParams{
+				Price:            &terms[i].Price,
+				BillingUnit:      &terms[i].Billing.Unit,
+				BillingCount:     &terms[i].Billing.Count,
+				BillingAnchorDay: &terms[i].Billing.AnchorDay,
+			})
+			if err != nil {
+				return err
+			}
+			if err := recordChange(ctx, q, model.AuditUpdate, &subs[i], &s); err != nil {
+				return err
+			}
+			changed = append(changed, s)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, mapError(err, "subscription")
+	}
+	return changed, nil
+}
+
+// ChangeStatus applies a status transition under a lock on the subscription,
+// together with its end date and pauses, and records it. It fails with a
+// conflict when the subscription has changed since params.Version, as the
+// transition was decided on that version's status, end date and pauses.
+func (r *subscriptionRepository) ChangeStatus(ctx context.Context, id int64, params *model.StatusTransitionParams) (*model.Subscription, error) {
+	var s model.Subscription
+	err := r.store.ExecTx(ctx, func(q *db.Queries) error {
+		before, err := q.GetSubscriptionForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if before.Version != params.Version {
+			return apperr.Conflict("subscription %d has changed, its current version is %d; retry to %s it",
+				id, before.Version, params.Action)
+		}
+
+		s, err = q.UpdateSubscription(ctx, id, model.UpdateSubscriptionParams{
+			Status:   &params.To,
+			EndDate:  params.EndDate,
+			CancelAt: params.CancelAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		if params.ResumeFrom != nil {
+			if err := q.ResumeSubscriptionPause(ctx, id, *params.ResumeFrom); err != nil {
+				return err
+			}
+		}
+		if params.Pause != nil {
+			if err := q.AddSubscriptionPause(ctx, *params.Pause); err != nil {
+				return err
+			}
+		}
+
+		change := params.StatusChange
+		change.SubscriptionID = id
+		if _, err := q.AddSubscriptionStatusChange(ctx, change); err != nil {
+			return err
+		}
+		return recordChange(ctx, q, model.AuditUpdate, &before, &s)
+	})
+	if err != nil {
+		return nil, mapError(err, "subscription")
+	}
+	return &s, nil
+}
+
+// ListPauses returns the pauses of each of the subscriptions ids, as recorded
+// at asOf when set. Subscriptions without pauses are left out.
+func (r *subscriptionRepository) ListPauses(ctx context.Context, ids []int64, asOf *time.Time) (map[int64][]model.SubscriptionPause, error) {
+	pauses, err := r.store.ListSubscriptionPauses(ctx, ids, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int64][]model.SubscriptionPause, len(ids))
+	for _, p := range pauses {
+		byID[p.SubscriptionID] = append(byID[p.SubscriptionID], p)
+	}
+	return byID, nil
+}
+
+func (r *subscriptionRepository) ListStatusChanges(ctx context.Context, id int64) ([]model.StatusChange, error) {
+	changes, err := r.store.ListSubscriptionStatusChanges(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// ConvertEndedTrials makes the trials whose last day is before today active
+// in one transaction, recording each conversion, and returns them.
+func (r *subscriptionRepository) ConvertEndedTrials(ctx context.Context, today time.Time) ([]model.Subscription, error) {
+	var converted []model.Subscription
+	err := r.store.ExecTx(ctx, func(q *db.Queries) error {
+		trials, err := q.ListEndedTrialsForUpdate(ctx, today)
+		if err != nil {
+			return err
+		}
+
+		converted = make([]model.Subscription, 0, len(trials))
+		active := model.StatusActive
+		for i := range trials {
+			s, err := q.UpdateSubscription(ctx, trials[i].ID, model.UpdateSubscriptionParams{Status: &active})
+			if err != nil {
+				return err
+			}
+			if _, err := q.AddSubscriptionStatusChange(ctx, model.StatusChange{
+				SubscriptionID: s.ID,
+				Action:         model.ActionConvert,
+				From:           model.StatusTrial,
+				To:             model.StatusActive,
+				EffectiveMonth: s.PaidFrom(),
+			}); err != nil {
+				return err
+			}
+			if err := recordChange(ctx, q, model.AuditUpdate, &trials[i], &s); err != nil {
+				return err
+			}
+			converted = append(converted, s)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, mapError(err, "subscription")
+	}
+	return converted, nil
+}
+
+// FlagEndingTrials flags the trials whose last day is until or earlier in one
+// transaction, recording each flag as a change, and returns them.
+func (r *subscriptionRepository) FlagEndingTrials(ctx context.Context, until time.Time) ([]model.Subscription, error) {
+	var flagged []model.Subscription
+	err := r.store.ExecTx(ctx, func(q *db.Queries) error {
+		trials, err := q.ListEndingTrialsForUpdate(ctx, until)
+		if err != nil {
+			return err
+		}
+
+		flagged = make([]model.Subscription, 0, len(trials))
+		for i := range trials {
+			s, err := q.FlagTrial(ctx, trials[i].ID)
+			if err != nil {
+				return err
+			}
+			if err := recordChange(ctx, q, model.AuditUpdate, &trials[i], &s); err != nil {
+				return err
+			}
+			flagged = append(flagged, s)
+		}
+		return nil
+	})
 	if err != nil {
-		return 0, err
+		return nil, mapError(err, "subscription")
 	}
-	return s, err
+	return flagged, nil
 }
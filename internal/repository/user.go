@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/morphlinkk/subscriptions/internal/apperr"
+	"github.com/morphlinkk/subscriptions/internal/db"
+	"github.com/morphlinkk/subscriptions/internal/model"
+)
+
+type UserRepository interface {
+	GetById(ctx context.Context, id uuid.UUID) (*model.User, error)
+	AddUser(ctx context.Context, params *model.AddUserParams) (*model.User, error)
+	UpdateUser(ctx context.Context, id uuid.UUID, params *model.UpdateUserParams) (*model.User, error)
+	DeleteUser(ctx context.Context, id uuid.UUID) error
+	ListUsers(ctx context.Context, params *model.ListUsersParams) ([]model.User, error)
+	SetCalendarToken(ctx context.Context, id uuid.UUID, hash []byte) error
+	GetCalendarToken(ctx context.Context, id uuid.UUID) ([]byte, error)
+}
+
+type userRepository struct {
+	store *db.Store
+}
+
+func NewUserRepository(store *db.Store) UserRepository {
+	return &userRepository{
+		store,
+	}
+}
+
+func (r *userRepository) GetById(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	u, err := r.store.GetUserById(ctx, id)
+	if err != nil {
+		return nil, mapError(err, "user")
+	}
+	return &u, nil
+}
+
+func (r *userRepository) AddUser(ctx context.Context, params *model.AddUserParams) (*model.User, error) {
+	u, err := r.store.AddUser(ctx, *params)
+	if err != nil {
+		return nil, mapError(err, "user")
+	}
+	return &u, nil
+}
+
+func (r *userRepository) UpdateUser(ctx context.Context, id uuid.UUID, params *model.UpdateUserParams) (*model.User, error) {
+	u, err := r.store.UpdateUser(ctx, id, *params)
+	if err != nil {
+		return nil, mapError(err, "user")
+	}
+	return &u, nil
+}
+
+func (r *userRepository) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	err := r.store.DeleteUser(ctx, id)
+	if pgErrorCode(err) == pgForeignKeyViolation {
+		return apperr.Wrap(err, apperr.KindConflict, "user still has subscriptions")
+	}
+	return mapError(err, "user")
+}
+
+func (r *userRepository) ListUsers(ctx context.Context, params *model.ListUsersParams) ([]model.User, error) {
+	u, err := r.store.ListUsers(ctx, *params)
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (r *userRepository) SetCalendarToken(ctx context.Context, id uuid.UUID, hash []byte) error {
+	return mapError(r.store.SetUserCalendarToken(ctx, id, hash), "user")
+}
+
+func (r *userRepository) GetCalendarToken(ctx context.Context, id uuid.UUID) ([]byte, error) {
+	hash, err := r.store.GetUserCalendarToken(ctx, id)
+	if err != nil {
+		return nil, mapError(err, "user")
+	}
+	return hash, nil
+}
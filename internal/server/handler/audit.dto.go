@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/morphlinkk/subscriptions/internal/apperr"
+	"github.com/morphlinkk/subscriptions/internal/model"
+)
+
+type AuditEntryResponse struct {
+	ID             int64           `json:"id"`
+	SubscriptionID int64           `json:"subscription_id"`
+	Operation      string          `json:"operation"`
+	Actor          string          `json:"actor"`
+	RequestID      *string         `json:"request_id"`
+	Before         json.RawMessage `json:"before" swaggertype:"object"`
+	After          json.RawMessage `json:"after" swaggertype:"object"`
+	CreatedAt      string          `json:"created_at"` // RFC 3339
+}
+
+func ToAuditEntryResponse(e model.SubscriptionAuditEntry) AuditEntryResponse {
+	before, after := e.Before, e.After
+	if before == nil {
+		before = jsonNull
+	}
+	if after == nil {
+		after = jsonNull
+	}
+
+	return AuditEntryResponse{
+		ID:             e.ID,
+		SubscriptionID: e.SubscriptionID,
+		Operation:      string(e.Operation),
+		Actor:          e.Actor,
+		RequestID:      e.RequestID,
+		Before:         before,
+		After:          after,
+		CreatedAt:      e.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+type ListAuditRequest struct {
+	SubscriptionID *int64  `form:"subscription_id"`
+	Actor          *string `form:"actor"`
+	Operation      *string `form:"operation"`
+	RequestID      *string `form:"request_id"`
+	From           *string `form:"from"` // RFC 3339
+	To             *string `form:"to"`   // RFC 3339
+	Limit          int     `form:"limit"`
+	Offset         int     `form:"offset"`
+}
+
+func (r ListAuditRequest) ToParams() (model.ListAuditParams, error) {
+	params := model.ListAuditParams{
+		SubscriptionID: r.SubscriptionID,
+		Actor:          r.Actor,
+		RequestID:      r.RequestID,
+		Limit:          r.Limit,
+		Offset:         r.Offset,
+	}
+
+	if r.Operation != nil {
+		op := model.AuditOperation(*r.Operation)
+		params.Operation = &op
+	}
+
+	var v apperr.Validator
+	if r.From != nil {
+		t, err := time.Parse(time.RFC3339, *r.From)
+		v.Check(err == nil, "from", apperr.CodeInvalidFormat, "from must be an RFC 3339 timestamp")
+		params.From = &t
+	}
+	if r.To != nil {
+		t, err := time.Parse(time.RFC3339, *r.To)
+		v.Check(err == nil, "to", apperr.CodeInvalidFormat, "to must be an RFC 3339 timestamp")
+		params.To = &t
+	}
+	if err := v.Err(); err != nil {
+		return model.ListAuditParams{}, err
+	}
+
+	return params, nil
+}
+
+type SubscriptionHistoryRequest struct {
+	Limit  int `form:"limit"`
+	Offset int `form:"offset"`
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/morphlinkk/subscriptions/internal/model"
+	"github.com/morphlinkk/subscriptions/internal/server/service"
+)
+
+type AuditHandler interface {
+	ListAudit(c *gin.Context)
+	GetSubscriptionHistory(c *gin.Context)
+}
+
+type auditHandler struct {
+	auditService service.AuditService
+}
+
+func NewAuditHandler(service service.AuditService) AuditHandler {
+	return &auditHandler{
+		auditService: service,
+	}
+}
+
+// ListAudit godoc
+// @Summary List audit entries
+// @Description Every change of every subscription, oldest first. Requires the admin token
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param subscription_id query int false "Filter by subscription ID"
+// @Param actor query string false "Filter by actor (X-Actor header of the change)"
+// @Param operation query string false "Filter by operation: create, update, delete, restore, purge, transfer_out, transfer_in"
+// @Param request_id query string false "Filter by request ID (X-Request-ID header of the change)"
+// @Param from query string false "Changes at or after this RFC 3339 timestamp"
+// @Param to query string false "Changes before this RFC 3339 timestamp"
+// @Param limit query int false "Pagination limit"
+// @Param offset query int false "Pagination offset"
+// @Success 200 {object} Response{data=[]AuditEntryResponse} "OK"
+// @Failure 400 {object} Response "Invalid query parameters"
+// @Failure 401 {object} Response "Missing admin token"
+// @Failure 403 {object} Response "Invalid admin token"
+// @Failure 422 {object} Response "Validation failed"
+// @Failure 500 {object} Response "Internal server error"
+// @Router /audit [get]
+func (h *auditHandler) ListAudit(c *gin.Context) {
+	var req ListAuditRequest
+	if err := c.BindQuery(&req); err != nil {
+		slog.Debug("invalid query params for ListAudit", "error", err)
+		JSONErrorMessage(c, http.StatusBadRequest, "invalid query parameters")
+		return
+	}
+
+	params, err := req.ToParams()
+	if err != nil {
+		slog.Debug("failed to parse ListAuditRequest", "error", err, "query", req)
+		JSONAppError(c, err)
+		return
+	}
+
+	entries, err := h.auditService.ListAudit(c.Request.Context(), params)
+	if err != nil {
+		slog.Debug("failed to list audit entries", "error", err)
+		JSONAppError(c, err)
+		return
+	}
+
+	JSONSuccess(c, http.StatusOK, toAuditEntryResponses(entries))
+}
+
+// GetSubscriptionHistory godoc
+// @Summary Get subscription history
+// @Description Every change of a subscription with its actor and before/after state, oldest first.
+// @Description History is kept after the subscription is purged. Requires the admin token
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param id path int true "Subscription ID"
+// @Param limit query int false "Pagination limit"
+// @Param offset query int false "Pagination offset"
+// @Success 200 {object} Response{data=[]AuditEntryResponse} "OK"
+// @Failure 400 {object} Response "Invalid ID or query parameters"
+// @Failure 401 {object} Response "Missing admin token"
+// @Failure 403 {object} Response "Invalid admin token"
+// @Failure 500 {object} Response "Internal server error"
+// @Router /subscriptions/{id}/history [get]
+func (h *auditHandler) GetSubscriptionHistory(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		slog.Debug("invalid subscription id param", "param", idStr, "error", err)
+		JSONErrorMessage(c, http.StatusBadRequest, "invalid subscription id")
+		return
+	}
+
+	var req SubscriptionHistoryRequest
+	if err := c.BindQuery(&req); err != nil {
+		slog.Debug("invalid query params for GetSubscriptionHistory", "error", err)
+		JSONErrorMessage(c, http.StatusBadRequest, "invalid query parameters")
+		return
+	}
+
+	entries, err := h.auditService.GetSubscriptionHistory(c.Request.Context(), id, req.Limit, req.Offset)
+	if err != nil {
+		slog.Debug("failed to get subscription history", "id", id, "error", err)
+		JSONAppError(c, err)
+		return
+	}
+
+	JSONSuccess(c, http.StatusOK, toAuditEntryResponses(entries))
+}
+
+func toAuditEntryResponses(entries []model.SubscriptionAuditEntry) []AuditEntryResponse {
+	responses := make([]AuditEntryResponse, len(entries))
+	for i, e := range entries {
+		responses[i] = ToAuditEntryResponse(e)
+	}
+	return responses
+}
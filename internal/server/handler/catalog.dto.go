@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/morphlinkk/subscriptions/internal/model"
+)
+
+type ServiceResponse struct {
+	ID           int64    `json:"id"`
+	Name         string   `json:"name"`
+	Aliases      []string `json:"aliases"`
+	Category     *string  `json:"category"`
+	Website      *string  `json:"website"`
+	DefaultPrice *int     `json:"default_price"` // minor units of currency
+	Currency     string   `json:"currency"`      // ISO 4217
+	CreatedAt    string   `json:"created_at"`    // RFC 3339
+}
+
+func ToServiceResponse(s model.Service) ServiceResponse {
+	aliases := s.Aliases
+	if aliases == nil {
+		aliases = []string{}
+	}
+
+	return ServiceResponse{
+		ID:           s.ID,
+		Name:         s.Name,
+		Aliases:      aliases,
+		Category:     s.Category,
+		Website:      s.Website,
+		DefaultPrice: s.DefaultPrice,
+		Currency:     s.Currency,
+		CreatedAt:    s.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+type AddServiceRequest struct {
+	Name         string   `json:"name" validate:"required"`
+	Aliases      []string `json:"aliases"`
+	Category     *string  `json:"category"`
+	Website      *string  `json:"website"`
+	DefaultPrice *int     `json:"default_price"`
+	Currency     string   `json:"currency"` // ISO 4217, default RUB
+}
+
+func (r AddServiceRequest) ToParams() model.AddServiceParams {
+	return model.AddServiceParams{
+		Name:         r.Name,
+		Aliases:      r.Aliases,
+		Category:     r.Category,
+		Website:      r.Website,
+		DefaultPrice: r.DefaultPrice,
+		Currency:     r.Currency,
+	}
+}
+
+// nullableField is a request member that tells an explicit null, which
+// clears the field, from an absent member, which leaves it alone.
+type nullableField[T any] struct {
+	model.Nullable[T]
+}
+
+func (f *nullableField[T]) UnmarshalJSON(data []byte) error {
+	f.Set = true
+	if isNull(data) {
+		f.Value = nil
+		return nil
+	}
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	f.Value = &v
+	return nil
+}
+
+type UpdateServiceRequest struct {
+	Name         *string               `json:"name"`
+	Aliases      []string              `json:"aliases"`                             // replaces all aliases when present
+	Category     nullableField[string] `json:"category" swaggertype:"string"`       // null clears it
+	Website      nullableField[string] `json:"website" swaggertype:"string"`        // null clears it
+	DefaultPrice nullableField[int]    `json:"default_price" swaggertype:"integer"` // null clears it
+	Currency     *string               `json:"currency"`
+}
+
+func (r UpdateServiceRequest) ToParams() model.UpdateServiceParams {
+	return model.UpdateServiceParams{
+		Name:         r.Name,
+		Aliases:      r.Aliases,
+		Category:     r.Category.Nullable,
+		Website:      r.Website.Nullable,
+		DefaultPrice: r.DefaultPrice.Nullable,
+		Currency:     r.Currency,
+	}
+}
+
+type ListServicesRequest struct {
+	Category *string `form:"category"`
+	Limit    int     `form:"limit"`
+	Offset   int     `form:"offset"`
+}
+
+func (r ListServicesRequest) ToParams() model.ListServicesParams {
+	return model.ListServicesParams{
+		Category: r.Category,
+		Limit:    r.Limit,
+		Offset:   r.Offset,
+	}
+}
@@ -0,0 +1,198 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/morphlinkk/subscriptions/internal/server/service"
+)
+
+type CatalogHandler interface {
+	AddService(c *gin.Context)
+	GetServiceByID(c *gin.Context)
+	UpdateService(c *gin.Context)
+	DeleteService(c *gin.Context)
+	ListServices(c *gin.Context)
+}
+
+type catalogHandler struct {
+	catalogService service.CatalogService
+}
+
+func NewCatalogHandler(service service.CatalogService) CatalogHandler {
+	return &catalogHandler{
+		catalogService: service,
+	}
+}
+
+// AddService godoc
+// @Summary Add a catalog service
+// @Description Add a service with its canonical name and the aliases subscriptions may use for it
+// @Tags services
+// @Accept json
+// @Produce json
+// @Param service body AddServiceRequest true "Service info"
+// @Success 201 {object} Response{data=ServiceResponse} "Created"
+// @Failure 400 {object} Response "Invalid request"
+// @Failure 409 {object} Response "Name or alias already in use"
+// @Failure 422 {object} Response "Validation failed"
+// @Failure 500 {object} Response "Internal server error"
+// @Router /services [post]
+func (h *catalogHandler) AddService(c *gin.Context) {
+	var req AddServiceRequest
+	if err := c.BindJSON(&req); err != nil {
+		slog.Debug("invalid request body", "error", err)
+		JSONErrorMessage(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	svc, err := h.catalogService.AddService(c.Request.Context(), req.ToParams())
+	if err != nil {
+		slog.Debug("failed to add service", "error", err, "name", req.Name)
+		JSONAppError(c, err)
+		return
+	}
+
+	slog.Info("service created", "id", svc.ID, "name", svc.Name)
+	JSONSuccess(c, http.StatusCreated, ToServiceResponse(*svc))
+}
+
+// GetServiceByID godoc
+// @Summary Get catalog service by ID
+// @Tags services
+// @Accept json
+// @Produce json
+// @Param id path int true "Service ID"
+// @Success 200 {object} Response{data=ServiceResponse} "OK"
+// @Failure 400 {object} Response "Invalid ID"
+// @Failure 404 {object} Response "Not found"
+// @Failure 500 {object} Response "Internal server error"
+// @Router /services/{id} [get]
+func (h *catalogHandler) GetServiceByID(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		slog.Debug("invalid service id param", "param", idStr, "error", err)
+		JSONErrorMessage(c, http.StatusBadRequest, "invalid service id")
+		return
+	}
+
+	svc, err := h.catalogService.GetByID(c.Request.Context(), id)
+	if err != nil {
+		slog.Debug("failed to get service by id", "id", id, "error", err)
+		JSONAppError(c, err)
+		return
+	}
+
+	JSONSuccess(c, http.StatusOK, ToServiceResponse(*svc))
+}
+
+// UpdateService godoc
+// @Summary Update catalog service
+// @Description Update a service. Renaming it also renames the subscriptions linked to it
+// @Tags services
+// @Accept json
+// @Produce json
+// @Param id path int true "Service ID"
+// @Param service body UpdateServiceRequest true "Service update info"
+// @Success 200 {object} Response{data=ServiceResponse} "Updated"
+// @Failure 400 {object} Response "Invalid request or ID"
+// @Failure 404 {object} Response "Not found"
+// @Failure 409 {object} Response "Name or alias already in use"
+// @Failure 422 {object} Response "Validation failed"
+// @Failure 500 {object} Response "Internal server error"
+// @Router /services/{id} [patch]
+func (h *catalogHandler) UpdateService(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		slog.Debug("invalid service id param", "param", idStr, "error", err)
+		JSONErrorMessage(c, http.StatusBadRequest, "invalid service id")
+		return
+	}
+
+	var req UpdateServiceRequest
+	if err := c.BindJSON(&req); err != nil {
+		slog.Debug("invalid request body for update", "error", err)
+		JSONErrorMessage(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	svc, err := h.catalogService.UpdateService(c.Request.Context(), id, req.ToParams())
+	if err != nil {
+		slog.Debug("failed to update service", "id", id, "error", err)
+		JSONAppError(c, err)
+		return
+	}
+
+	slog.Info("service updated", "id", svc.ID)
+	JSONSuccess(c, http.StatusOK, ToServiceResponse(*svc))
+}
+
+// DeleteService godoc
+// @Summary Delete catalog service
+// @Description Delete a service. Linked subscriptions keep their service name and lose the link
+// @Tags services
+// @Accept json
+// @Produce json
+// @Param id path int true "Service ID"
+// @Success 204 "Deleted"
+// @Failure 400 {object} Response "Invalid ID"
+// @Failure 404 {object} Response "Not found"
+// @Failure 500 {object} Response "Internal server error"
+// @Router /services/{id} [delete]
+func (h *catalogHandler) DeleteService(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		slog.Debug("invalid service id param", "param", idStr, "error", err)
+		JSONErrorMessage(c, http.StatusBadRequest, "invalid service id")
+		return
+	}
+
+	if err := h.catalogService.DeleteService(c.Request.Context(), id); err != nil {
+		slog.Debug("failed to delete service", "id", id, "error", err)
+		JSONAppError(c, err)
+		return
+	}
+
+	slog.Info("service deleted", "id", id)
+	c.Status(http.StatusNoContent)
+}
+
+// ListServices godoc
+// @Summary List catalog services
+// @Tags services
+// @Accept json
+// @Produce json
+// @Param category query string false "Filter by category"
+// @Param limit query int false "Pagination limit"
+// @Param offset query int false "Pagination offset"
+// @Success 200 {object} Response{data=[]ServiceResponse} "OK"
+// @Failure 400 {object} Response "Invalid query parameters"
+// @Failure 500 {object} Response "Internal server error"
+// @Router /services [get]
+func (h *catalogHandler) ListServices(c *gin.Context) {
+	var req ListServicesRequest
+	if err := c.BindQuery(&req); err != nil {
+		slog.Debug("invalid query params for ListServices", "error", err)
+		JSONErrorMessage(c, http.StatusBadRequest, "invalid query parameters")
+		return
+	}
+
+	services, err := h.catalogService.ListServices(c.Request.Context(), req.ToParams())
+	if err != nil {
+		slog.Debug("failed to list services", "error", err)
+		JSONAppError(c, err)
+		return
+	}
+
+	responses := make([]ServiceResponse, len(services))
+	for i, s := range services {
+		responses[i] = ToServiceResponse(s)
+	}
+
+	JSONSuccess(c, http.StatusOK, responses)
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/morphlinkk/subscriptions/internal/model"
+)
+
+// SubscriptionETag returns the strong entity tag of s: its version followed by
+// its status, which is derived from the current day and can change without a
+// new version. A read as of a past moment also carries asOf.
+func SubscriptionETag(s model.Subscription, asOf *time.Time) string {
+	tag := strconv.Itoa(s.Version) + "-" + string(s.Status)
+	if asOf != nil {
+		tag += "-" + asOf.UTC().Format(time.RFC3339Nano)
+	}
+	return `"` + tag + `"`
+}
+
+// SetETag writes the ETag header.
+func SetETag(c *gin.Context, etag string) {
+	c.Header("ETag", etag)
+}
+
+// splitETags splits an If-Match or If-None-Match header into its entity tags.
+func splitETags(header string) []string {
+	var tags []string
+	for _, t := range strings.Split(header, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// IfMatchVersions returns the versions the entity tags in the If-Match header
+// start with. Updates are conditional on the version alone, as the status in a
+// tag follows from it and the current day. It returns nil when the header is
+// missing or "*", meaning any current version is acceptable. Weak and
+// malformed tags never match, so a header made only of them yields an empty,
+// non-nil slice.
+func IfMatchVersions(c *gin.Context) []int {
+	header := c.GetHeader("If-Match")
+	if header == "" || strings.TrimSpace(header) == "*" {
+		return nil
+	}
+
+	versions := []int{}
+	for _, tag := range splitETags(header) {
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		version, _, _ := strings.Cut(tag[1:len(tag)-1], "-")
+		v, err := strconv.Atoi(version)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, v)
+	}
+	return versions
+}
+
+// IfNoneMatch reports whether the If-None-Match header matches etag, using
+// the weak comparison RFC 9110 prescribes for it.
+func IfNoneMatch(c *gin.Context, etag string) bool {
+	header := c.GetHeader("If-None-Match")
+	if header == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	for _, tag := range splitETags(header) {
+		if strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
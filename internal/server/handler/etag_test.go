@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/morphlinkk/subscriptions/internal/model"
+)
+
+func TestSubscriptionETag(t *testing.T) {
+	asOf := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.FixedZone("MSK", 3*60*60))
+	sub := model.Subscription{Version: 3, Status: model.StatusPendingCancel}
+
+	if got, want := SubscriptionETag(sub, nil), `"3-pending_cancel"`; got != want {
+		t.Errorf("SubscriptionETag() = %s, want %s", got, want)
+	}
+	if got, want := SubscriptionETag(sub, &asOf), `"3-pending_cancel-2026-03-01T09:00:00Z"`; got != want {
+		t.Errorf("SubscriptionETag() as of = %s, want %s", got, want)
+	}
+	sub.Status = model.StatusCanceled
+	if SubscriptionETag(sub, nil) == `"3-pending_cancel"` {
+		t.Error("SubscriptionETag() does not change with the derived status")
+	}
+}
+
+func TestIfMatchVersions(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []int
+	}{
+		{"", nil},
+		{"*", nil},
+		{`"3-active"`, []int{3}},
+		{`"3-active", "4-paused"`, []int{3, 4}},
+		{`"3"`, []int{3}},
+		{`"3-active-2026-03-01T09:00:00Z"`, []int{3}},
+		{`W/"3-active"`, []int{}},
+		{`active`, []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("PATCH", "/subscriptions/1", nil)
+			c.Request.Header.Set("If-Match", tt.header)
+			if got := IfMatchVersions(c); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("IfMatchVersions(%s) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
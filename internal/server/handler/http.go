@@ -1,15 +1,45 @@
 package handler
 
 import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
 	"github.com/gin-gonic/gin"
+	"github.com/morphlinkk/subscriptions/internal/apperr"
+	"github.com/morphlinkk/subscriptions/internal/reqctx"
 )
 
 type Response struct {
 	Success bool   `json:"success"`
 	Data    any    `json:"data,omitempty"`
+	Meta    any    `json:"meta,omitempty"`
 	Error   string `json:"error,omitempty"`
 }
 
+const problemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object. Code is an extension member
+// holding the same machine-readable value the type URI ends with.
+type Problem struct {
+	Type     string         `json:"type"`
+	Title    string         `json:"title"`
+	Status   int            `json:"status"`
+	Detail   string         `json:"detail,omitempty"`
+	Instance string         `json:"instance,omitempty"`
+	Code     string         `json:"code"`
+	Errors   []FieldProblem `json:"errors,omitempty"`
+}
+
+type FieldProblem struct {
+	Field  string `json:"field"`
+	Code   string `json:"code"`
+	Detail string `json:"detail"`
+}
+
 func JSONSuccess(c *gin.Context, status int, data any) {
 	c.JSON(status, Response{
 		Success: true,
@@ -17,16 +47,128 @@ func JSONSuccess(c *gin.Context, status int, data any) {
 	})
 }
 
-func JSONError(c *gin.Context, status int, err error) {
+func JSONSuccessWithMeta(c *gin.Context, status int, data any, meta any) {
 	c.JSON(status, Response{
-		Success: false,
-		Error:   err.Error(),
+		Success: true,
+		Data:    data,
+		Meta:    meta,
 	})
 }
 
+func JSONError(c *gin.Context, status int, err error) {
+	JSONErrorMessage(c, status, err.Error())
+}
+
 func JSONErrorMessage(c *gin.Context, status int, msg string) {
+	if wantsProblem(c) {
+		writeProblem(c, newProblem(c, status, codeForStatus(status), msg))
+		return
+	}
+
 	c.JSON(status, Response{
 		Success: false,
 		Error:   msg,
 	})
 }
+
+// StatusFor maps a domain error kind to its HTTP status.
+func StatusFor(kind apperr.Kind) int {
+	switch kind {
+	case apperr.KindNotFound:
+		return http.StatusNotFound
+	case apperr.KindValidation:
+		return http.StatusUnprocessableEntity
+	case apperr.KindConflict:
+		return http.StatusConflict
+	case apperr.KindForbidden:
+		return http.StatusForbidden
+	case apperr.KindPreconditionFailed:
+		return http.StatusPreconditionFailed
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// JSONAppError writes err with the status of its domain error kind. Errors
+// that are not domain errors are logged and reported as 500 without leaking
+// their message.
+func JSONAppError(c *gin.Context, err error) {
+	appErr, ok := apperr.As(err)
+	if !ok || appErr.Kind == apperr.KindInternal {
+		slog.Error("internal error", "method", c.Request.Method, "path", c.FullPath(),
+			"request_id", reqctx.RequestID(c.Request.Context()), "error", err)
+		JSONErrorMessage(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	status := StatusFor(appErr.Kind)
+	if !wantsProblem(c) {
+		JSONError(c, status, err)
+		return
+	}
+
+	p := newProblem(c, status, appErr.Kind.String(), appErr.Message)
+	for _, f := range appErr.Fields {
+		p.Errors = append(p.Errors, FieldProblem{
+			Field:  f.Field,
+			Code:   f.Code,
+			Detail: f.Message,
+		})
+	}
+	writeProblem(c, p)
+}
+
+// SetNextLink adds an RFC 8288 Link header pointing at the page that starts
+// after cursor. The current query is kept, except for offset which does not
+// apply to cursor pages.
+func SetNextLink(c *gin.Context, cursor string, limit int) {
+	q := c.Request.URL.Query()
+	q.Del("offset")
+	q.Set("cursor", cursor)
+	q.Set("limit", strconv.Itoa(limit))
+
+	next := url.URL{Path: c.Request.URL.Path, RawQuery: q.Encode()}
+	c.Header("Link", fmt.Sprintf(`<%s>; rel="next"`, next.String()))
+}
+
+// wantsProblem reports whether the client prefers problem+json over the
+// regular response envelope.
+func wantsProblem(c *gin.Context) bool {
+	return c.NegotiateFormat(gin.MIMEJSON, problemContentType) == problemContentType
+}
+
+func newProblem(c *gin.Context, status int, code, detail string) Problem {
+	return Problem{
+		Type:     "/problems/" + strings.ReplaceAll(code, "_", "-"),
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: c.Request.URL.Path,
+		Code:     code,
+	}
+}
+
+func writeProblem(c *gin.Context, p Problem) {
+	c.Header("Content-Type", problemContentType)
+	c.JSON(p.Status, p)
+}
+
+// codeForStatus derives a problem code for errors that carry no domain kind,
+// e.g. http.StatusBadRequest becomes "bad_request".
+func codeForStatus(status int) string {
+	switch status {
+	case http.StatusNotFound:
+		return apperr.KindNotFound.String()
+	case http.StatusUnprocessableEntity:
+		return apperr.KindValidation.String()
+	case http.StatusConflict:
+		return apperr.KindConflict.String()
+	case http.StatusForbidden:
+		return apperr.KindForbidden.String()
+	case http.StatusPreconditionFailed:
+		return apperr.KindPreconditionFailed.String()
+	case http.StatusInternalServerError:
+		return apperr.KindInternal.String()
+	}
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
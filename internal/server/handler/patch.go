@@ -0,0 +1,302 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/morphlinkk/subscriptions/internal/apperr"
+	"github.com/morphlinkk/subscriptions/internal/model"
+)
+
+const (
+	// MIMEMergePatch is RFC 7396 JSON Merge Patch.
+	MIMEMergePatch = "application/merge-patch+json"
+	// MIMEJSONPatch is RFC 6902 JSON Patch.
+	MIMEJSONPatch = "application/json-patch+json"
+)
+
+// errInvalidBody marks update bodies that are not well-formed JSON of the
+// expected shape; handlers answer them with 400.
+var errInvalidBody = errors.New("invalid patch document")
+
+// patchDocument is a JSON object keyed by its top-level members.
+type patchDocument map[string]json.RawMessage
+
+var jsonNull = json.RawMessage("null")
+
+func isNull(v json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(v), jsonNull)
+}
+
+// jsonEqual compares two JSON values structurally, ignoring formatting and
+// member order.
+func jsonEqual(a, b json.RawMessage) bool {
+	var va, vb any
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// decodeMergePatch parses an RFC 7396 merge patch. The document must be a
+// JSON object.
+func decodeMergePatch(body []byte) (patchDocument, error) {
+	var doc patchDocument
+	if err := json.Unmarshal(body, &doc); err != nil || doc == nil {
+		return nil, errInvalidBody
+	}
+	return doc, nil
+}
+
+// readOnlySubscriptionFields are members of SubscriptionResponse that patches
+// cannot change.
+var readOnlySubscriptionFields = map[string]bool{
+	"id":                   true,
+	"service_id":           true,
+	"start_date":           true,
+	"transferred_from":     true,
+	"relevance":            true,
+	"version":              true,
+	"monthly_equivalent":   true,
+	"annualized_cost":      true,
+	"currency":             true,
+	"status":               true,
+	"status_changed_at":    true,
+	"trial_end":            true,
+	"trial_flagged_at":     true,
+	"cancel_at_period_end": true,
+	"cancel_at":            true,
+}
+
+// mergePatchToUpdateParams converts a merge patch of a SubscriptionResponse
+// into update params. A missing member is left alone and null clears it,
+// which only end_date allows. effective_from is not a member of subscriptions
+// but qualifies a price or billing change, as in UpdateSubscriptionRequest;
+// null leaves it unset.
+func mergePatchToUpdateParams(patch patchDocument) (model.UpdateSubscriptionParams, error) {
+	var params model.UpdateSubscriptionParams
+	var v apperr.Validator
+
+	fields := make([]string, 0, len(patch))
+	for field := range patch {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		raw := patch[field]
+		switch {
+		case readOnlySubscriptionFields[field]:
+			v.Add(field, apperr.CodeInvalid, field+" is read-only")
+			continue
+		case isNull(raw):
+			if field == "end_date" {
+				params.EndDate = model.Nullable[time.Time]{Set: true}
+			} else if field == "effective_from" {
+				continue
+			} else if isPatchableSubscriptionField(field) {
+				v.Add(field, apperr.CodeRequired, field+" cannot be cleared")
+			} else {
+				v.Add(field, apperr.CodeUnknown, "unknown field "+field)
+			}
+			continue
+		}
+
+		switch field {
+		case "service_name":
+			var name string
+			if json.Unmarshal(raw, &name) != nil {
+				v.Add(field, apperr.CodeInvalidFormat, "service_name must be a string")
+				continue
+			}
+			params.Service = &name
+		case "price":
+			var price int
+			if json.Unmarshal(raw, &price) != nil {
+				v.Add(field, apperr.CodeInvalidFormat, "price must be an integer")
+				continue
+			}
+			params.Price = &price
+		case "billing_interval":
+			var unit string
+			if json.Unmarshal(raw, &unit) != nil {
+				v.Add(field, apperr.CodeInvalidFormat, "billing_interval must be a string")
+				continue
+			}
+			params.BillingUnit = (*model.BillingUnit)(&unit)
+		case "billing_interval_count", "billing_anchor_day":
+			var n int
+			if json.Unmarshal(raw, &n) != nil {
+				v.Add(field, apperr.CodeInvalidFormat, field+" must be an integer")
+				continue
+			}
+			if field == "billing_interval_count" {
+				params.BillingCount = &n
+			} else {
+				params.BillingAnchorDay = &n
+			}
+		case "user_id":
+			var s string
+			if json.Unmarshal(raw, &s) != nil {
+				v.Add(field, apperr.CodeInvalidFormat, "user_id must be a valid UUID")
+				continue
+			}
+			uid, err := parseUUID(field, s)
+			if err != nil {
+				v.Add(field, apperr.CodeInvalidFormat, "user_id must be a valid UUID")
+				continue
+			}
+			params.UserID = &uid
+		case "end_date":
+			var s string
+			if json.Unmarshal(raw, &s) != nil {
+				v.Add(field, apperr.CodeInvalidFormat, "end_date must be in MM-YYYY format")
+				continue
+			}
+			t, err := parseMonth(field, s)
+			if err != nil {
+				v.Add(field, apperr.CodeInvalidFormat, "end_date must be in MM-YYYY format")
+				continue
+			}
+			params.EndDate = model.NullableOf(t)
+		case "effective_from":
+			var s string
+			if json.Unmarshal(raw, &s) != nil {
+				v.Add(field, apperr.CodeInvalidFormat, "effective_from must be in MM-YYYY format")
+				continue
+			}
+			t, err := parseMonth(field, s)
+			if err != nil {
+				v.Add(field, apperr.CodeInvalidFormat, "effective_from must be in MM-YYYY format")
+				continue
+			}
+			params.PriceEffectiveFrom = &t
+		default:
+			v.Add(field, apperr.CodeUnknown, "unknown field "+field)
+		}
+	}
+
+	return params, v.Err()
+}
+
+func isPatchableSubscriptionField(field string) bool {
+	switch field {
+	case "service_name", "price", "billing_interval", "billing_interval_count", "billing_anchor_day", "user_id", "end_date":
+		return true
+	}
+	return false
+}
+
+// jsonPatchOp is one RFC 6902 operation. Value stays nil when the member is
+// missing and holds "null" when it is an explicit null.
+type jsonPatchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// decodeJSONPatch parses an RFC 6902 patch, which must be a JSON array.
+func decodeJSONPatch(body []byte) ([]jsonPatchOp, error) {
+	var ops []jsonPatchOp
+	if err := json.Unmarshal(body, &ops); err != nil || ops == nil {
+		return nil, errInvalidBody
+	}
+	return ops, nil
+}
+
+// patchMember returns the member name addressed by an RFC 6901 pointer.
+// Subscriptions are flat, so only top-level members can be addressed.
+func patchMember(field, pointer string) (string, error) {
+	if !strings.HasPrefix(pointer, "/") || strings.Count(pointer, "/") != 1 || pointer == "/" {
+		return "", apperr.InvalidField(field, apperr.CodeInvalid, field+" must address a top-level member, e.g. /end_date")
+	}
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(pointer[1:]), nil
+}
+
+// applyJSONPatch applies ops to doc in order. Any failing operation aborts
+// the whole patch; a failed test is reported as a conflict.
+func applyJSONPatch(doc patchDocument, ops []jsonPatchOp) error {
+	for i, op := range ops {
+		key, err := patchMember("path", op.Path)
+		if err != nil {
+			return err
+		}
+		_, exists := doc[key]
+
+		switch op.Op {
+		case "add", "replace":
+			if op.Value == nil {
+				return apperr.InvalidField("value", apperr.CodeRequired, op.Op+" requires a value")
+			}
+			if op.Op == "replace" && !exists {
+				return apperr.InvalidField("path", apperr.CodeInvalid, op.Path+" does not exist")
+			}
+			doc[key] = op.Value
+		case "remove":
+			if !exists {
+				return apperr.InvalidField("path", apperr.CodeInvalid, op.Path+" does not exist")
+			}
+			delete(doc, key)
+		case "test":
+			if op.Value == nil {
+				return apperr.InvalidField("value", apperr.CodeRequired, "test requires a value")
+			}
+			if !exists || !jsonEqual(doc[key], op.Value) {
+				return apperr.Conflict("test operation %d failed: %s does not match", i, op.Path)
+			}
+		case "move", "copy":
+			from, err := patchMember("from", op.From)
+			if err != nil {
+				return err
+			}
+			v, ok := doc[from]
+			if !ok {
+				return apperr.InvalidField("from", apperr.CodeInvalid, op.From+" does not exist")
+			}
+			if op.Op == "move" {
+				delete(doc, from)
+			}
+			doc[key] = v
+		default:
+			return apperr.InvalidField("op", apperr.CodeInvalid, "unsupported operation "+op.Op)
+		}
+	}
+	return nil
+}
+
+// diffToMergePatch returns the merge patch that turns before into after:
+// changed and added members with their new value, removed members as null.
+func diffToMergePatch(before, after patchDocument) patchDocument {
+	patch := patchDocument{}
+	for k, v := range after {
+		if old, ok := before[k]; !ok || !jsonEqual(old, v) {
+			patch[k] = v
+		}
+	}
+	for k := range before {
+		if _, ok := after[k]; !ok {
+			patch[k] = jsonNull
+		}
+	}
+	return patch
+}
+
+// subscriptionDocument returns the JSON representation of s that JSON Patch
+// operations apply to.
+func subscriptionDocument(s model.Subscription) (patchDocument, error) {
+	body, err := json.Marshal(ToSubscriptionResponse(s))
+	if err != nil {
+		return nil, err
+	}
+	var doc patchDocument
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
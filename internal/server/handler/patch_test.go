@@ -0,0 +1,249 @@
+package handler
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/morphlinkk/subscriptions/internal/apperr"
+	"github.com/morphlinkk/subscriptions/internal/model"
+)
+
+func TestApplyJSONPatch(t *testing.T) {
+	const doc = `{"service_name":"Netflix","price":400,"end_date":"12-2026"}`
+
+	tests := []struct {
+		name     string
+		ops      string
+		want     string
+		wantKind apperr.Kind
+		wantErr  bool
+	}{
+		{
+			name: "replace",
+			ops:  `[{"op":"replace","path":"/price","value":500}]`,
+			want: `{"service_name":"Netflix","price":500,"end_date":"12-2026"}`,
+		},
+		{
+			name: "add replaces an existing member",
+			ops:  `[{"op":"add","path":"/price","value":500}]`,
+			want: `{"service_name":"Netflix","price":500,"end_date":"12-2026"}`,
+		},
+		{
+			name: "remove",
+			ops:  `[{"op":"remove","path":"/end_date"}]`,
+			want: `{"service_name":"Netflix","price":400}`,
+		},
+		{
+			name: "passing test then replace",
+			ops:  `[{"op":"test","path":"/price","value":400},{"op":"replace","path":"/price","value":500}]`,
+			want: `{"service_name":"Netflix","price":500,"end_date":"12-2026"}`,
+		},
+		{
+			name: "copy",
+			ops:  `[{"op":"copy","from":"/service_name","path":"/alias"}]`,
+			want: `{"service_name":"Netflix","alias":"Netflix","price":400,"end_date":"12-2026"}`,
+		},
+		{
+			name: "move",
+			ops:  `[{"op":"move","from":"/service_name","path":"/alias"}]`,
+			want: `{"alias":"Netflix","price":400,"end_date":"12-2026"}`,
+		},
+		{
+			name: "escaped pointer",
+			ops:  `[{"op":"add","path":"/a~1b~0c","value":1}]`,
+			want: `{"service_name":"Netflix","price":400,"end_date":"12-2026","a/b~c":1}`,
+		},
+		{
+			name:     "failing test is a conflict",
+			ops:      `[{"op":"test","path":"/price","value":500},{"op":"replace","path":"/price","value":600}]`,
+			wantErr:  true,
+			wantKind: apperr.KindConflict,
+		},
+		{
+			name:     "test of a missing member is a conflict",
+			ops:      `[{"op":"test","path":"/user_id","value":null}]`,
+			wantErr:  true,
+			wantKind: apperr.KindConflict,
+		},
+		{
+			name:     "replace of a missing member",
+			ops:      `[{"op":"replace","path":"/user_id","value":"x"}]`,
+			wantErr:  true,
+			wantKind: apperr.KindValidation,
+		},
+		{
+			name:     "remove of a missing member",
+			ops:      `[{"op":"remove","path":"/user_id"}]`,
+			wantErr:  true,
+			wantKind: apperr.KindValidation,
+		},
+		{
+			name:     "add without a value",
+			ops:      `[{"op":"add","path":"/price"}]`,
+			wantErr:  true,
+			wantKind: apperr.KindValidation,
+		},
+		{
+			name:     "nested pointer",
+			ops:      `[{"op":"replace","path":"/price/amount","value":1}]`,
+			wantErr:  true,
+			wantKind: apperr.KindValidation,
+		},
+		{
+			name:     "root pointer",
+			ops:      `[{"op":"replace","path":"/","value":1}]`,
+			wantErr:  true,
+			wantKind: apperr.KindValidation,
+		},
+		{
+			name:     "unsupported operation",
+			ops:      `[{"op":"increment","path":"/price","value":1}]`,
+			wantErr:  true,
+			wantKind: apperr.KindValidation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := decodeMergePatch([]byte(doc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ops, err := decodeJSONPatch([]byte(tt.ops))
+			if err != nil {
+				t.Fatalf("decodeJSONPatch() error = %v", err)
+			}
+
+			err = applyJSONPatch(d, ops)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("applyJSONPatch() error = nil, want an error")
+				}
+				if kind := apperr.KindOf(err); kind != tt.wantKind {
+					t.Errorf("applyJSONPatch() error kind = %v, want %v", kind, tt.wantKind)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyJSONPatch() error = %v", err)
+			}
+			got, _ := json.Marshal(d)
+			if !jsonEqual(got, json.RawMessage(tt.want)) {
+				t.Errorf("applyJSONPatch() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodePatchInvalid(t *testing.T) {
+	for _, body := range []string{``, `null`, `[]`, `"x"`, `{`} {
+		if _, err := decodeMergePatch([]byte(body)); err == nil {
+			t.Errorf("decodeMergePatch(%q) error = nil, want an error", body)
+		}
+	}
+	for _, body := range []string{``, `null`, `{}`, `[{"op":1}]`} {
+		if _, err := decodeJSONPatch([]byte(body)); err == nil {
+			t.Errorf("decodeJSONPatch(%q) error = nil, want an error", body)
+		}
+	}
+}
+
+func TestDiffToMergePatch(t *testing.T) {
+	before := `{"service_name":"Netflix","price":400,"end_date":"12-2026"}`
+
+	tests := []struct {
+		name  string
+		after string
+		want  string
+	}{
+		{"unchanged", `{"price":400,"end_date":"12-2026","service_name":"Netflix"}`, `{}`},
+		{"changed member", `{"service_name":"Netflix","price":500,"end_date":"12-2026"}`, `{"price":500}`},
+		{"removed member", `{"service_name":"Netflix","price":400}`, `{"end_date":null}`},
+		{"added member", `{"service_name":"Netflix","price":400,"end_date":"12-2026","user_id":"x"}`, `{"user_id":"x"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _ := decodeMergePatch([]byte(before))
+			a, _ := decodeMergePatch([]byte(tt.after))
+			got, _ := json.Marshal(diffToMergePatch(b, a))
+			if !jsonEqual(got, json.RawMessage(tt.want)) {
+				t.Errorf("diffToMergePatch() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergePatchToUpdateParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		patch   string
+		check   func(t *testing.T, p model.UpdateSubscriptionParams)
+		wantErr bool
+	}{
+		{
+			name:  "empty patch changes nothing",
+			patch: `{}`,
+			check: func(t *testing.T, p model.UpdateSubscriptionParams) {
+				if p.Service != nil || p.Price != nil || p.UserID != nil || p.EndDate.Set {
+					t.Errorf("params = %+v, want none set", p)
+				}
+			},
+		},
+		{
+			name:  "price and service",
+			patch: `{"price":500,"service_name":"Netflix"}`,
+			check: func(t *testing.T, p model.UpdateSubscriptionParams) {
+				if p.Price == nil || *p.Price != 500 || p.Service == nil || *p.Service != "Netflix" {
+					t.Errorf("params = %+v, want price 500 and Netflix", p)
+				}
+			},
+		},
+		{
+			name:  "end date",
+			patch: `{"end_date":"03-2027"}`,
+			check: func(t *testing.T, p model.UpdateSubscriptionParams) {
+				want := time.Date(2027, time.March, 1, 0, 0, 0, 0, time.UTC)
+				if !p.EndDate.Set || p.EndDate.Value == nil || !p.EndDate.Value.Equal(want) {
+					t.Errorf("EndDate = %+v, want %s", p.EndDate, want)
+				}
+			},
+		},
+		{
+			name:  "null end date reopens",
+			patch: `{"end_date":null}`,
+			check: func(t *testing.T, p model.UpdateSubscriptionParams) {
+				if !p.EndDate.Set || p.EndDate.Value != nil {
+					t.Errorf("EndDate = %+v, want cleared", p.EndDate)
+				}
+			},
+		},
+		{name: "read-only member", patch: `{"id":4}`, wantErr: true},
+		{name: "unknown member", patch: `{"color":"red"}`, wantErr: true},
+		{name: "null price", patch: `{"price":null}`, wantErr: true},
+		{name: "price of the wrong type", patch: `{"price":"500"}`, wantErr: true},
+		{name: "end date in the wrong format", patch: `{"end_date":"2027-03-01"}`, wantErr: true},
+		{name: "invalid user id", patch: `{"user_id":"me"}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, err := decodeMergePatch([]byte(tt.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+			params, err := mergePatchToUpdateParams(patch)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("mergePatchToUpdateParams() = %+v, want an error", params)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("mergePatchToUpdateParams() error = %v", err)
+			}
+			tt.check(t, params)
+		})
+	}
+}
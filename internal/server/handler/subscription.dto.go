@@ -1,21 +1,94 @@
 package handler
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/morphlinkk/subscriptions/internal/apperr"
 	"github.com/morphlinkk/subscriptions/internal/model"
 )
 
-const dateLayout = "01-2006"
+const (
+	dateLayout = "01-2006"
+	dayLayout  = "2006-01-02"
+)
+
+func parseMonth(field, value string) (time.Time, error) {
+	t, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return time.Time{}, apperr.InvalidField(field, apperr.CodeInvalidFormat, field+" must be in MM-YYYY format")
+	}
+	return t, nil
+}
+
+func parseDay(field, value string) (time.Time, error) {
+	t, err := time.Parse(dayLayout, value)
+	if err != nil {
+		return time.Time{}, apperr.InvalidField(field, apperr.CodeInvalidFormat, field+" must be in YYYY-MM-DD format")
+	}
+	return t, nil
+}
+
+func parseTimestamp(field, value string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, apperr.InvalidField(field, apperr.CodeInvalidFormat, field+" must be an RFC 3339 timestamp")
+	}
+	return t, nil
+}
+
+func parseUUID(field, value string) (uuid.UUID, error) {
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, apperr.InvalidField(field, apperr.CodeInvalidFormat, field+" must be a valid UUID")
+	}
+	return id, nil
+}
+
+// roundCost rounds a normalized cost to two decimals.
+func roundCost(x float64) float64 {
+	return math.Round(x*100) / 100
+}
 
 type SubscriptionResponse struct {
-	ID        int64   `json:"id"`
-	Service   string  `json:"service_name"`
-	Price     int     `json:"price"`
-	UserID    string  `json:"user_id"`
-	StartDate string  `json:"start_date"` // MM-YYYY
-	EndDate   *string `json:"end_date"`   // MM-YYYY
+	ID        int64  `json:"id"`
+	Service   string `json:"service_name"`
+	ServiceID *int64 `json:"service_id"`
+	Price     int    `json:"price"`    // minor units of currency
+	Currency  string `json:"currency"` // ISO 4217
+	// Price is paid every BillingIntervalCount BillingInterval units.
+	BillingInterval      string `json:"billing_interval"` // week | month | quarter | year
+	BillingIntervalCount int    `json:"billing_interval_count"`
+	BillingAnchorDay     int    `json:"billing_anchor_day"`
+	// MonthlyEquivalent and AnnualizedCost normalize Price to a month and a
+	// year.
+	MonthlyEquivalent float64 `json:"monthly_equivalent"`
+	AnnualizedCost    float64 `json:"annualized_cost"`
+	UserID            string  `json:"user_id"`
+	StartDate         string  `json:"start_date"` // MM-YYYY
+	EndDate           *string `json:"end_date"`   // MM-YYYY
+	// Status is trial, active, paused, pending_cancel, canceled or expired.
+	Status          string `json:"status"`
+	StatusChangedAt string `json:"status_changed_at"` // RFC 3339
+	// TrialEnd is the last day of the trial; Price is charged from the first
+	// billing day after it, and spend counts it from that day's month.
+	// TrialFlaggedAt is when the trial was reported as ending soon.
+	TrialEnd       *string `json:"trial_end"`        // YYYY-MM-DD
+	TrialFlaggedAt *string `json:"trial_flagged_at"` // RFC 3339
+	// CancelAtPeriodEnd is set when the subscription was canceled at the end
+	// of the billing period that ends on CancelAt; EndDate is its month.
+	CancelAtPeriodEnd bool    `json:"cancel_at_period_end"`
+	CancelAt          *string `json:"cancel_at"` // YYYY-MM-DD
+	// TransferredFrom is the previous owner's subscription after a transfer.
+	TransferredFrom *int64 `json:"transferred_from"`
+	// Version is also sent in the ETag header, with Status.
+	Version int `json:"version"`
+	// Relevance is only present in search results.
+	Relevance *float32 `json:"relevance,omitempty"`
 }
 
 func ToSubscriptionResponse(s model.Subscription) SubscriptionResponse {
@@ -27,122 +100,545 @@ func ToSubscriptionResponse(s model.Subscription) SubscriptionResponse {
 		end = &e
 	}
 
+	var trialEnd, trialFlaggedAt, cancelAt *string
+	if s.TrialEnd != nil {
+		t := s.TrialEnd.Format(dayLayout)
+		trialEnd = &t
+	}
+	if s.TrialFlaggedAt != nil {
+		t := s.TrialFlaggedAt.Format(time.RFC3339)
+		trialFlaggedAt = &t
+	}
+	if s.CancelAt != nil {
+		t := s.CancelAt.Format(dayLayout)
+		cancelAt = &t
+	}
+
 	return SubscriptionResponse{
-		ID:        s.ID,
-		Service:   s.Service,
-		Price:     s.Price,
-		UserID:    s.UserID.String(),
-		StartDate: start,
-		EndDate:   end,
+		ID:                   s.ID,
+		Service:              s.Service,
+		ServiceID:            s.ServiceID,
+		Price:                s.Price,
+		Currency:             s.Currency,
+		BillingInterval:      string(s.Billing.Unit),
+		BillingIntervalCount: s.Billing.Count,
+		BillingAnchorDay:     s.Billing.AnchorDay,
+		MonthlyEquivalent:    roundCost(s.Billing.MonthlyCost(s.Price)),
+		AnnualizedCost:       roundCost(s.Billing.AnnualCost(s.Price)),
+		UserID:               s.UserID.String(),
+		StartDate:            start,
+		EndDate:              end,
+		Status:               string(s.Status),
+		StatusChangedAt:      s.StatusChangedAt.Format(time.RFC3339),
+		TrialEnd:             trialEnd,
+		TrialFlaggedAt:       trialFlaggedAt,
+		CancelAtPeriodEnd:    s.CancelAtPeriodEnd(),
+		CancelAt:             cancelAt,
+		TransferredFrom:      s.TransferredFrom,
+		Version:              s.Version,
+		Relevance:            s.Relevance,
 	}
 }
 
 type AddSubscriptionRequest struct {
-	Service   string  `json:"service_name" validate:"required"`
-	Price     int     `json:"price" validate:"required,gte=0"`
-	UserID    string  `json:"user_id" validate:"required,uuid"`
-	StartDate string  `json:"start_date" validate:"required"` // MM-YYYY
-	EndDate   *string `json:"end_date"`                       // MM-YYYY
+	Service              string  `json:"service_name" validate:"required"`
+	Price                int     `json:"price" validate:"gte=0"` // minor units of currency
+	Currency             string  `json:"currency"`               // ISO 4217, default RUB
+	BillingInterval      *string `json:"billing_interval"`       // week | month | quarter | year, default month
+	BillingIntervalCount *int    `json:"billing_interval_count"`
+	BillingAnchorDay     *int    `json:"billing_anchor_day"`
+	UserID               string  `json:"user_id" validate:"required,uuid"`
+	StartDate            string  `json:"start_date" validate:"required"` // MM-YYYY
+	EndDate              *string `json:"end_date"`                       // MM-YYYY
+	Status               *string `json:"status"`                         // active | trial, default trial until trial_end has passed
+	// TrialEnd is the last day of a trial charged TrialPrice; Price is charged
+	// from the first billing day after it, and spend counts it from that
+	// day's month.
+	TrialEnd   *string `json:"trial_end"` // YYYY-MM-DD
+	TrialPrice int     `json:"trial_price" validate:"gte=0"`
 }
 
 func (r AddSubscriptionRequest) ToParams() (model.AddSubscriptionParams, error) {
-	start, err := time.Parse(dateLayout, r.StartDate)
+	start, err := parseMonth("start_date", r.StartDate)
 	if err != nil {
 		return model.AddSubscriptionParams{}, err
 	}
 
 	var end *time.Time
 	if r.EndDate != nil {
-		e, err := time.Parse(dateLayout, *r.EndDate)
+		e, err := parseMonth("end_date", *r.EndDate)
 		if err != nil {
 			return model.AddSubscriptionParams{}, err
 		}
 		end = &e
 	}
 
-	uid, err := uuid.Parse(r.UserID)
+	var trialEnd *time.Time
+	if r.TrialEnd != nil {
+		t, err := parseDay("trial_end", *r.TrialEnd)
+		if err != nil {
+			return model.AddSubscriptionParams{}, err
+		}
+		trialEnd = &t
+	}
+
+	uid, err := parseUUID("user_id", r.UserID)
 	if err != nil {
 		return model.AddSubscriptionParams{}, err
 	}
 
-	return model.AddSubscriptionParams{
-		Service:   r.Service,
-		Price:     r.Price,
-		UserID:    uid,
-		StartDate: start,
-		EndDate:   end,
-	}, nil
+	params := model.AddSubscriptionParams{
+		Service:    r.Service,
+		Price:      r.Price,
+		Currency:   r.Currency,
+		UserID:     uid,
+		StartDate:  start,
+		EndDate:    end,
+		TrialEnd:   trialEnd,
+		TrialPrice: r.TrialPrice,
+	}
+	if r.BillingInterval != nil {
+		params.Billing.Unit = model.BillingUnit(*r.BillingInterval)
+	}
+	if r.BillingIntervalCount != nil {
+		params.Billing.Count = *r.BillingIntervalCount
+	}
+	if r.BillingAnchorDay != nil {
+		params.Billing.AnchorDay = *r.BillingAnchorDay
+	}
+	if r.Status != nil {
+		params.Status = model.SubscriptionStatus(*r.Status)
+	}
+	return params, nil
 }
 
 type UpdateSubscriptionRequest struct {
-	Service *string `json:"service_name"`
-	Price   *int    `json:"price"`
-	UserID  *string `json:"user_id"`
-	EndDate *string `json:"end_date"` // MM-YYYY
+	Service       *string `json:"service_name"`
+	Price         *int    `json:"price"`
+	EffectiveFrom *string `json:"effective_from"` // MM-YYYY, first month of price and billing; defaults to the first paid month after any trial
+	// BillingInterval is week, month, quarter or year.
+	BillingInterval      *string `json:"billing_interval"`
+	BillingIntervalCount *int    `json:"billing_interval_count"`
+	BillingAnchorDay     *int    `json:"billing_anchor_day"`
+	UserID               *string `json:"user_id"`  // rejected, use POST /subscriptions/{id}/transfer
+	EndDate              *string `json:"end_date"` // MM-YYYY
 }
 
 func (r UpdateSubscriptionRequest) ToParams() (model.UpdateSubscriptionParams, error) {
 	params := model.UpdateSubscriptionParams{
-		Service: r.Service,
-		Price:   r.Price,
+		Service:          r.Service,
+		Price:            r.Price,
+		BillingCount:     r.BillingIntervalCount,
+		BillingAnchorDay: r.BillingAnchorDay,
+	}
+	if r.BillingInterval != nil {
+		unit := model.BillingUnit(*r.BillingInterval)
+		params.BillingUnit = &unit
 	}
 
 	if r.UserID != nil {
-		uid, err := uuid.Parse(*r.UserID)
+		uid, err := parseUUID("user_id", *r.UserID)
 		if err != nil {
 			return params, err
 		}
 		params.UserID = &uid
 	}
 
+	if r.EffectiveFrom != nil {
+		t, err := parseMonth("effective_from", *r.EffectiveFrom)
+		if err != nil {
+			return params, err
+		}
+		params.PriceEffectiveFrom = &t
+	}
+
 	if r.EndDate != nil {
-		t, err := time.Parse(dateLayout, *r.EndDate)
+		t, err := parseMonth("end_date", *r.EndDate)
 		if err != nil {
 			return params, err
 		}
-		params.EndDate = &t
+		params.EndDate = model.NullableOf(t)
 	}
 
 	return params, nil
 }
 
+type TransferSubscriptionRequest struct {
+	UserID         string `json:"user_id" validate:"required,uuid"`
+	EffectiveMonth string `json:"effective_month" validate:"required"` // MM-YYYY
+}
+
+func (r TransferSubscriptionRequest) ToParams() (model.TransferSubscriptionParams, error) {
+	uid, err := parseUUID("user_id", r.UserID)
+	if err != nil {
+		return model.TransferSubscriptionParams{}, err
+	}
+
+	month, err := parseMonth("effective_month", r.EffectiveMonth)
+	if err != nil {
+		return model.TransferSubscriptionParams{}, err
+	}
+
+	return model.TransferSubscriptionParams{
+		UserID:         uid,
+		EffectiveMonth: month,
+	}, nil
+}
+
+type SubscriptionTransferResponse struct {
+	From SubscriptionResponse `json:"from"`
+	To   SubscriptionResponse `json:"to"`
+}
+
+func ToSubscriptionTransferResponse(t model.SubscriptionTransfer) SubscriptionTransferResponse {
+	return SubscriptionTransferResponse{
+		From: ToSubscriptionResponse(t.From),
+		To:   ToSubscriptionResponse(t.To),
+	}
+}
+
+type ServicePriceChangeRequest struct {
+	OldPrice       int     `json:"old_price" validate:"required"`
+	NewPrice       int     `json:"new_price" validate:"required"`
+	Currency       string  `json:"currency" validate:"required"`        // ISO 4217
+	EffectiveMonth string  `json:"effective_month" validate:"required"` // MM-YYYY
+	UserID         *string `json:"user_id"`
+	DryRun         bool    `json:"dry_run"`
+}
+
+func (r ServicePriceChangeRequest) ToParams(service string) (model.ServicePriceChangeParams, error) {
+	params := model.ServicePriceChangeParams{
+		Service:  service,
+		Currency: r.Currency,
+		OldPrice: r.OldPrice,
+		NewPrice: r.NewPrice,
+		DryRun:   r.DryRun,
+	}
+
+	month, err := parseMonth("effective_month", r.EffectiveMonth)
+	if err != nil {
+		return params, err
+	}
+	params.EffectiveMonth = month
+
+	if r.UserID != nil {
+		uid, err := parseUUID("user_id", *r.UserID)
+		if err != nil {
+			return params, err
+		}
+		params.UserID = &uid
+	}
+
+	return params, nil
+}
+
+type ChangeStatusRequest struct {
+	EffectiveMonth *string `json:"effective_month"` // MM-YYYY, default current month
+	// AtPeriodEnd cancels at the end of the current billing period instead of
+	// in EffectiveMonth.
+	AtPeriodEnd bool `json:"at_period_end"`
+}
+
+func (r ChangeStatusRequest) ToParams(action model.StatusAction) (model.ChangeStatusParams, error) {
+	params := model.ChangeStatusParams{Action: action, AtPeriodEnd: r.AtPeriodEnd}
+	if r.EffectiveMonth != nil {
+		month, err := parseMonth("effective_month", *r.EffectiveMonth)
+		if err != nil {
+			return params, err
+		}
+		params.EffectiveMonth = &month
+	}
+	return params, nil
+}
+
+type StatusChangeResponse struct {
+	Action         string `json:"action"` // pause | resume | cancel | reactivate
+	From           string `json:"from"`
+	To             string `json:"to"`
+	EffectiveMonth string `json:"effective_month"` // MM-YYYY
+	ChangedAt      string `json:"changed_at"`      // RFC 3339
+}
+
+func ToStatusChangeResponses(changes []model.StatusChange) []StatusChangeResponse {
+	responses := make([]StatusChangeResponse, len(changes))
+	for i, c := range changes {
+		responses[i] = StatusChangeResponse{
+			Action:         string(c.Action),
+			From:           string(c.From),
+			To:             string(c.To),
+			EffectiveMonth: c.EffectiveMonth.Format(dateLayout),
+			ChangedAt:      c.ChangedAt.Format(time.RFC3339),
+		}
+	}
+	return responses
+}
+
+type PriceChangeItemResponse struct {
+	SubscriptionID int64  `json:"subscription_id"`
+	UserID         string `json:"user_id"`
+	EffectiveFrom  string `json:"effective_from"` // MM-YYYY
+	Version        int    `json:"version"`
+}
+
+type ServicePriceChangeResponse struct {
+	Service        string                    `json:"service_name"`
+	OldPrice       int                       `json:"old_price"`
+	NewPrice       int                       `json:"new_price"`
+	Currency       string                    `json:"currency"`
+	EffectiveMonth string                    `json:"effective_month"` // MM-YYYY
+	DryRun         bool                      `json:"dry_run"`
+	Affected       int                       `json:"affected"`
+	MonthlyDelta   int64                     `json:"monthly_delta"`
+	Subscriptions  []PriceChangeItemResponse `json:"subscriptions"`
+}
+
+func ToServicePriceChangeResponse(c model.ServicePriceChange) ServicePriceChangeResponse {
+	items := make([]PriceChangeItemResponse, len(c.Subscriptions))
+	for i, sub := range c.Subscriptions {
+		items[i] = PriceChangeItemResponse{
+			SubscriptionID: sub.ID,
+			UserID:         sub.UserID.String(),
+			EffectiveFrom:  model.EffectiveFrom(sub, c.Params.EffectiveMonth).Format(dateLayout),
+			Version:        sub.Version,
+		}
+	}
+
+	return ServicePriceChangeResponse{
+		Service:        c.Params.Service,
+		OldPrice:       c.Params.OldPrice,
+		NewPrice:       c.Params.NewPrice,
+		Currency:       c.Params.Currency,
+		EffectiveMonth: c.Params.EffectiveMonth.Format(dateLayout),
+		DryRun:         c.Params.DryRun,
+		Affected:       len(c.Subscriptions),
+		MonthlyDelta:   c.MonthlyDelta,
+		Subscriptions:  items,
+	}
+}
+
+type SubscriptionPriceResponse struct {
+	Price int `json:"price"`
+	// Price is paid every BillingIntervalCount BillingInterval units.
+	BillingInterval      string `json:"billing_interval"` // week | month | quarter | year
+	BillingIntervalCount int    `json:"billing_interval_count"`
+	BillingAnchorDay     int    `json:"billing_anchor_day"`
+	EffectiveFrom        string `json:"effective_from"` // MM-YYYY
+	RecordedAt           string `json:"recorded_at"`    // RFC 3339
+}
+
+func ToSubscriptionPriceResponses(prices []model.SubscriptionPrice) []SubscriptionPriceResponse {
+	responses := make([]SubscriptionPriceResponse, len(prices))
+	for i, p := range prices {
+		responses[i] = SubscriptionPriceResponse{
+			Price:                p.Price,
+			BillingInterval:      string(p.Billing.Unit),
+			BillingIntervalCount: p.Billing.Count,
+			BillingAnchorDay:     p.Billing.AnchorDay,
+			EffectiveFrom:        p.EffectiveFrom.Format(dateLayout),
+			RecordedAt:           p.RecordedAt.Format(time.RFC3339),
+		}
+	}
+	return responses
+}
+
+type GetSubscriptionRequest struct {
+	AsOf *string `form:"as_of"` // RFC 3339
+}
+
+// AsOfTime returns the parsed as_of timestamp, or nil when it is not set.
+func (r GetSubscriptionRequest) AsOfTime() (*time.Time, error) {
+	if r.AsOf == nil {
+		return nil, nil
+	}
+	asOf, err := parseTimestamp("as_of", *r.AsOf)
+	if err != nil {
+		return nil, err
+	}
+	return &asOf, nil
+}
+
 type ListSubscriptionsRequest struct {
-	UserID *string `form:"user_id"`
-	Limit  int     `form:"limit"`
-	Offset int     `form:"offset"`
+	Query         *string `form:"q"`
+	UserID        *string `form:"user_id"`
+	ServiceName   *string `form:"service_name"`
+	ServicePrefix *string `form:"service_prefix"`
+	PriceMin      *int    `form:"price_min"`
+	PriceMax      *int    `form:"price_max"`
+	ActiveOn      *string `form:"active_on"`    // YYYY-MM-DD
+	ActiveIn      *string `form:"active_in"`    // MM-YYYY
+	StartedFrom   *string `form:"started_from"` // MM-YYYY
+	StartedTo     *string `form:"started_to"`   // MM-YYYY
+	EndedFrom     *string `form:"ended_from"`   // MM-YYYY
+	EndedTo       *string `form:"ended_to"`     // MM-YYYY
+	HasEndDate    *bool   `form:"has_end_date"`
+	EndingWithin  *int    `form:"ending_within"` // days
+	Sort          *string `form:"sort"`          // e.g. -price,service_name
+	Limit         int     `form:"limit"`
+	Offset        int     `form:"offset"`
+	Cursor        *string `form:"cursor"`
+	IncludeTotal  bool    `form:"include_total"`
+	AsOf          *string `form:"as_of"` // RFC 3339
 }
 
 func (r ListSubscriptionsRequest) ToParams() (model.ListSubscriptionsParams, error) {
 	params := model.ListSubscriptionsParams{
-		Limit:  r.Limit,
-		Offset: r.Offset,
+		Query:         r.Query,
+		ServiceName:   r.ServiceName,
+		ServicePrefix: r.ServicePrefix,
+		PriceMin:      r.PriceMin,
+		PriceMax:      r.PriceMax,
+		HasEndDate:    r.HasEndDate,
+		EndingWithin:  r.EndingWithin,
+		Limit:         r.Limit,
+		Offset:        r.Offset,
+		IncludeTotal:  r.IncludeTotal,
+	}
+
+	if r.Cursor != nil {
+		cursor, err := DecodeCursor(*r.Cursor)
+		if err != nil {
+			return params, err
+		}
+		params.After = cursor
 	}
 
 	if r.UserID != nil {
-		uid, err := uuid.Parse(*r.UserID)
+		uid, err := parseUUID("user_id", *r.UserID)
 		if err != nil {
 			return params, err
 		}
 		params.UserID = &uid
 	}
 
+	if r.ActiveOn != nil {
+		on, err := parseDay("active_on", *r.ActiveOn)
+		if err != nil {
+			return params, err
+		}
+		params.ActiveOn = &on
+	}
+
+	months := []struct {
+		field string
+		value *string
+		dst   **time.Time
+	}{
+		{"active_in", r.ActiveIn, &params.ActiveIn},
+		{"started_from", r.StartedFrom, &params.StartedFrom},
+		{"started_to", r.StartedTo, &params.StartedTo},
+		{"ended_from", r.EndedFrom, &params.EndedFrom},
+		{"ended_to", r.EndedTo, &params.EndedTo},
+	}
+	for _, m := range months {
+		if m.value == nil {
+			continue
+		}
+		t, err := parseMonth(m.field, *m.value)
+		if err != nil {
+			return params, err
+		}
+		*m.dst = &t
+	}
+
+	if r.AsOf != nil {
+		asOf, err := parseTimestamp("as_of", *r.AsOf)
+		if err != nil {
+			return params, err
+		}
+		params.AsOf = &asOf
+	}
+
+	if r.Sort != nil {
+		params.Sort = parseSort(*r.Sort)
+	}
+
 	return params, nil
 }
 
+// parseSort turns "-price,service_name" into sort fields; a leading "-" sorts
+// descending. Field names are validated by the service.
+func parseSort(s string) model.SubscriptionSort {
+	var sort model.SubscriptionSort
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		field, desc := strings.CutPrefix(part, "-")
+		sort = append(sort, model.SortField{Field: strings.TrimPrefix(field, "+"), Desc: desc})
+	}
+	return sort
+}
+
+// cursorPayload is the JSON form of model.SubscriptionCursor. Clients only see
+// it base64url-encoded and must treat it as opaque.
+type cursorPayload struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+}
+
+func EncodeCursor(c model.SubscriptionCursor) string {
+	b, _ := json.Marshal(cursorPayload{Sort: c.Sort, Values: c.Values})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func DecodeCursor(s string) (*model.SubscriptionCursor, error) {
+	invalid := apperr.InvalidField("cursor", apperr.CodeInvalid, "cursor is invalid")
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, invalid
+	}
+
+	var p cursorPayload
+	if err := json.Unmarshal(b, &p); err != nil || len(p.Values) == 0 {
+		return nil, invalid
+	}
+
+	return &model.SubscriptionCursor{Sort: p.Sort, Values: p.Values}, nil
+}
+
+type PageMeta struct {
+	Limit      int     `json:"limit"`
+	NextCursor *string `json:"next_cursor"`
+	Total      *int64  `json:"total,omitempty"`
+}
+
+type SuggestServicesRequest struct {
+	Query string `form:"q"`
+	Limit int    `form:"limit"`
+}
+
+func (r SuggestServicesRequest) ToParams() model.SuggestServicesParams {
+	return model.SuggestServicesParams{
+		Query: r.Query,
+		Limit: r.Limit,
+	}
+}
+
+type ServiceSuggestionResponse struct {
+	Name          string  `json:"service_name"`
+	Subscriptions int64   `json:"subscriptions"`
+	Score         float32 `json:"score"`
+}
+
 type SumOfSubscriptionPricesRequest struct {
 	UserID      *string `form:"user_id"`
 	Service     *string `form:"service_name"`
 	PeriodStart *string `form:"period_start"` // MM-YYYY
 	PeriodEnd   *string `form:"period_end"`   // MM-YYYY
+	AsOf        *string `form:"as_of"`        // RFC 3339
+	Currency    *string `form:"currency"`     // ISO 4217
 }
 
 func (r SumOfSubscriptionPricesRequest) ToParams() (model.SumOfSubscriptionPricesParams, error) {
 	params := model.SumOfSubscriptionPricesParams{
 		ServiceName: r.Service,
+		Currency:    r.Currency,
 	}
 
 	if r.UserID != nil {
-		uid, err := uuid.Parse(*r.UserID)
+		uid, err := parseUUID("user_id", *r.UserID)
 		if err != nil {
 			return params, err
 		}
@@ -150,7 +646,7 @@ func (r SumOfSubscriptionPricesRequest) ToParams() (model.SumOfSubscriptionPrice
 	}
 
 	if r.PeriodStart != nil {
-		start, err := time.Parse(dateLayout, *r.PeriodStart)
+		start, err := parseMonth("period_start", *r.PeriodStart)
 		if err != nil {
 			return params, err
 		}
@@ -158,12 +654,197 @@ func (r SumOfSubscriptionPricesRequest) ToParams() (model.SumOfSubscriptionPrice
 	}
 
 	if r.PeriodEnd != nil {
-		end, err := time.Parse(dateLayout, *r.PeriodEnd)
+		end, err := parseMonth("period_end", *r.PeriodEnd)
 		if err != nil {
 			return params, err
 		}
 		params.PeriodEnd = &end
 	}
 
+	if r.AsOf != nil {
+		asOf, err := parseTimestamp("as_of", *r.AsOf)
+		if err != nil {
+			return params, err
+		}
+		params.AsOf = &asOf
+	}
+
 	return params, nil
 }
+
+type SpendItemResponse struct {
+	SubscriptionID int64  `json:"subscription_id"`
+	Service        string `json:"service_name"`
+	UserID         string `json:"user_id"`
+	Price          int    `json:"price"`
+	// Currency is the currency of Price and MonthlyEquivalent. Subtotal is
+	// converted to the currency of the response.
+	Currency string `json:"currency"`
+	// BillingInterval and BillingIntervalCount tell how often Price is paid.
+	BillingInterval      string  `json:"billing_interval"`
+	BillingIntervalCount int     `json:"billing_interval_count"`
+	MonthlyEquivalent    float64 `json:"monthly_equivalent"`
+	// Months counts the billed months of the period; PausedMonths those left
+	// out because the subscription was paused. TrialMonths of the billed
+	// months were within the trial and cost TrialSubtotal of Subtotal.
+	Months        int   `json:"months"`
+	PausedMonths  int   `json:"paused_months"`
+	TrialMonths   int   `json:"trial_months"`
+	Subtotal      int64 `json:"subtotal"`
+	TrialSubtotal int64 `json:"trial_subtotal"`
+}
+
+// ExchangeRateResponse says that one unit of base was worth rate units of
+// quote on date.
+type ExchangeRateResponse struct {
+	Date  string  `json:"date"` // YYYY-MM-DD
+	Base  string  `json:"base"`
+	Quote string  `json:"quote"`
+	Rate  float64 `json:"rate"`
+}
+
+func toExchangeRateResponse(r model.ExchangeRate) ExchangeRateResponse {
+	return ExchangeRateResponse{
+		Date:  r.Date.Format(time.DateOnly),
+		Base:  r.Base,
+		Quote: r.Quote,
+		Rate:  r.Rate,
+	}
+}
+
+type AppliedRateResponse struct {
+	Month string `json:"month"` // MM-YYYY
+	ExchangeRateResponse
+}
+
+type SumOfSubscriptionPricesResponse struct {
+	TotalPrice int64 `json:"total_price"`
+	// TrialTotal is the part of TotalPrice spent on trial months.
+	TrialTotal  int64               `json:"trial_total"`
+	Currency    string              `json:"currency"`
+	PeriodStart string              `json:"period_start"` // MM-YYYY
+	PeriodEnd   string              `json:"period_end"`   // MM-YYYY
+	Items       []SpendItemResponse `json:"items"`
+	// Rates are the exchange rates used to convert each month.
+	Rates []AppliedRateResponse `json:"rates"`
+}
+
+func ToSumOfSubscriptionPricesResponse(s model.SpendSummary) SumOfSubscriptionPricesResponse {
+	items := make([]SpendItemResponse, len(s.Items))
+	for i, it := range s.Items {
+		items[i] = SpendItemResponse{
+			SubscriptionID:       it.SubscriptionID,
+			Service:              it.Service,
+			UserID:               it.UserID.String(),
+			Price:                it.Price,
+			Currency:             it.Currency,
+			BillingInterval:      string(it.Billing.Unit),
+			BillingIntervalCount: it.Billing.Count,
+			MonthlyEquivalent:    roundCost(it.MonthlyCost),
+			Months:               it.Months,
+			PausedMonths:         it.PausedMonths,
+			TrialMonths:          it.TrialMonths,
+			Subtotal:             it.Subtotal,
+			TrialSubtotal:        it.TrialSubtotal,
+		}
+	}
+
+	rates := make([]AppliedRateResponse, len(s.Rates))
+	for i, r := range s.Rates {
+		rates[i] = AppliedRateResponse{
+			Month:                r.Month.Format(dateLayout),
+			ExchangeRateResponse: toExchangeRateResponse(r.ExchangeRate),
+		}
+	}
+
+	return SumOfSubscriptionPricesResponse{
+		TotalPrice:  s.Total,
+		TrialTotal:  s.TrialTotal,
+		Currency:    s.Currency,
+		PeriodStart: s.PeriodStart.Format(dateLayout),
+		PeriodEnd:   s.PeriodEnd.Format(dateLayout),
+		Items:       items,
+		Rates:       rates,
+	}
+}
+
+type SpendTimeSeriesRequest struct {
+	UserID      *string `form:"user_id"`
+	Service     *string `form:"service_name"`
+	PeriodStart *string `form:"period_start"` // MM-YYYY
+	PeriodEnd   *string `form:"period_end"`   // MM-YYYY
+	GroupBy     string  `form:"group_by"`     // service_name | user_id
+	AsOf        *string `form:"as_of"`        // RFC 3339
+	Currency    *string `form:"currency"`     // ISO 4217
+}
+
+func (r SpendTimeSeriesRequest) ToParams() (model.SpendTimeSeriesParams, error) {
+	sum, err := SumOfSubscriptionPricesRequest{
+		UserID:      r.UserID,
+		Service:     r.Service,
+		PeriodStart: r.PeriodStart,
+		PeriodEnd:   r.PeriodEnd,
+		AsOf:        r.AsOf,
+		Currency:    r.Currency,
+	}.ToParams()
+	if err != nil {
+		return model.SpendTimeSeriesParams{}, err
+	}
+
+	return model.SpendTimeSeriesParams{
+		UserID:      sum.UserID,
+		ServiceName: sum.ServiceName,
+		PeriodStart: sum.PeriodStart,
+		PeriodEnd:   sum.PeriodEnd,
+		AsOf:        sum.AsOf,
+		Currency:    sum.Currency,
+		GroupBy:     model.SpendGroupBy(r.GroupBy),
+	}, nil
+}
+
+type SpendGroupResponse struct {
+	Key                 string `json:"key"`
+	Total               int64  `json:"total"`
+	ActiveSubscriptions int    `json:"active_subscriptions"`
+}
+
+type SpendBucketResponse struct {
+	Month               string `json:"month"` // MM-YYYY
+	Total               int64  `json:"total"`
+	Currency            string `json:"currency"`
+	ActiveSubscriptions int    `json:"active_subscriptions"`
+	// TrialTotal and TrialSubscriptions are the part of Total and
+	// ActiveSubscriptions that were in a trial.
+	TrialTotal         int64                `json:"trial_total"`
+	TrialSubscriptions int                  `json:"trial_subscriptions"`
+	Groups             []SpendGroupResponse `json:"groups,omitempty"`
+	// Rates are the exchange rates the month was converted with.
+	Rates []ExchangeRateResponse `json:"rates,omitempty"`
+}
+
+func ToSpendBucketResponse(b model.SpendBucket) SpendBucketResponse {
+	var groups []SpendGroupResponse
+	for _, g := range b.Groups {
+		groups = append(groups, SpendGroupResponse{
+			Key:                 g.Key,
+			Total:               g.Total,
+			ActiveSubscriptions: g.ActiveSubscriptions,
+		})
+	}
+
+	var rates []ExchangeRateResponse
+	for _, r := range b.Rates {
+		rates = append(rates, toExchangeRateResponse(r))
+	}
+
+	return SpendBucketResponse{
+		Month:               b.Month.Format(dateLayout),
+		Total:               b.Total,
+		Currency:            b.Currency,
+		ActiveSubscriptions: b.ActiveSubscriptions,
+		TrialTotal:          b.TrialTotal,
+		TrialSubscriptions:  b.TrialSubscriptions,
+		Groups:              groups,
+		Rates:               rates,
+	}
+}
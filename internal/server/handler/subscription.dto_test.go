@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+
+	"github.com/morphlinkk/subscriptions/internal/model"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []model.SubscriptionCursor{
+		{Sort: "-start_date", Values: []string{"2026-03-01T00:00:00Z", "1"}},
+		{Sort: "price,-id", Values: []string{"799", "9007199254740993"}},
+		{Sort: "end_date", Values: []string{"infinity", "4"}},
+	}
+
+	for _, want := range tests {
+		t.Run(want.Sort, func(t *testing.T) {
+			got, err := DecodeCursor(EncodeCursor(want))
+			if err != nil {
+				t.Fatalf("DecodeCursor() error = %v", err)
+			}
+			if !reflect.DeepEqual(*got, want) {
+				t.Errorf("DecodeCursor() = %+v, want %+v", *got, want)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	encode := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"empty", ""},
+		{"not base64url", "a+b/c"},
+		{"not JSON", encode("cursor")},
+		{"missing values", encode(`{"s":"price"}`)},
+		{"empty values", encode(`{"s":"price","v":[]}`)},
+		{"values of the wrong type", encode(`{"s":"price","v":[799,1]}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if c, err := DecodeCursor(tt.cursor); err == nil {
+				t.Errorf("DecodeCursor(%q) = %+v, want an error", tt.cursor, *c)
+			}
+		})
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		in   string
+		want model.SubscriptionSort
+	}{
+		{"price", model.SubscriptionSort{{Field: "price"}}},
+		{"-price", model.SubscriptionSort{{Field: "price", Desc: true}}},
+		{"+price", model.SubscriptionSort{{Field: "price"}}},
+		{"-price, service_name", model.SubscriptionSort{{Field: "price", Desc: true}, {Field: "service_name"}}},
+		{"start_date,,-id,", model.SubscriptionSort{{Field: "start_date"}, {Field: "id", Desc: true}}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got := parseSort(tt.in)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSort(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+			if len(tt.want) > 0 && got.String() != tt.want.String() {
+				t.Errorf("String() = %q, want %q", got.String(), tt.want.String())
+			}
+		})
+	}
+}
@@ -1,11 +1,16 @@
 package handler
 
 import (
+	"errors"
+	"io"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/morphlinkk/subscriptions/internal/apperr"
+	"github.com/morphlinkk/subscriptions/internal/model"
 	"github.com/morphlinkk/subscriptions/internal/server/service"
 )
 
@@ -13,8 +18,21 @@ type SubscriptionHandler interface {
 	AddSubscription(c *gin.Context)
 	GetSubscriptionByID(c *gin.Context)
 	UpdateSubscription(c *gin.Context)
+	DeleteSubscription(c *gin.Context)
+	RestoreSubscription(c *gin.Context)
+	PurgeSubscription(c *gin.Context)
+	TransferSubscription(c *gin.Context)
 	ListSubscriptions(c *gin.Context)
+	SuggestServices(c *gin.Context)
 	GetSumOfSubscriptionPrices(c *gin.Context)
+	GetSpendTimeSeries(c *gin.Context)
+	ListSubscriptionPrices(c *gin.Context)
+	ChangeServicePrice(c *gin.Context)
+	PauseSubscription(c *gin.Context)
+	ResumeSubscription(c *gin.Context)
+	CancelSubscription(c *gin.Context)
+	ReactivateSubscription(c *gin.Context)
+	ListStatusChanges(c *gin.Context)
 }
 
 type subscriptionHandler struct {
@@ -29,13 +47,18 @@ func NewSubscriptionHandler(service service.SubscriptionService) SubscriptionHan
 
 // AddSubscription godoc
 // @Summary Add a new subscription
-// @Description Add a subscription for a user
+// @Description Add a subscription for a user. It is billed monthly on the 1st unless billing_interval,
+// @Description billing_interval_count and billing_anchor_day say otherwise, e.g. every 3 months or every year
 // @Tags subscriptions
 // @Accept json
 // @Produce json
 // @Param subscription body handler.AddSubscriptionRequest true "Subscription info"
+// @Param Idempotency-Key header string false "Unique key making retries return the first response instead of creating duplicates"
 // @Success 201 {object} handler.SubscriptionResponse "Created"
+// @Header 201 {string} Idempotent-Replayed "true when the response was replayed for a repeated Idempotency-Key"
 // @Failure 400 {object} Response "Invalid request"
+// @Failure 409 {object} Response "A request with the same Idempotency-Key is still in progress"
+// @Failure 422 {object} Response "Validation failed or Idempotency-Key reused for a different request"
 // @Failure 500 {object} Response "Internal server error"
 // @Router /subscriptions [post]
 func (h *subscriptionHandler) AddSubscription(c *gin.Context) {
@@ -49,14 +72,14 @@ func (h *subscriptionHandler) AddSubscription(c *gin.Context) {
 	params, err := req.ToParams()
 	if err != nil {
 		slog.Debug("failed to parse AddSubscriptionRequest", "error", err, "body", req)
-		JSONErrorMessage(c, http.StatusBadRequest, err.Error())
+		JSONAppError(c, err)
 		return
 	}
 
 	sub, err := h.subscriptionService.AddSubscription(c.Request.Context(), params)
 	if err != nil {
-		slog.Error("failed to add subscription", "error", err, "user_id", req.UserID)
-		JSONError(c, http.StatusInternalServerError, err)
+		slog.Debug("failed to add subscription", "error", err, "user_id", req.UserID)
+		JSONAppError(c, err)
 		return
 	}
 
@@ -71,9 +94,14 @@ func (h *subscriptionHandler) AddSubscription(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path int true "Subscription ID"
+// @Param as_of query string false "Read the subscription as stored at this RFC 3339 timestamp"
+// @Param If-None-Match header string false "ETag of a cached copy; answers 304 when it is still current"
 // @Success 200 {object} Response{data=SubscriptionResponse} "OK"
+// @Header 200 {string} ETag "Current version and status of the subscription, and as_of when given"
+// @Success 304 "Not modified"
 // @Failure 400 {object} Response "Invalid ID"
 // @Failure 404 {object} Response "Not found"
+// @Failure 422 {object} Response "Validation failed"
 // @Failure 500 {object} Response "Internal server error"
 // @Router /subscriptions/{id} [get]
 func (h *subscriptionHandler) GetSubscriptionByID(c *gin.Context) {
@@ -85,31 +113,62 @@ func (h *subscriptionHandler) GetSubscriptionByID(c *gin.Context) {
 		return
 	}
 
-	sub, err := h.subscriptionService.GetByID(c.Request.Context(), id)
+	var req GetSubscriptionRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		slog.Debug("failed to bind GetSubscriptionRequest", "error", err)
+		JSONErrorMessage(c, http.StatusBadRequest, "invalid query parameters")
+		return
+	}
+
+	asOf, err := req.AsOfTime()
 	if err != nil {
-		slog.Error("failed to get subscription by id", "id", id, "error", err)
-		JSONError(c, http.StatusInternalServerError, err)
+		JSONAppError(c, err)
 		return
 	}
-	if sub == nil {
-		slog.Debug("subscription not found", "id", id)
-		JSONErrorMessage(c, http.StatusNotFound, "subscription not found")
+
+	var sub *model.Subscription
+	if asOf != nil {
+		sub, err = h.subscriptionService.GetByIDAsOf(c.Request.Context(), id, *asOf)
+	} else {
+		sub, err = h.subscriptionService.GetByID(c.Request.Context(), id)
+	}
+	if err != nil {
+		slog.Debug("failed to get subscription by id", "id", id, "error", err)
+		JSONAppError(c, err)
 		return
 	}
 
+	etag := SubscriptionETag(*sub, asOf)
+	SetETag(c, etag)
+	if IfNoneMatch(c, etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
 	JSONSuccess(c, http.StatusOK, ToSubscriptionResponse(*sub))
 }
 
 // UpdateSubscription godoc
 // @Summary Update subscription
-// @Description Update a subscription by its ID
+// @Description Update a subscription by its ID. With application/json, null and missing fields are left unchanged.
+// @Description With application/merge-patch+json (RFC 7396), null clears a field, e.g. {"end_date": null} reopens the subscription.
+// @Description With application/json-patch+json (RFC 6902), operations such as test, replace and remove apply to top-level
+// @Description members of the subscription; a failed test answers 409, and a change made while the patch was applied answers 412.
+// @Description A price or billing interval change applies from effective_from (MM-YYYY) on, or to every month when it is not given.
 // @Tags subscriptions
 // @Accept json
+// @Accept application/merge-patch+json
+// @Accept application/json-patch+json
 // @Produce json
 // @Param id path int true "Subscription ID"
-// @Param subscription body UpdateSubscriptionRequest true "Subscription update info"
+// @Param subscription body UpdateSubscriptionRequest true "Subscription update info, a merge patch or a JSON Patch"
+// @Param If-Match header string false "ETag the update is based on; answers 412 when the subscription has changed since"
 // @Success 200 {object} Response{data=SubscriptionResponse} "Updated"
+// @Header 200 {string} ETag "New version and status of the subscription"
 // @Failure 400 {object} Response "Invalid request or ID"
+// @Failure 404 {object} Response "Not found"
+// @Failure 409 {object} Response "JSON Patch test failed, or end_date changed on a subscription that is not active or in a trial"
+// @Failure 412 {object} Response "If-Match does not match the current version"
+// @Failure 422 {object} Response "Validation failed"
 // @Failure 500 {object} Response "Internal server error"
 // @Router /subscriptions/{id} [patch]
 func (h *subscriptionHandler) UpdateSubscription(c *gin.Context) {
@@ -121,42 +180,276 @@ func (h *subscriptionHandler) UpdateSubscription(c *gin.Context) {
 		return
 	}
 
-	var req UpdateSubscriptionRequest
-	if err := c.BindJSON(&req); err != nil {
+	params, err := h.bindUpdateParams(c, id, IfMatchVersions(c))
+	if errors.Is(err, errInvalidBody) {
 		slog.Debug("invalid request body for update", "error", err)
 		JSONErrorMessage(c, http.StatusBadRequest, "invalid request body")
 		return
 	}
-
-	params, err := req.ToParams()
 	if err != nil {
-		slog.Debug("failed to parse UpdateSubscriptionRequest", "error", err, "body", req)
-		JSONErrorMessage(c, http.StatusBadRequest, err.Error())
+		slog.Debug("failed to parse update request", "id", id, "error", err)
+		JSONAppError(c, err)
 		return
 	}
 
 	sub, err := h.subscriptionService.UpdateSubscription(c.Request.Context(), id, params)
 	if err != nil {
-		slog.Error("failed to update subscription", "id", id, "error", err)
-		JSONError(c, http.StatusInternalServerError, err)
+		slog.Debug("failed to update subscription", "id", id, "error", err)
+		JSONAppError(c, err)
 		return
 	}
 
-	slog.Info("subscription updated", "id", sub.ID)
+	slog.Info("subscription updated", "id", sub.ID, "version", sub.Version)
+	SetETag(c, SubscriptionETag(*sub, nil))
 	JSONSuccess(c, http.StatusOK, ToSubscriptionResponse(*sub))
 }
 
+// bindUpdateParams reads the update of subscription id in the format given by
+// the Content-Type. Plain JSON ignores nulls, merge patch clears the fields set
+// to null, and JSON Patch is applied to the current subscription. ifMatch
+// becomes the expected versions; a JSON Patch instead expects the version it
+// was applied to, so that a concurrent change fails with 412.
+func (h *subscriptionHandler) bindUpdateParams(c *gin.Context, id int64, ifMatch []int) (model.UpdateSubscriptionParams, error) {
+	switch c.ContentType() {
+	case MIMEMergePatch:
+		body, err := c.GetRawData()
+		if err != nil {
+			return model.UpdateSubscriptionParams{}, errInvalidBody
+		}
+		patch, err := decodeMergePatch(body)
+		if err != nil {
+			return model.UpdateSubscriptionParams{}, err
+		}
+		params, err := mergePatchToUpdateParams(patch)
+		params.ExpectedVersions = ifMatch
+		return params, err
+
+	case MIMEJSONPatch:
+		body, err := c.GetRawData()
+		if err != nil {
+			return model.UpdateSubscriptionParams{}, errInvalidBody
+		}
+		ops, err := decodeJSONPatch(body)
+		if err != nil {
+			return model.UpdateSubscriptionParams{}, err
+		}
+
+		sub, err := h.subscriptionService.GetByID(c.Request.Context(), id)
+		if err != nil {
+			return model.UpdateSubscriptionParams{}, err
+		}
+		if ifMatch != nil && !slices.Contains(ifMatch, sub.Version) {
+			return model.UpdateSubscriptionParams{}, apperr.PreconditionFailed(
+				"subscription %d has changed, its current version is %d", id, sub.Version)
+		}
+		before, err := subscriptionDocument(*sub)
+		if err != nil {
+			return model.UpdateSubscriptionParams{}, err
+		}
+		after, err := subscriptionDocument(*sub)
+		if err != nil {
+			return model.UpdateSubscriptionParams{}, err
+		}
+		if err := applyJSONPatch(after, ops); err != nil {
+			return model.UpdateSubscriptionParams{}, err
+		}
+		params, err := mergePatchToUpdateParams(diffToMergePatch(before, after))
+		params.ExpectedVersions = []int{sub.Version}
+		return params, err
+
+	default:
+		var req UpdateSubscriptionRequest
+		if err := c.BindJSON(&req); err != nil {
+			return model.UpdateSubscriptionParams{}, errors.Join(errInvalidBody, err)
+		}
+		params, err := req.ToParams()
+		params.ExpectedVersions = ifMatch
+		return params, err
+	}
+}
+
+// DeleteSubscription godoc
+// @Summary Delete subscription
+// @Description Soft-delete a subscription by its ID. It can be brought back with the restore endpoint
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Success 204 "Deleted"
+// @Failure 400 {object} Response "Invalid ID"
+// @Failure 404 {object} Response "Not found"
+// @Failure 500 {object} Response "Internal server error"
+// @Router /subscriptions/{id} [delete]
+func (h *subscriptionHandler) DeleteSubscription(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		slog.Debug("invalid subscription id param", "param", idStr, "error", err)
+		JSONErrorMessage(c, http.StatusBadRequest, "invalid subscription id")
+		return
+	}
+
+	if err := h.subscriptionService.DeleteSubscription(c.Request.Context(), id); err != nil {
+		slog.Debug("failed to delete subscription", "id", id, "error", err)
+		JSONAppError(c, err)
+		return
+	}
+
+	slog.Info("subscription deleted", "id", id)
+	c.Status(http.StatusNoContent)
+}
+
+// RestoreSubscription godoc
+// @Summary Restore subscription
+// @Description Undo a soft delete of a subscription
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Success 200 {object} Response{data=SubscriptionResponse} "Restored"
+// @Failure 400 {object} Response "Invalid ID"
+// @Failure 404 {object} Response "Not found"
+// @Failure 500 {object} Response "Internal server error"
+// @Router /subscriptions/{id}/restore [post]
+func (h *subscriptionHandler) RestoreSubscription(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		slog.Debug("invalid subscription id param", "param", idStr, "error", err)
+		JSONErrorMessage(c, http.StatusBadRequest, "invalid subscription id")
+		return
+	}
+
+	sub, err := h.subscriptionService.RestoreSubscription(c.Request.Context(), id)
+	if err != nil {
+		slog.Debug("failed to restore subscription", "id", id, "error", err)
+		JSONAppError(c, err)
+		return
+	}
+
+	slog.Info("subscription restored", "id", sub.ID)
+	JSONSuccess(c, http.StatusOK, ToSubscriptionResponse(*sub))
+}
+
+// TransferSubscription godoc
+// @Summary Transfer subscription
+// @Description Move a subscription to another user from effective_month on. The current subscription ends the month
+// @Description before and a new one, linked through transferred_from, continues for the new owner until the original end date
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Param transfer body TransferSubscriptionRequest true "Transfer info"
+// @Success 201 {object} Response{data=SubscriptionTransferResponse} "Transferred"
+// @Failure 400 {object} Response "Invalid request or ID"
+// @Failure 404 {object} Response "Not found"
+// @Failure 409 {object} Response "Subscription changed or was already transferred"
+// @Failure 422 {object} Response "Validation failed"
+// @Failure 500 {object} Response "Internal server error"
+// @Router /subscriptions/{id}/transfer [post]
+func (h *subscriptionHandler) TransferSubscription(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		slog.Debug("invalid subscription id param", "param", idStr, "error", err)
+		JSONErrorMessage(c, http.StatusBadRequest, "invalid subscription id")
+		return
+	}
+
+	var req TransferSubscriptionRequest
+	if err := c.BindJSON(&req); err != nil {
+		slog.Debug("invalid request body for transfer", "error", err)
+		JSONErrorMessage(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	params, err := req.ToParams()
+	if err != nil {
+		slog.Debug("failed to parse TransferSubscriptionRequest", "error", err, "body", req)
+		JSONAppError(c, err)
+		return
+	}
+
+	transfer, err := h.subscriptionService.TransferSubscription(c.Request.Context(), id, params)
+	if err != nil {
+		slog.Debug("failed to transfer subscription", "id", id, "error", err)
+		JSONAppError(c, err)
+		return
+	}
+
+	slog.Info("subscription transferred", "from", transfer.From.ID, "to", transfer.To.ID, "user_id", transfer.To.UserID)
+	JSONSuccess(c, http.StatusCreated, ToSubscriptionTransferResponse(*transfer))
+}
+
+// PurgeSubscription godoc
+// @Summary Purge subscription
+// @Description Permanently delete a subscription together with its prices, pauses and status changes. Its audit
+// @Description history is kept. Requires the admin token
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param id path int true "Subscription ID"
+// @Success 204 "Purged"
+// @Failure 400 {object} Response "Invalid ID"
+// @Failure 401 {object} Response "Missing admin token"
+// @Failure 403 {object} Response "Invalid admin token"
+// @Failure 404 {object} Response "Not found"
+// @Failure 500 {object} Response "Internal server error"
+// @Router /admin/subscriptions/{id} [delete]
+func (h *subscriptionHandler) PurgeSubscription(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		slog.Debug("invalid subscription id param", "param", idStr, "error", err)
+		JSONErrorMessage(c, http.StatusBadRequest, "invalid subscription id")
+		return
+	}
+
+	if err := h.subscriptionService.PurgeSubscription(c.Request.Context(), id); err != nil {
+		slog.Debug("failed to purge subscription", "id", id, "error", err)
+		JSONAppError(c, err)
+		return
+	}
+
+	slog.Info("subscription purged", "id", id)
+	c.Status(http.StatusNoContent)
+}
+
 // ListSubscriptions godoc
 // @Summary List subscriptions
-// @Description Get a paginated list of subscriptions, optionally filtered by user_id
+// @Description Get a paginated list of subscriptions. All filters are optional and combined with AND.
+// @Description Pages are ordered by sort (newest first by default) with id as a tiebreaker. Follow
+// @Description meta.next_cursor (or the rel="next" Link header) to walk every page; limit/offset is kept
+// @Description for backward compatibility.
 // @Tags subscriptions
 // @Accept json
 // @Produce json
+// @Param q query string false "Fuzzy, case-insensitive service name search; results are ranked by similarity"
 // @Param user_id query string false "Filter by User ID"
+// @Param service_name query string false "Exact service name"
+// @Param service_prefix query string false "Case-insensitive service name prefix"
+// @Param price_min query int false "Minimum price, inclusive"
+// @Param price_max query int false "Maximum price, inclusive"
+// @Param active_on query string false "Active on date YYYY-MM-DD"
+// @Param active_in query string false "Active in month MM-YYYY"
+// @Param started_from query string false "Started in or after month MM-YYYY"
+// @Param started_to query string false "Started in or before month MM-YYYY"
+// @Param ended_from query string false "Ended in or after month MM-YYYY"
+// @Param ended_to query string false "Ended in or before month MM-YYYY"
+// @Param has_end_date query bool false "Only subscriptions with (true) or without (false) an end date"
+// @Param ending_within query int false "Only subscriptions whose last billed day is within this many days from today"
+// @Param sort query string false "Comma-separated fields among id, service_name, price, start_date, end_date, relevance; prefix with - for descending"
 // @Param limit query int false "Pagination limit"
-// @Param offset query int false "Pagination offset"
-// @Success 200 {array} Response{data=SubscriptionResponse} "OK"
+// @Param offset query int false "Pagination offset, cannot be combined with cursor"
+// @Param cursor query string false "Opaque cursor from meta.next_cursor"
+// @Param include_total query bool false "Include the total number of matching rows"
+// @Param as_of query string false "Read data as stored at this RFC 3339 timestamp"
+// @Success 200 {object} Response{data=[]SubscriptionResponse,meta=PageMeta} "OK"
+// @Header 200 {string} Link "RFC 8288 link to the next page"
+// @Header 200 {integer} X-Total-Count "Total number of matching rows, when include_total is set"
 // @Failure 400 {object} Response "Invalid query parameters"
+// @Failure 422 {object} Response "Validation failed"
 // @Failure 500 {object} Response "Internal server error"
 // @Router /subscriptions [get]
 func (h *subscriptionHandler) ListSubscriptions(c *gin.Context) {
@@ -170,28 +463,89 @@ func (h *subscriptionHandler) ListSubscriptions(c *gin.Context) {
 	params, err := req.ToParams()
 	if err != nil {
 		slog.Debug("failed to parse ListSubscriptionsRequest", "error", err, "query", req)
-		JSONErrorMessage(c, http.StatusBadRequest, err.Error())
+		JSONAppError(c, err)
 		return
 	}
 
-	subs, err := h.subscriptionService.ListSubscriptions(c.Request.Context(), params)
+	page, err := h.subscriptionService.ListSubscriptions(c.Request.Context(), params)
 	if err != nil {
-		slog.Error("failed to list subscriptions", "error", err)
-		JSONError(c, http.StatusInternalServerError, err)
+		slog.Debug("failed to list subscriptions", "error", err)
+		JSONAppError(c, err)
 		return
 	}
 
-	responses := make([]SubscriptionResponse, len(subs))
-	for i, s := range subs {
+	writeSubscriptionPage(c, page)
+}
+
+// writeSubscriptionPage writes page with its pagination meta, Link and
+// X-Total-Count headers.
+func writeSubscriptionPage(c *gin.Context, page *model.SubscriptionPage) {
+	responses := make([]SubscriptionResponse, len(page.Items))
+	for i, s := range page.Items {
 		responses[i] = ToSubscriptionResponse(s)
 	}
 
+	meta := PageMeta{Limit: page.Limit, Total: page.Total}
+	if page.Next != nil {
+		next := EncodeCursor(*page.Next)
+		meta.NextCursor = &next
+		SetNextLink(c, next, page.Limit)
+	}
+	if page.Total != nil {
+		c.Header("X-Total-Count", strconv.FormatInt(*page.Total, 10))
+	}
+
+	JSONSuccessWithMeta(c, http.StatusOK, responses, meta)
+}
+
+// SuggestServices godoc
+// @Summary Suggest service names
+// @Description Autocomplete distinct service names that fuzzy-match or start with q, best matches first
+// @Tags services
+// @Accept json
+// @Produce json
+// @Param q query string true "Partial service name"
+// @Param limit query int false "Maximum number of suggestions"
+// @Success 200 {object} Response{data=[]ServiceSuggestionResponse} "OK"
+// @Failure 400 {object} Response "Invalid query parameters"
+// @Failure 422 {object} Response "Validation failed"
+// @Failure 500 {object} Response "Internal server error"
+// @Router /services/suggest [get]
+func (h *subscriptionHandler) SuggestServices(c *gin.Context) {
+	var req SuggestServicesRequest
+	if err := c.BindQuery(&req); err != nil {
+		slog.Debug("invalid query params for SuggestServices", "error", err)
+		JSONErrorMessage(c, http.StatusBadRequest, "invalid query parameters")
+		return
+	}
+
+	suggestions, err := h.subscriptionService.SuggestServiceNames(c.Request.Context(), req.ToParams())
+	if err != nil {
+		slog.Debug("failed to suggest service names", "error", err)
+		JSONAppError(c, err)
+		return
+	}
+
+	responses := make([]ServiceSuggestionResponse, len(suggestions))
+	for i, s := range suggestions {
+		responses[i] = ServiceSuggestionResponse{
+			Name:          s.Name,
+			Subscriptions: s.Subscriptions,
+			Score:         s.Score,
+		}
+	}
+
 	JSONSuccess(c, http.StatusOK, responses)
 }
 
 // GetSumOfSubscriptionPrices godoc
 // @Summary Get sum of subscription prices
-// @Description Get total subscription prices for a period, optionally filtered by user or service
+// @Description Get the total cost of subscriptions over a period, optionally filtered by user or service.
+// @Description Every subscription active in any month of the period adds its monthly equivalent for each overlapped
+// @Description month, e.g. a twelfth of a yearly price; open-ended subscriptions are clipped to period_end.
+// @Description Items show how the total was computed. Amounts are in minor units. Each month is converted to currency
+// @Description with the latest exchange rate dated within or before it; currency can be left out when all subscriptions
+// @Description share one. rates lists the rates used.
 // @Tags subscriptions
 // @Accept json
 // @Produce json
@@ -199,8 +553,11 @@ func (h *subscriptionHandler) ListSubscriptions(c *gin.Context) {
 // @Param period_end query string true "Period end MM-YYYY"
 // @Param user_id query string false "Filter by User ID"
 // @Param service_name query string false "Filter by Service name"
-// @Success 200 {object} Response{data=map[string]int64} "OK"
+// @Param as_of query string false "Read data as stored at this RFC 3339 timestamp"
+// @Param currency query string false "ISO 4217 currency to convert amounts to"
+// @Success 200 {object} Response{data=SumOfSubscriptionPricesResponse} "OK"
 // @Failure 400 {object} Response "Invalid query parameters"
+// @Failure 422 {object} Response "Validation failed"
 // @Failure 500 {object} Response "Internal server error"
 // @Router /subscriptions/sum [get]
 func (h *subscriptionHandler) GetSumOfSubscriptionPrices(c *gin.Context) {
@@ -214,16 +571,314 @@ func (h *subscriptionHandler) GetSumOfSubscriptionPrices(c *gin.Context) {
 	params, err := req.ToParams()
 	if err != nil {
 		slog.Debug("failed to parse SumOfSubscriptionPricesRequest", "error", err, "query", req)
-		JSONErrorMessage(c, http.StatusBadRequest, err.Error())
+		JSONAppError(c, err)
 		return
 	}
 
 	sum, err := h.subscriptionService.GetSumOfSubscriptionPrices(c.Request.Context(), params)
 	if err != nil {
-		slog.Error("failed to calculate sum of subscription prices", "error", err)
-		JSONError(c, http.StatusInternalServerError, err)
+		slog.Debug("failed to calculate sum of subscription prices", "error", err)
+		JSONAppError(c, err)
+		return
+	}
+
+	JSONSuccess(c, http.StatusOK, ToSumOfSubscriptionPricesResponse(*sum))
+}
+
+// GetSpendTimeSeries godoc
+// @Summary Get monthly spend time series
+// @Description Get the spend and number of active subscriptions for every month of a period. Prices are normalized
+// @Description from their billing interval to a month.
+// @Description Buckets can optionally be split by service_name or user_id. Each month is converted to currency with
+// @Description its own exchange rates, listed in the bucket; currency can be left out when all subscriptions share one.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param period_start query string true "Period start MM-YYYY"
+// @Param period_end query string true "Period end MM-YYYY"
+// @Param user_id query string false "Filter by User ID"
+// @Param service_name query string false "Filter by Service name"
+// @Param group_by query string false "Split buckets by service_name or user_id"
+// @Param as_of query string false "Read data as stored at this RFC 3339 timestamp"
+// @Param currency query string false "ISO 4217 currency to convert amounts to"
+// @Success 200 {object} Response{data=[]SpendBucketResponse} "OK"
+// @Failure 400 {object} Response "Invalid query parameters"
+// @Failure 422 {object} Response "Validation failed"
+// @Failure 500 {object} Response "Internal server error"
+// @Router /subscriptions/spend/timeseries [get]
+func (h *subscriptionHandler) GetSpendTimeSeries(c *gin.Context) {
+	var req SpendTimeSeriesRequest
+	if err := c.BindQuery(&req); err != nil {
+		slog.Debug("invalid query params for GetSpendTimeSeries", "error", err)
+		JSONErrorMessage(c, http.StatusBadRequest, "invalid query parameters")
+		return
+	}
+
+	params, err := req.ToParams()
+	if err != nil {
+		slog.Debug("failed to parse SpendTimeSeriesRequest", "error", err, "query", req)
+		JSONAppError(c, err)
+		return
+	}
+
+	buckets, err := h.subscriptionService.GetSpendTimeSeries(c.Request.Context(), params)
+	if err != nil {
+		slog.Debug("failed to calculate spend time series", "error", err)
+		JSONAppError(c, err)
+		return
+	}
+
+	responses := make([]SpendBucketResponse, len(buckets))
+	for i, b := range buckets {
+		responses[i] = ToSpendBucketResponse(b)
+	}
+
+	JSONSuccess(c, http.StatusOK, responses)
+}
+
+// ListSubscriptionPrices godoc
+// @Summary List subscription prices
+// @Description The price history of a subscription, oldest first. Each price and the billing interval it is paid per
+// @Description apply from its effective month until the next price takes effect
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Param as_of query string false "Read the prices as recorded at this RFC 3339 timestamp"
+// @Success 200 {object} Response{data=[]SubscriptionPriceResponse} "OK"
+// @Failure 400 {object} Response "Invalid ID or query parameters"
+// @Failure 404 {object} Response "Not found"
+// @Failure 422 {object} Response "Validation failed"
+// @Failure 500 {object} Response "Internal server error"
+// @Router /subscriptions/{id}/prices [get]
+func (h *subscriptionHandler) ListSubscriptionPrices(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		slog.Debug("invalid subscription id param", "param", idStr, "error", err)
+		JSONErrorMessage(c, http.StatusBadRequest, "invalid subscription id")
+		return
+	}
+
+	var req GetSubscriptionRequest
+	if err := c.BindQuery(&req); err != nil {
+		slog.Debug("invalid query params for ListSubscriptionPrices", "error", err)
+		JSONErrorMessage(c, http.StatusBadRequest, "invalid query parameters")
+		return
+	}
+
+	asOf, err := req.AsOfTime()
+	if err != nil {
+		JSONAppError(c, err)
+		return
+	}
+
+	prices, err := h.subscriptionService.ListPrices(c.Request.Context(), id, asOf)
+	if err != nil {
+		slog.Debug("failed to list subscription prices", "id", id, "error", err)
+		JSONAppError(c, err)
+		return
+	}
+
+	JSONSuccess(c, http.StatusOK, ToSubscriptionPriceResponses(prices))
+}
+
+// ChangeServicePrice godoc
+// @Summary Change the price of a service
+// @Description Change the price of every subscription to a service that pays old_price in effective_month, or in its
+// @Description first paid month when that is later, to new_price from that month on, whatever its status. Subscriptions
+// @Description ending before effective_month are left out. Optionally limited to one user.
+// @Description With dry_run the affected subscriptions and the change in monthly spend are only previewed; otherwise all of
+// @Description them are updated in one transaction, each with an audit entry
+// @Tags services
+// @Accept json
+// @Produce json
+// @Param name path string true "Service name or catalog alias"
+// @Param change body ServicePriceChangeRequest true "Price change"
+// @Success 200 {object} Response{data=ServicePriceChangeResponse} "Previewed or applied"
+// @Failure 400 {object} Response "Invalid request"
+// @Failure 422 {object} Response "Validation failed"
+// @Failure 500 {object} Response "Internal server error"
+// @Router /services/{name}/price-change [post]
+func (h *subscriptionHandler) ChangeServicePrice(c *gin.Context) {
+	name := c.Param("name")
+
+	var req ServicePriceChangeRequest
+	if err := c.BindJSON(&req); err != nil {
+		slog.Debug("invalid request body for price change", "error", err)
+		JSONErrorMessage(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	params, err := req.ToParams(name)
+	if err != nil {
+		slog.Debug("failed to parse ServicePriceChangeRequest", "error", err, "body", req)
+		JSONAppError(c, err)
+		return
+	}
+
+	change, err := h.subscriptionService.ChangeServicePrice(c.Request.Context(), params)
+	if err != nil {
+		slog.Debug("failed to change service price", "service_name", name, "error", err)
+		JSONAppError(c, err)
+		return
+	}
+
+	if !params.DryRun {
+		slog.Info("service price changed", "service_name", change.Params.Service,
+			"new_price", params.NewPrice, "subscriptions", len(change.Subscriptions))
+	}
+	JSONSuccess(c, http.StatusOK, ToServicePriceChangeResponse(*change))
+}
+
+// PauseSubscription godoc
+// @Summary Pause subscription
+// @Description Stop billing an active subscription from effective_month on, the current month by default. Paused months
+// @Description are left out of sums and time series. effective_month cannot be in the future
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Param change body ChangeStatusRequest false "Effective month"
+// @Success 200 {object} Response{data=SubscriptionResponse} "Paused"
+// @Failure 400 {object} Response "Invalid request or ID"
+// @Failure 404 {object} Response "Not found"
+// @Failure 409 {object} Response "Subscription is not active"
+// @Failure 422 {object} Response "Validation failed"
+// @Failure 500 {object} Response "Internal server error"
+// @Router /subscriptions/{id}/pause [post]
+func (h *subscriptionHandler) PauseSubscription(c *gin.Context) {
+	h.changeStatus(c, model.ActionPause)
+}
+
+// ResumeSubscription godoc
+// @Summary Resume subscription
+// @Description Bill a paused subscription again from effective_month on, the current month by default.
+// @Description effective_month cannot be in the future
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Param change body ChangeStatusRequest false "Effective month"
+// @Success 200 {object} Response{data=SubscriptionResponse} "Resumed"
+// @Failure 400 {object} Response "Invalid request or ID"
+// @Failure 404 {object} Response "Not found"
+// @Failure 409 {object} Response "Subscription is not paused"
+// @Failure 422 {object} Response "Validation failed"
+// @Failure 500 {object} Response "Internal server error"
+// @Router /subscriptions/{id}/resume [post]
+func (h *subscriptionHandler) ResumeSubscription(c *gin.Context) {
+	h.changeStatus(c, model.ActionResume)
+}
+
+// CancelSubscription godoc
+// @Summary Cancel subscription
+// @Description Cancel a trial, active or paused subscription with effective_month, the current month by default, as its
+// @Description last billed month. Until that month has passed the subscription is pending_cancel. With at_period_end a
+// @Description trial or active subscription is instead canceled on the last day of its current billing period, computed
+// @Description from its billing interval and anchor day, or of its trial; it is pending_cancel until that day and can be
+// @Description reactivated until then.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Param change body ChangeStatusRequest false "Last billed month, or at_period_end"
+// @Success 200 {object} Response{data=SubscriptionResponse} "Canceled"
+// @Failure 400 {object} Response "Invalid request or ID"
+// @Failure 404 {object} Response "Not found"
+// @Failure 409 {object} Response "Subscription is already canceled or expired, or paused with at_period_end"
+// @Failure 422 {object} Response "Validation failed"
+// @Failure 500 {object} Response "Internal server error"
+// @Router /subscriptions/{id}/cancel [post]
+func (h *subscriptionHandler) CancelSubscription(c *gin.Context) {
+	h.changeStatus(c, model.ActionCancel)
+}
+
+// ReactivateSubscription godoc
+// @Summary Reactivate subscription
+// @Description Undo the cancellation of a pending_cancel subscription, or bill a canceled or expired subscription again
+// @Description from effective_month on, the current month by default. The months since it ended count as paused
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Param change body ChangeStatusRequest false "Effective month"
+// @Success 200 {object} Response{data=SubscriptionResponse} "Reactivated"
+// @Failure 400 {object} Response "Invalid request or ID"
+// @Failure 404 {object} Response "Not found"
+// @Failure 409 {object} Response "Subscription is not canceled or expired"
+// @Failure 422 {object} Response "Validation failed"
+// @Failure 500 {object} Response "Internal server error"
+// @Router /subscriptions/{id}/reactivate [post]
+func (h *subscriptionHandler) ReactivateSubscription(c *gin.Context) {
+	h.changeStatus(c, model.ActionReactivate)
+}
+
+// changeStatus takes action on the subscription of the request. The body is
+// optional.
+func (h *subscriptionHandler) changeStatus(c *gin.Context, action model.StatusAction) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		slog.Debug("invalid subscription id param", "param", idStr, "error", err)
+		JSONErrorMessage(c, http.StatusBadRequest, "invalid subscription id")
+		return
+	}
+
+	var req ChangeStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		slog.Debug("invalid request body for status change", "action", action, "error", err)
+		JSONErrorMessage(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	params, err := req.ToParams(action)
+	if err != nil {
+		slog.Debug("failed to parse ChangeStatusRequest", "error", err, "body", req)
+		JSONAppError(c, err)
+		return
+	}
+
+	sub, err := h.subscriptionService.ChangeStatus(c.Request.Context(), id, params)
+	if err != nil {
+		slog.Debug("failed to change subscription status", "id", id, "action", action, "error", err)
+		JSONAppError(c, err)
+		return
+	}
+
+	slog.Info("subscription status changed", "id", sub.ID, "action", action, "status", sub.Status)
+	SetETag(c, SubscriptionETag(*sub, nil))
+	JSONSuccess(c, http.StatusOK, ToSubscriptionResponse(*sub))
+}
+
+// ListStatusChanges godoc
+// @Summary List subscription status changes
+// @Description The status transitions of a subscription, oldest first, with the month each took effect and when it
+// @Description was made
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Success 200 {object} Response{data=[]StatusChangeResponse} "OK"
+// @Failure 400 {object} Response "Invalid ID"
+// @Failure 404 {object} Response "Not found"
+// @Failure 500 {object} Response "Internal server error"
+// @Router /subscriptions/{id}/status-changes [get]
+func (h *subscriptionHandler) ListStatusChanges(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		slog.Debug("invalid subscription id param", "param", idStr, "error", err)
+		JSONErrorMessage(c, http.StatusBadRequest, "invalid subscription id")
+		return
+	}
+
+	changes, err := h.subscriptionService.ListStatusChanges(c.Request.Context(), id)
+	if err != nil {
+		slog.Debug("failed to list subscription status changes", "id", id, "error", err)
+		JSONAppError(c, err)
 		return
 	}
 
-	JSONSuccess(c, http.StatusOK, map[string]int64{"total_price": sum})
+	JSONSuccess(c, http.StatusOK, ToStatusChangeResponses(changes))
 }
@@ -0,0 +1,283 @@
+package handler
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/morphlinkk/subscriptions/internal/apperr"
+	"github.com/morphlinkk/subscriptions/internal/ical"
+	"github.com/morphlinkk/subscriptions/internal/model"
+)
+
+type UserResponse struct {
+	ID        string  `json:"id"`
+	Name      *string `json:"name"`
+	Email     *string `json:"email"`
+	CreatedAt string  `json:"created_at"` // RFC 3339
+}
+
+func ToUserResponse(u model.User) UserResponse {
+	return UserResponse{
+		ID:        u.ID.String(),
+		Name:      u.Name,
+		Email:     u.Email,
+		CreatedAt: u.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+type AddUserRequest struct {
+	ID    *string `json:"id" validate:"uuid"` // generated when omitted
+	Name  string  `json:"name" validate:"required"`
+	Email *string `json:"email"`
+}
+
+func (r AddUserRequest) ToParams() (model.AddUserParams, error) {
+	params := model.AddUserParams{
+		Name:  r.Name,
+		Email: r.Email,
+	}
+	if r.ID != nil {
+		id, err := parseUUID("id", *r.ID)
+		if err != nil {
+			return model.AddUserParams{}, err
+		}
+		params.ID = &id
+	}
+	return params, nil
+}
+
+type UpdateUserRequest struct {
+	Name  *string `json:"name"`
+	Email *string `json:"email"`
+}
+
+func (r UpdateUserRequest) ToParams() model.UpdateUserParams {
+	return model.UpdateUserParams{
+		Name:  r.Name,
+		Email: r.Email,
+	}
+}
+
+type ListUsersRequest struct {
+	Limit  int `form:"limit"`
+	Offset int `form:"offset"`
+}
+
+func (r ListUsersRequest) ToParams() model.ListUsersParams {
+	return model.ListUsersParams{
+		Limit:  r.Limit,
+		Offset: r.Offset,
+	}
+}
+
+type RenewalsRequest struct {
+	From     *string `form:"from"`     // YYYY-MM-DD, default today
+	To       *string `form:"to"`       // YYYY-MM-DD, default 30 days after from
+	Currency *string `form:"currency"` // ISO 4217
+}
+
+func (r RenewalsRequest) ToParams(userID uuid.UUID) (model.RenewalsParams, error) {
+	params := model.RenewalsParams{
+		UserID:   userID,
+		Currency: r.Currency,
+	}
+
+	if r.From != nil {
+		from, err := parseDay("from", *r.From)
+		if err != nil {
+			return params, err
+		}
+		params.From = &from
+	}
+
+	if r.To != nil {
+		to, err := parseDay("to", *r.To)
+		if err != nil {
+			return params, err
+		}
+		params.To = &to
+	}
+
+	return params, nil
+}
+
+type RenewalResponse struct {
+	Date           string `json:"date"` // YYYY-MM-DD
+	SubscriptionID int64  `json:"subscription_id"`
+	Service        string `json:"service_name"`
+	Amount         int    `json:"amount"`   // minor units of currency
+	Currency       string `json:"currency"` // ISO 4217
+	// BillingInterval and BillingIntervalCount tell how often Amount is paid.
+	BillingInterval      string `json:"billing_interval"`
+	BillingIntervalCount int    `json:"billing_interval_count"`
+	// RunningTotal adds up this and every earlier renewal in the currency of
+	// the calendar.
+	RunningTotal int64 `json:"running_total"`
+}
+
+type RenewalCalendarResponse struct {
+	From     string            `json:"from"`     // YYYY-MM-DD
+	To       string            `json:"to"`       // YYYY-MM-DD
+	Currency string            `json:"currency"` // ISO 4217, of the totals
+	Total    int64             `json:"total"`
+	Renewals []RenewalResponse `json:"renewals"`
+	// Rates are the exchange rates used to convert each renewal.
+	Rates []AppliedRateResponse `json:"rates"`
+}
+
+func ToRenewalCalendarResponse(c model.RenewalCalendar) RenewalCalendarResponse {
+	renewals := make([]RenewalResponse, len(c.Renewals))
+	for i, r := range c.Renewals {
+		renewals[i] = RenewalResponse{
+			Date:                 r.Date.Format(dayLayout),
+			SubscriptionID:       r.SubscriptionID,
+			Service:              r.Service,
+			Amount:               r.Amount,
+			Currency:             r.Currency,
+			BillingInterval:      string(r.Billing.Unit),
+			BillingIntervalCount: r.Billing.Count,
+			RunningTotal:         r.RunningTotal,
+		}
+	}
+
+	rates := make([]AppliedRateResponse, len(c.Rates))
+	for i, r := range c.Rates {
+		rates[i] = AppliedRateResponse{
+			Month:                r.Month.Format(dateLayout),
+			ExchangeRateResponse: toExchangeRateResponse(r.ExchangeRate),
+		}
+	}
+
+	return RenewalCalendarResponse{
+		From:     c.From.Format(dayLayout),
+		To:       c.To.Format(dayLayout),
+		Currency: c.Currency,
+		Total:    c.Total,
+		Renewals: renewals,
+		Rates:    rates,
+	}
+}
+
+type CalendarTokenResponse struct {
+	// Token is only shown once; rotating it again invalidates it.
+	Token string `json:"token"`
+	// URL is the path of the calendar feed with the token, to subscribe to.
+	URL string `json:"url"`
+}
+
+func ToCalendarTokenResponse(userID uuid.UUID, token string) CalendarTokenResponse {
+	feed := url.URL{
+		Path:     "/users/" + userID.String() + "/renewals.ics",
+		RawQuery: url.Values{"token": {token}}.Encode(),
+	}
+	return CalendarTokenResponse{
+		Token: token,
+		URL:   feed.String(),
+	}
+}
+
+const (
+	defaultReminderDays = 1
+	maxReminderDays     = 30
+)
+
+type RenewalsFeedRequest struct {
+	Token        string `form:"token"`
+	ReminderDays *int   `form:"reminder_days"` // default 1
+}
+
+// ReminderTrigger returns the VALARM trigger reminding of a renewal
+// ReminderDays days ahead.
+func (r RenewalsFeedRequest) ReminderTrigger() (string, error) {
+	days := defaultReminderDays
+	if r.ReminderDays != nil {
+		days = *r.ReminderDays
+	}
+	if days < 0 || days > maxReminderDays {
+		return "", apperr.InvalidField("reminder_days", apperr.CodeOutOfRange,
+			fmt.Sprintf("reminder_days must be between 0 and %d", maxReminderDays))
+	}
+	if days == 0 {
+		return "PT0S", nil
+	}
+	return fmt.Sprintf("-P%dD", days), nil
+}
+
+// ToRenewalsCalendar returns one recurring all-day event per schedule, on the
+// days the subscription renews, with a reminder at trigger. UIDs are derived
+// from subscription IDs and sequences from their versions, so calendar apps
+// update the events they already have.
+func ToRenewalsCalendar(schedules []model.RenewalSchedule, trigger string, now time.Time) ical.Calendar {
+	cal := ical.Calendar{
+		ProdID: "-//morphlinkk//subscriptions//EN",
+		Name:   "Subscription renewals",
+		Events: make([]ical.Event, len(schedules)),
+	}
+	for i, sched := range schedules {
+		sub := sched.Subscription
+		amount := model.FormatAmount(sub.Price, sub.Currency)
+		cal.Events[i] = ical.Event{
+			UID:         fmt.Sprintf("subscription-%d@subscriptions", sub.ID),
+			Sequence:    sub.Version,
+			Stamp:       now,
+			Start:       sched.First,
+			Summary:     fmt.Sprintf("%s renewal: %s", sub.Service, amount),
+			Description: fmt.Sprintf("%s renews for %s every %s.", sub.Service, amount, describeBilling(sub.Billing)),
+			RRule:       billingRRule(sub.Billing, sched.First, sched.Until),
+			ExDates:     sched.Skipped,
+			Alarms: []ical.Alarm{{
+				Trigger:     trigger,
+				Description: fmt.Sprintf("%s renews for %s", sub.Service, amount),
+			}},
+		}
+	}
+	return cal
+}
+
+// isoWeekdays are the RRULE weekdays by ISO weekday number.
+var isoWeekdays = [...]string{1: "MO", 2: "TU", 3: "WE", 4: "TH", 5: "FR", 6: "SA", 7: "SU"}
+
+// billingRRule returns the RRULE of the billing days of b from first, which
+// is one of them, until the day until when set.
+func billingRRule(b model.BillingInterval, first time.Time, until *time.Time) string {
+	var rule string
+	switch b.Unit {
+	case model.BillingWeek:
+		rule = fmt.Sprintf("FREQ=WEEKLY;INTERVAL=%d;BYDAY=%s", b.Count, isoWeekdays[b.AnchorDay])
+	case model.BillingQuarter:
+		rule = fmt.Sprintf("FREQ=MONTHLY;INTERVAL=%d;%s", 3*b.Count, byMonthDay(b.AnchorDay))
+	case model.BillingYear:
+		rule = fmt.Sprintf("FREQ=YEARLY;INTERVAL=%d;BYMONTH=%d;%s", b.Count, first.Month(), byMonthDay(b.AnchorDay))
+	default:
+		rule = fmt.Sprintf("FREQ=MONTHLY;INTERVAL=%d;%s", b.Count, byMonthDay(b.AnchorDay))
+	}
+	if until != nil {
+		rule += ";UNTIL=" + ical.Date(*until)
+	}
+	return rule
+}
+
+// byMonthDay returns the RRULE parts selecting anchor day, or the last day of
+// months that are shorter: the latest of the days from the 28th to anchor.
+func byMonthDay(anchor int) string {
+	if anchor <= 28 {
+		return fmt.Sprintf("BYMONTHDAY=%d", anchor)
+	}
+	days := make([]string, 0, anchor-27)
+	for d := 28; d <= anchor; d++ {
+		days = append(days, strconv.Itoa(d))
+	}
+	return "BYMONTHDAY=" + strings.Join(days, ",") + ";BYSETPOS=-1"
+}
+
+// describeBilling returns b in words, e.g. "month" or "3 months".
+func describeBilling(b model.BillingInterval) string {
+	if b.Count == 1 {
+		return string(b.Unit)
+	}
+	return fmt.Sprintf("%d %ss", b.Count, b.Unit)
+}
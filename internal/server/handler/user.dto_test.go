@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/morphlinkk/subscriptions/internal/model"
+)
+
+func TestByMonthDay(t *testing.T) {
+	tests := []struct {
+		anchor int
+		want   string
+	}{
+		{1, "BYMONTHDAY=1"},
+		{28, "BYMONTHDAY=28"},
+		{29, "BYMONTHDAY=28,29;BYSETPOS=-1"},
+		{30, "BYMONTHDAY=28,29,30;BYSETPOS=-1"},
+		{31, "BYMONTHDAY=28,29,30,31;BYSETPOS=-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := byMonthDay(tt.anchor); got != tt.want {
+				t.Errorf("byMonthDay(%d) = %q, want %q", tt.anchor, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBillingRRule(t *testing.T) {
+	until := time.Date(2027, time.March, 31, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		billing model.BillingInterval
+		first   time.Time
+		until   *time.Time
+		want    string
+	}{
+		{
+			name:    "monthly",
+			billing: model.BillingInterval{Unit: model.BillingMonth, Count: 1, AnchorDay: 15},
+			first:   time.Date(2026, time.October, 15, 0, 0, 0, 0, time.UTC),
+			want:    "FREQ=MONTHLY;INTERVAL=1;BYMONTHDAY=15",
+		},
+		{
+			name:    "monthly anchor 31 until a day",
+			billing: model.BillingInterval{Unit: model.BillingMonth, Count: 1, AnchorDay: 31},
+			first:   time.Date(2026, time.October, 31, 0, 0, 0, 0, time.UTC),
+			until:   &until,
+			want:    "FREQ=MONTHLY;INTERVAL=1;BYMONTHDAY=28,29,30,31;BYSETPOS=-1;UNTIL=20270331",
+		},
+		{
+			name:    "every two weeks",
+			billing: model.BillingInterval{Unit: model.BillingWeek, Count: 2, AnchorDay: 1},
+			first:   time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC),
+			want:    "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO",
+		},
+		{
+			name:    "weekly on sunday",
+			billing: model.BillingInterval{Unit: model.BillingWeek, Count: 1, AnchorDay: 7},
+			first:   time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC),
+			want:    "FREQ=WEEKLY;INTERVAL=1;BYDAY=SU",
+		},
+		{
+			name:    "quarterly",
+			billing: model.BillingInterval{Unit: model.BillingQuarter, Count: 1, AnchorDay: 1},
+			first:   time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC),
+			want:    "FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=1",
+		},
+		{
+			name:    "yearly anchor 29 falls back in february",
+			billing: model.BillingInterval{Unit: model.BillingYear, Count: 1, AnchorDay: 29},
+			first:   time.Date(2027, time.February, 28, 0, 0, 0, 0, time.UTC),
+			want:    "FREQ=YEARLY;INTERVAL=1;BYMONTH=2;BYMONTHDAY=28,29;BYSETPOS=-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := billingRRule(tt.billing, tt.first, tt.until); got != tt.want {
+				t.Errorf("billingRRule() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,484 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/morphlinkk/subscriptions/internal/ical"
+	"github.com/morphlinkk/subscriptions/internal/server/service"
+)
+
+type UserHandler interface {
+	AddUser(c *gin.Context)
+	GetUserByID(c *gin.Context)
+	UpdateUser(c *gin.Context)
+	DeleteUser(c *gin.Context)
+	ListUsers(c *gin.Context)
+	ListUserSubscriptions(c *gin.Context)
+	GetUserSpend(c *gin.Context)
+	ListUserRenewals(c *gin.Context)
+	RotateCalendarToken(c *gin.Context)
+	GetRenewalsFeed(c *gin.Context)
+}
+
+type userHandler struct {
+	userService         service.UserService
+	subscriptionService service.SubscriptionService
+}
+
+func NewUserHandler(userService service.UserService, subscriptionService service.SubscriptionService) UserHandler {
+	return &userHandler{
+		userService:         userService,
+		subscriptionService: subscriptionService,
+	}
+}
+
+// AddUser godoc
+// @Summary Add a user
+// @Description Add a user. The id is generated unless one is given, e.g. to register an existing user id
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param user body AddUserRequest true "User info"
+// @Success 201 {object} Response{data=UserResponse} "Created"
+// @Failure 400 {object} Response "Invalid request"
+// @Failure 409 {object} Response "User id or email already in use"
+// @Failure 422 {object} Response "Validation failed"
+// @Failure 500 {object} Response "Internal server error"
+// @Router /users [post]
+func (h *userHandler) AddUser(c *gin.Context) {
+	var req AddUserRequest
+	if err := c.BindJSON(&req); err != nil {
+		slog.Debug("invalid request body", "error", err)
+		JSONErrorMessage(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	params, err := req.ToParams()
+	if err != nil {
+		slog.Debug("failed to parse AddUserRequest", "error", err)
+		JSONAppError(c, err)
+		return
+	}
+
+	user, err := h.userService.AddUser(c.Request.Context(), params)
+	if err != nil {
+		slog.Debug("failed to add user", "error", err)
+		JSONAppError(c, err)
+		return
+	}
+
+	slog.Info("user created", "id", user.ID)
+	JSONSuccess(c, http.StatusCreated, ToUserResponse(*user))
+}
+
+// GetUserByID godoc
+// @Summary Get user by ID
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} Response{data=UserResponse} "OK"
+// @Failure 400 {object} Response "Invalid ID"
+// @Failure 404 {object} Response "Not found"
+// @Failure 500 {object} Response "Internal server error"
+// @Router /users/{id} [get]
+func (h *userHandler) GetUserByID(c *gin.Context) {
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.userService.GetByID(c.Request.Context(), id)
+	if err != nil {
+		slog.Debug("failed to get user by id", "id", id, "error", err)
+		JSONAppError(c, err)
+		return
+	}
+
+	JSONSuccess(c, http.StatusOK, ToUserResponse(*user))
+}
+
+// UpdateUser godoc
+// @Summary Update user
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param user body UpdateUserRequest true "User update info"
+// @Success 200 {object} Response{data=UserResponse} "Updated"
+// @Failure 400 {object} Response "Invalid request or ID"
+// @Failure 404 {object} Response "Not found"
+// @Failure 409 {object} Response "Email already in use"
+// @Failure 422 {object} Response "Validation failed"
+// @Failure 500 {object} Response "Internal server error"
+// @Router /users/{id} [patch]
+func (h *userHandler) UpdateUser(c *gin.Context) {
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateUserRequest
+	if err := c.BindJSON(&req); err != nil {
+		slog.Debug("invalid request body for update", "error", err)
+		JSONErrorMessage(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	user, err := h.userService.UpdateUser(c.Request.Context(), id, req.ToParams())
+	if err != nil {
+		slog.Debug("failed to update user", "id", id, "error", err)
+		JSONAppError(c, err)
+		return
+	}
+
+	slog.Info("user updated", "id", user.ID)
+	JSONSuccess(c, http.StatusOK, ToUserResponse(*user))
+}
+
+// DeleteUser godoc
+// @Summary Delete user
+// @Description Delete a user that has no subscriptions left, including soft-deleted ones
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 204 "Deleted"
+// @Failure 400 {object} Response "Invalid ID"
+// @Failure 404 {object} Response "Not found"
+// @Failure 409 {object} Response "User still has subscriptions"
+// @Failure 500 {object} Response "Internal server error"
+// @Router /users/{id} [delete]
+func (h *userHandler) DeleteUser(c *gin.Context) {
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.userService.DeleteUser(c.Request.Context(), id); err != nil {
+		slog.Debug("failed to delete user", "id", id, "error", err)
+		JSONAppError(c, err)
+		return
+	}
+
+	slog.Info("user deleted", "id", id)
+	c.Status(http.StatusNoContent)
+}
+
+// ListUsers godoc
+// @Summary List users
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param limit query int false "Pagination limit"
+// @Param offset query int false "Pagination offset"
+// @Success 200 {object} Response{data=[]UserResponse} "OK"
+// @Failure 400 {object} Response "Invalid query parameters"
+// @Failure 500 {object} Response "Internal server error"
+// @Router /users [get]
+func (h *userHandler) ListUsers(c *gin.Context) {
+	var req ListUsersRequest
+	if err := c.BindQuery(&req); err != nil {
+		slog.Debug("invalid query params for ListUsers", "error", err)
+		JSONErrorMessage(c, http.StatusBadRequest, "invalid query parameters")
+		return
+	}
+
+	users, err := h.userService.ListUsers(c.Request.Context(), req.ToParams())
+	if err != nil {
+		slog.Debug("failed to list users", "error", err)
+		JSONAppError(c, err)
+		return
+	}
+
+	responses := make([]UserResponse, len(users))
+	for i, u := range users {
+		responses[i] = ToUserResponse(u)
+	}
+
+	JSONSuccess(c, http.StatusOK, responses)
+}
+
+// ListUserSubscriptions godoc
+// @Summary List a user's subscriptions
+// @Description Same as GET /subscriptions, restricted to the user in the path
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param q query string false "Fuzzy, case-insensitive service name search; results are ranked by similarity"
+// @Param service_name query string false "Exact service name"
+// @Param service_prefix query string false "Case-insensitive service name prefix"
+// @Param price_min query int false "Minimum price, inclusive"
+// @Param price_max query int false "Maximum price, inclusive"
+// @Param active_on query string false "Active on date YYYY-MM-DD"
+// @Param active_in query string false "Active in month MM-YYYY"
+// @Param started_from query string false "Started in or after month MM-YYYY"
+// @Param started_to query string false "Started in or before month MM-YYYY"
+// @Param ended_from query string false "Ended in or after month MM-YYYY"
+// @Param ended_to query string false "Ended in or before month MM-YYYY"
+// @Param has_end_date query bool false "Only subscriptions with (true) or without (false) an end date"
+// @Param ending_within query int false "Only subscriptions whose last billed day is within this many days from today"
+// @Param sort query string false "Comma-separated fields among id, service_name, price, start_date, end_date, relevance; prefix with - for descending"
+// @Param limit query int false "Pagination limit"
+// @Param offset query int false "Pagination offset, cannot be combined with cursor"
+// @Param cursor query string false "Opaque cursor from meta.next_cursor"
+// @Param include_total query bool false "Include the total number of matching rows"
+// @Param as_of query string false "Read data as stored at this RFC 3339 timestamp"
+// @Success 200 {object} Response{data=[]SubscriptionResponse,meta=PageMeta} "OK"
+// @Header 200 {string} Link "RFC 8288 link to the next page"
+// @Header 200 {integer} X-Total-Count "Total number of matching rows, when include_total is set"
+// @Failure 400 {object} Response "Invalid ID or query parameters"
+// @Failure 404 {object} Response "User not found"
+// @Failure 422 {object} Response "Validation failed"
+// @Failure 500 {object} Response "Internal server error"
+// @Router /users/{id}/subscriptions [get]
+func (h *userHandler) ListUserSubscriptions(c *gin.Context) {
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req ListSubscriptionsRequest
+	if err := c.BindQuery(&req); err != nil {
+		slog.Debug("invalid query params for ListUserSubscriptions", "error", err)
+		JSONErrorMessage(c, http.StatusBadRequest, "invalid query parameters")
+		return
+	}
+
+	params, err := req.ToParams()
+	if err != nil {
+		slog.Debug("failed to parse ListSubscriptionsRequest", "error", err, "query", req)
+		JSONAppError(c, err)
+		return
+	}
+	params.UserID = &id
+
+	if _, err := h.userService.GetByID(c.Request.Context(), id); err != nil {
+		slog.Debug("failed to get user by id", "id", id, "error", err)
+		JSONAppError(c, err)
+		return
+	}
+
+	page, err := h.subscriptionService.ListSubscriptions(c.Request.Context(), params)
+	if err != nil {
+		slog.Debug("failed to list user subscriptions", "id", id, "error", err)
+		JSONAppError(c, err)
+		return
+	}
+
+	writeSubscriptionPage(c, page)
+}
+
+// GetUserSpend godoc
+// @Summary Get a user's spend
+// @Description Same as GET /subscriptions/sum, restricted to the user in the path
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param period_start query string true "Period start MM-YYYY"
+// @Param period_end query string true "Period end MM-YYYY"
+// @Param service_name query string false "Filter by Service name"
+// @Param as_of query string false "Read data as stored at this RFC 3339 timestamp"
+// @Success 200 {object} Response{data=SumOfSubscriptionPricesResponse} "OK"
+// @Failure 400 {object} Response "Invalid ID or query parameters"
+// @Failure 404 {object} Response "User not found"
+// @Failure 422 {object} Response "Validation failed"
+// @Failure 500 {object} Response "Internal server error"
+// @Router /users/{id}/spend [get]
+func (h *userHandler) GetUserSpend(c *gin.Context) {
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req SumOfSubscriptionPricesRequest
+	if err := c.BindQuery(&req); err != nil {
+		slog.Debug("invalid query params for GetUserSpend", "error", err)
+		JSONErrorMessage(c, http.StatusBadRequest, "invalid query parameters")
+		return
+	}
+
+	params, err := req.ToParams()
+	if err != nil {
+		slog.Debug("failed to parse SumOfSubscriptionPricesRequest", "error", err, "query", req)
+		JSONAppError(c, err)
+		return
+	}
+	params.UserID = &id
+
+	if _, err := h.userService.GetByID(c.Request.Context(), id); err != nil {
+		slog.Debug("failed to get user by id", "id", id, "error", err)
+		JSONAppError(c, err)
+		return
+	}
+
+	sum, err := h.subscriptionService.GetSumOfSubscriptionPrices(c.Request.Context(), params)
+	if err != nil {
+		slog.Debug("failed to calculate user spend", "id", id, "error", err)
+		JSONAppError(c, err)
+		return
+	}
+
+	JSONSuccess(c, http.StatusOK, ToSumOfSubscriptionPricesResponse(*sum))
+}
+
+// ListUserRenewals godoc
+// @Summary List a user's upcoming renewals
+// @Description Expand each subscription of the user into the days it is billed on between from and to, following its
+// @Description start date and the price, billing interval and anchor day in effect each month. Days up to the end of a
+// @Description trial, paused months and days after the subscription ends are left out. Renewals are ordered by date
+// @Description with a running total in currency.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param from query string false "First day YYYY-MM-DD, default today"
+// @Param to query string false "Last day YYYY-MM-DD, default 30 days after from"
+// @Param currency query string false "ISO 4217 currency of the totals; required when subscriptions use several currencies"
+// @Success 200 {object} Response{data=RenewalCalendarResponse} "OK"
+// @Failure 400 {object} Response "Invalid ID or query parameters"
+// @Failure 404 {object} Response "User not found"
+// @Failure 422 {object} Response "Validation failed"
+// @Failure 500 {object} Response "Internal server error"
+// @Router /users/{id}/renewals [get]
+func (h *userHandler) ListUserRenewals(c *gin.Context) {
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req RenewalsRequest
+	if err := c.BindQuery(&req); err != nil {
+		slog.Debug("invalid query params for ListUserRenewals", "error", err)
+		JSONErrorMessage(c, http.StatusBadRequest, "invalid query parameters")
+		return
+	}
+
+	params, err := req.ToParams(id)
+	if err != nil {
+		slog.Debug("failed to parse RenewalsRequest", "error", err, "query", req)
+		JSONAppError(c, err)
+		return
+	}
+
+	if _, err := h.userService.GetByID(c.Request.Context(), id); err != nil {
+		slog.Debug("failed to get user by id", "id", id, "error", err)
+		JSONAppError(c, err)
+		return
+	}
+
+	calendar, err := h.subscriptionService.ListRenewals(c.Request.Context(), params)
+	if err != nil {
+		slog.Debug("failed to list user renewals", "id", id, "error", err)
+		JSONAppError(c, err)
+		return
+	}
+
+	JSONSuccess(c, http.StatusOK, ToRenewalCalendarResponse(*calendar))
+}
+
+// RotateCalendarToken godoc
+// @Summary Issue a calendar feed token
+// @Description Issue a new secret token for the user's renewals calendar feed and return it with the feed URL to
+// @Description subscribe to. The token is only shown once, and any previous token stops working.
+// @Description Requires the admin token, held by operators and by the gateway acting for the signed-in user
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param id path string true "User ID"
+// @Success 201 {object} Response{data=CalendarTokenResponse} "Created"
+// @Failure 400 {object} Response "Invalid ID"
+// @Failure 401 {object} Response "Missing admin token"
+// @Failure 403 {object} Response "Invalid admin token"
+// @Failure 404 {object} Response "User not found"
+// @Failure 500 {object} Response "Internal server error"
+// @Router /admin/users/{id}/calendar-token [post]
+func (h *userHandler) RotateCalendarToken(c *gin.Context) {
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	token, err := h.userService.RotateCalendarToken(c.Request.Context(), id)
+	if err != nil {
+		slog.Debug("failed to rotate calendar token", "id", id, "error", err)
+		JSONAppError(c, err)
+		return
+	}
+
+	slog.Info("calendar token rotated", "id", id)
+	JSONSuccess(c, http.StatusCreated, ToCalendarTokenResponse(id, token))
+}
+
+// GetRenewalsFeed godoc
+// @Summary Get a user's renewals calendar
+// @Description RFC 5545 calendar with one recurring all-day event per trial, active or pending_cancel subscription of
+// @Description the user, repeating on its billing days from its start date, billing interval and anchor day, after
+// @Description any trial and until it ends. Each event has a reminder and a stable UID, so calendar apps update
+// @Description existing events. Access requires the token from POST /admin/users/{id}/calendar-token.
+// @Tags users
+// @Produce text/calendar
+// @Param id path string true "User ID"
+// @Param token query string true "Calendar token"
+// @Param reminder_days query int false "Days before a renewal to remind of it, 0 to 30, default 1"
+// @Success 200 {string} string "iCalendar data"
+// @Failure 400 {object} Response "Invalid ID"
+// @Failure 404 {object} Response "Unknown user or token"
+// @Failure 422 {object} Response "Validation failed"
+// @Failure 500 {object} Response "Internal server error"
+// @Router /users/{id}/renewals.ics [get]
+func (h *userHandler) GetRenewalsFeed(c *gin.Context) {
+	id, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req RenewalsFeedRequest
+	if err := c.BindQuery(&req); err != nil {
+		slog.Debug("invalid query params for GetRenewalsFeed", "error", err)
+		JSONErrorMessage(c, http.StatusBadRequest, "invalid query parameters")
+		return
+	}
+
+	if err := h.userService.CheckCalendarToken(c.Request.Context(), id, req.Token); err != nil {
+		slog.Debug("calendar token rejected", "id", id, "error", err)
+		JSONAppError(c, err)
+		return
+	}
+
+	trigger, err := req.ReminderTrigger()
+	if err != nil {
+		JSONAppError(c, err)
+		return
+	}
+
+	schedules, err := h.subscriptionService.ListRenewalSchedules(c.Request.Context(), id)
+	if err != nil {
+		slog.Debug("failed to list renewal schedules", "id", id, "error", err)
+		JSONAppError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", `inline; filename="renewals.ics"`)
+	c.Data(http.StatusOK, ical.ContentType, ToRenewalsCalendar(schedules, trigger, time.Now()).Marshal())
+}
+
+// userIDParam parses the :id path parameter, answering 400 when it is not a
+// UUID.
+func userIDParam(c *gin.Context) (uuid.UUID, bool) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		slog.Debug("invalid user id param", "param", idStr, "error", err)
+		JSONErrorMessage(c, http.StatusBadRequest, "invalid user id")
+		return uuid.Nil, false
+	}
+	return id, true
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/morphlinkk/subscriptions/internal/apperr"
+	"github.com/morphlinkk/subscriptions/internal/server/handler"
+)
+
+// RequireAdminToken only lets through requests carrying
+// "Authorization: Bearer <token>". An empty token disables the guarded routes.
+func RequireAdminToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		provided, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || provided == "" {
+			handler.JSONErrorMessage(c, http.StatusUnauthorized, "admin token required")
+			c.Abort()
+			return
+		}
+
+		if token == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			slog.Warn("rejected admin request", "path", c.FullPath(), "ip", c.ClientIP())
+			handler.JSONAppError(c, apperr.Forbidden("admin token is invalid"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/morphlinkk/subscriptions/internal/apperr"
+	"github.com/morphlinkk/subscriptions/internal/model"
+	"github.com/morphlinkk/subscriptions/internal/repository"
+	"github.com/morphlinkk/subscriptions/internal/server/handler"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marks responses replayed from an earlier
+	// request with the same key.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+	// pendingIdempotencyTTL is how long a claim stays pending before it
+	// expires. It outlasts any request, so a claim only expires while pending
+	// when its request never finished, e.g. because the process died.
+	pendingIdempotencyTTL = time.Minute
+)
+
+// Idempotency makes requests carrying an Idempotency-Key header safe to retry.
+// The first request with a key claims it with a pending record, runs
+// normally and then stores its response for ttl; later requests with the same
+// key and body get that response back without running again, while a
+// different body is rejected with 422. A retry arriving while the key is
+// still pending is answered with 409, including when the response could not
+// be stored, so a request never runs twice. 5xx responses release the key, so
+// a failed request can be retried with it, and a claim that was never
+// completed expires after pendingIdempotencyTTL.
+func Idempotency(repo repository.IdempotencyRepository, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			handler.JSONErrorMessage(c, http.StatusBadRequest, "Idempotency-Key must be at most 255 characters")
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			handler.JSONErrorMessage(c, http.StatusBadRequest, "invalid request body")
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		route := c.Request.Method + " " + c.FullPath()
+		hash := requestHash(c.Request.Method, c.Request.URL.Path, body)
+
+		stored, err := repo.Claim(c.Request.Context(), model.IdempotencyRecord{
+			Route:       route,
+			Key:         key,
+			RequestHash: hash,
+			Body:        []byte{},
+			ExpiresAt:   time.Now().Add(pendingIdempotencyTTL),
+		})
+		if err != nil {
+			handler.JSONAppError(c, err)
+			c.Abort()
+			return
+		}
+		if stored != nil {
+			switch {
+			case stored.RequestHash != hash:
+				handler.JSONAppError(c, apperr.InvalidField(IdempotencyKeyHeader, apperr.CodeInvalid,
+					"Idempotency-Key was already used for a different request"))
+			case stored.Pending():
+				handler.JSONAppError(c, apperr.Conflict("a request with this idempotency key is still in progress"))
+			default:
+				c.Header(IdempotentReplayedHeader, "true")
+				c.Data(stored.Status, stored.ContentType, stored.Body)
+			}
+			c.Abort()
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = rec
+		c.Next()
+
+		// The outcome is stored even when the client has gone away, since the
+		// request has run.
+		ctx := context.WithoutCancel(c.Request.Context())
+		if rec.Status() >= http.StatusInternalServerError {
+			if err := repo.Release(ctx, route, key); err != nil {
+				slog.Error("failed to release idempotency key", "route", route, "error", err)
+			}
+			return
+		}
+		err = repo.Complete(ctx, model.IdempotencyRecord{
+			Route:       route,
+			Key:         key,
+			RequestHash: hash,
+			Status:      rec.Status(),
+			ContentType: rec.Header().Get("Content-Type"),
+			Body:        rec.body.Bytes(),
+			ExpiresAt:   time.Now().Add(ttl),
+		})
+		if err != nil {
+			slog.Error("failed to store idempotent response", "route", route, "error", err)
+		}
+	}
+}
+
+// requestHash fingerprints a request. JSON bodies are hashed in canonical
+// form so that retries serializing the same object differently still match.
+func requestHash(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(canonicalJSON(body))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// canonicalJSON re-encodes body with sorted object keys and no insignificant
+// whitespace. Bodies that are not JSON are returned unchanged.
+func canonicalJSON(body []byte) []byte {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	var v any
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return body
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+// responseRecorder keeps a copy of the response body while writing it.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/morphlinkk/subscriptions/internal/reqctx"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+	// ActorHeader names who acts on behalf of the request, e.g. a support
+	// agent. It is trusted as sent, so it must be set by a gateway in front of
+	// the service.
+	ActorHeader = "X-Actor"
+
+	maxRequestIDLength = 128
+	maxActorLength     = 255
+)
+
+// RequestContext stores the request ID and actor in the request context. The
+// request ID comes from the X-Request-ID header or is generated, and is
+// echoed in the response.
+func RequestContext() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := strings.TrimSpace(c.GetHeader(RequestIDHeader))
+		if id == "" || len(id) > maxRequestIDLength {
+			id = uuid.NewString()
+		}
+		c.Header(RequestIDHeader, id)
+
+		ctx := reqctx.WithRequestID(c.Request.Context(), id)
+		if actor := strings.TrimSpace(c.GetHeader(ActorHeader)); actor != "" && len(actor) <= maxActorLength {
+			ctx = reqctx.WithActor(ctx, actor)
+		}
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
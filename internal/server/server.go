@@ -10,8 +10,10 @@ import (
 	_ "github.com/morphlinkk/subscriptions/cmd/api/docs"
 	"github.com/morphlinkk/subscriptions/internal/config"
 	"github.com/morphlinkk/subscriptions/internal/db"
+	"github.com/morphlinkk/subscriptions/internal/jobs"
 	"github.com/morphlinkk/subscriptions/internal/repository"
 	"github.com/morphlinkk/subscriptions/internal/server/handler"
+	"github.com/morphlinkk/subscriptions/internal/server/middleware"
 	"github.com/morphlinkk/subscriptions/internal/server/service"
 	swaggerfiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -24,47 +26,71 @@ type Server struct {
 
 type Repositories struct {
 	Subscription repository.SubscriptionRepository
+	Catalog      repository.CatalogRepository
+	User         repository.UserRepository
+	Idempotency  repository.IdempotencyRepository
+	Audit        repository.AuditRepository
+	ExchangeRate repository.ExchangeRateRepository
 }
 
 type Services struct {
 	Subscription service.SubscriptionService
+	Catalog      service.CatalogService
+	User         service.UserService
+	Audit        service.AuditService
 }
 
 type Handlers struct {
 	Subscription handler.SubscriptionHandler
+	Catalog      handler.CatalogHandler
+	User         handler.UserHandler
+	Audit        handler.AuditHandler
 }
 
 func initRepositories(store *db.Store) *Repositories {
 	return &Repositories{
 		Subscription: repository.NewSubscriptionRepository(store),
+		Catalog:      repository.NewCatalogRepository(store),
+		User:         repository.NewUserRepository(store),
+		Idempotency:  repository.NewIdempotencyRepository(store),
+		Audit:        repository.NewAuditRepository(store),
+		ExchangeRate: repository.NewExchangeRateRepository(store),
 	}
 }
 
 func initServices(repositories *Repositories) *Services {
 	return &Services{
-		Subscription: service.NewSubscriptionService(repositories.Subscription),
+		Subscription: service.NewSubscriptionService(repositories.Subscription, repositories.Catalog, repositories.User, repositories.ExchangeRate),
+		Catalog:      service.NewCatalogService(repositories.Catalog),
+		User:         service.NewUserService(repositories.User),
+		Audit:        service.NewAuditService(repositories.Audit),
 	}
 }
 
 func initHandlers(services *Services) *Handlers {
 	return &Handlers{
 		Subscription: handler.NewSubscriptionHandler(services.Subscription),
+		Catalog:      handler.NewCatalogHandler(services.Catalog),
+		User:         handler.NewUserHandler(services.User, services.Subscription),
+		Audit:        handler.NewAuditHandler(services.Audit),
 	}
 }
 
-func NewServer(conf *config.Config) (*gin.Engine, error) {
+// NewServer returns the router of the API and a function that runs its
+// background jobs until the context it is given is done.
+func NewServer(conf *config.Config) (*gin.Engine, func(context.Context), error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	store, err := db.NewStore(ctx, *conf)
 
 	slog.Info("Creating database connection pool")
 	if err != nil {
-		return nil, fmt.Errorf("failed to create database store: %w", err)
+		return nil, nil, fmt.Errorf("failed to create database store: %w", err)
 	}
 
 	slog.Debug("Pinging database")
 	if err := store.Ping(ctx); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+		return nil, nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 	gin.SetMode(conf.EnvMode)
 
@@ -74,17 +100,68 @@ func NewServer(conf *config.Config) (*gin.Engine, error) {
 
 	r := gin.Default()
 	r.SetTrustedProxies([]string{"localhost"})
+	r.Use(middleware.RequestContext())
 
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
 
 	subs := r.Group("/subscriptions")
 	{
-		subs.POST("/", handlers.Subscription.AddSubscription)
+		subs.POST("/", middleware.Idempotency(repositories.Idempotency, conf.IdempotencyTTL), handlers.Subscription.AddSubscription)
 		subs.GET("/:id", handlers.Subscription.GetSubscriptionByID)
 		subs.PATCH("/:id", handlers.Subscription.UpdateSubscription)
+		subs.DELETE("/:id", handlers.Subscription.DeleteSubscription)
+		subs.POST("/:id/restore", handlers.Subscription.RestoreSubscription)
+		subs.POST("/:id/transfer", handlers.Subscription.TransferSubscription)
+		subs.GET("/:id/history", middleware.RequireAdminToken(conf.AdminToken), handlers.Audit.GetSubscriptionHistory)
+		subs.GET("/:id/prices", handlers.Subscription.ListSubscriptionPrices)
+		subs.POST("/:id/pause", handlers.Subscription.PauseSubscription)
+		subs.POST("/:id/resume", handlers.Subscription.ResumeSubscription)
+		subs.POST("/:id/cancel", handlers.Subscription.CancelSubscription)
+		subs.POST("/:id/reactivate", handlers.Subscription.ReactivateSubscription)
+		subs.GET("/:id/status-changes", handlers.Subscription.ListStatusChanges)
 		subs.GET("/", handlers.Subscription.ListSubscriptions)
 		subs.GET("/sum", handlers.Subscription.GetSumOfSubscriptionPrices)
+		subs.GET("/spend/timeseries", handlers.Subscription.GetSpendTimeSeries)
 	}
 
-	return r, nil
+	catalog := r.Group("/services")
+	{
+		catalog.POST("/", handlers.Catalog.AddService)
+		catalog.GET("/", handlers.Catalog.ListServices)
+		catalog.GET("/suggest", handlers.Subscription.SuggestServices)
+		catalog.GET("/:id", handlers.Catalog.GetServiceByID)
+		catalog.PATCH("/:id", handlers.Catalog.UpdateService)
+		catalog.DELETE("/:id", handlers.Catalog.DeleteService)
+		catalog.POST("/:name/price-change", handlers.Subscription.ChangeServicePrice)
+	}
+
+	users := r.Group("/users")
+	{
+		users.POST("/", handlers.User.AddUser)
+		users.GET("/", handlers.User.ListUsers)
+		users.GET("/:id", handlers.User.GetUserByID)
+		users.PATCH("/:id", handlers.User.UpdateUser)
+		users.DELETE("/:id", handlers.User.DeleteUser)
+		users.GET("/:id/subscriptions", handlers.User.ListUserSubscriptions)
+		users.GET("/:id/spend", handlers.User.GetUserSpend)
+		users.GET("/:id/renewals", handlers.User.ListUserRenewals)
+		users.GET("/:id/renewals.ics", handlers.User.GetRenewalsFeed)
+	}
+
+	admin := r.Group("/admin", middleware.RequireAdminToken(conf.AdminToken))
+	{
+		admin.DELETE("/subscriptions/:id", handlers.Subscription.PurgeSubscription)
+		admin.POST("/users/:id/calendar-token", handlers.User.RotateCalendarToken)
+	}
+
+	audit := r.Group("/audit", middleware.RequireAdminToken(conf.AdminToken))
+	{
+		audit.GET("/", handlers.Audit.ListAudit)
+	}
+
+	runJobs := func(ctx context.Context) {
+		go jobs.Every(ctx, "prices", conf.PriceJobInterval, jobs.Prices(services.Subscription))
+		jobs.Every(ctx, "trials", conf.TrialJobInterval, jobs.Trials(services.Subscription, conf.TrialReminderDays))
+	}
+	return r, runJobs, nil
 }
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+
+	"github.com/morphlinkk/subscriptions/internal/apperr"
+	"github.com/morphlinkk/subscriptions/internal/model"
+	"github.com/morphlinkk/subscriptions/internal/repository"
+)
+
+type AuditService interface {
+	ListAudit(ctx context.Context, params model.ListAuditParams) ([]model.SubscriptionAuditEntry, error)
+	GetSubscriptionHistory(ctx context.Context, id int64, limit, offset int) ([]model.SubscriptionAuditEntry, error)
+}
+
+type auditService struct {
+	repo repository.AuditRepository
+}
+
+func NewAuditService(repo repository.AuditRepository) AuditService {
+	return &auditService{
+		repo,
+	}
+}
+
+func (s *auditService) ListAudit(ctx context.Context, params model.ListAuditParams) ([]model.SubscriptionAuditEntry, error) {
+	params.Limit, params.Offset = normalizePage(params.Limit, params.Offset)
+
+	var v apperr.Validator
+	if params.Operation != nil {
+		v.Check(model.IsAuditOperation(string(*params.Operation)), "operation", apperr.CodeInvalid, "unknown operation "+string(*params.Operation))
+	}
+	if params.SubscriptionID != nil {
+		v.Check(*params.SubscriptionID > 0, "subscription_id", apperr.CodeOutOfRange, "invalid subscription id")
+	}
+	if params.From != nil && params.To != nil {
+		v.Check(params.From.Before(*params.To), "to", apperr.CodeOutOfRange, "to must be after from")
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	return s.repo.ListAudit(ctx, &params)
+}
+
+// GetSubscriptionHistory returns the audit entries of one subscription, oldest
+// first. Purged subscriptions keep their history.
+func (s *auditService) GetSubscriptionHistory(ctx context.Context, id int64, limit, offset int) ([]model.SubscriptionAuditEntry, error) {
+	if err := validateID(id); err != nil {
+		return nil, err
+	}
+	params := model.ListAuditParams{SubscriptionID: &id}
+	params.Limit, params.Offset = normalizePage(limit, offset)
+	return s.repo.ListAudit(ctx, &params)
+}
+
+// normalizePage applies the default and maximum list limit and clamps a
+// negative offset to zero.
+func normalizePage(limit, offset int) (int, int) {
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	if limit > maxListLimit {
+		limit = maxListLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+
+	"github.com/google/uuid"
+	"github.com/morphlinkk/subscriptions/internal/apperr"
+)
+
+// calendarTokenBytes is the number of random bytes in a calendar token.
+const calendarTokenBytes = 32
+
+// RotateCalendarToken issues a new secret token for the renewals calendar of
+// user id and returns it. Only its hash is stored, so the previous token stops
+// working and the new one cannot be read back.
+func (s *userService) RotateCalendarToken(ctx context.Context, id uuid.UUID) (string, error) {
+	b := make([]byte, calendarTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	hash := sha256.Sum256([]byte(token))
+	if err := s.repo.SetCalendarToken(ctx, id, hash[:]); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// CheckCalendarToken fails with not found unless token is the calendar token
+// of user id, so that the feed does not tell which users exist.
+func (s *userService) CheckCalendarToken(ctx context.Context, id uuid.UUID, token string) error {
+	stored, err := s.repo.GetCalendarToken(ctx, id)
+	if err != nil && apperr.KindOf(err) != apperr.KindNotFound {
+		return err
+	}
+
+	hash := sha256.Sum256([]byte(token))
+	if token == "" || stored == nil || subtle.ConstantTimeCompare(stored, hash[:]) != 1 {
+		return apperr.NotFound("calendar not found")
+	}
+	return nil
+}
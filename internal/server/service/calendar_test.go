@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/morphlinkk/subscriptions/internal/apperr"
+	"github.com/morphlinkk/subscriptions/internal/repository"
+)
+
+// fakeUserRepo stores calendar token hashes in memory.
+type fakeUserRepo struct {
+	repository.UserRepository
+	tokens map[uuid.UUID][]byte
+}
+
+func (r *fakeUserRepo) SetCalendarToken(ctx context.Context, id uuid.UUID, hash []byte) error {
+	r.tokens[id] = hash
+	return nil
+}
+
+func (r *fakeUserRepo) GetCalendarToken(ctx context.Context, id uuid.UUID) ([]byte, error) {
+	return r.tokens[id], nil
+}
+
+func TestCalendarToken(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeUserRepo{tokens: map[uuid.UUID][]byte{}}
+	s := NewUserService(repo)
+	user, other := uuid.New(), uuid.New()
+
+	first, err := s.RotateCalendarToken(ctx, user)
+	if err != nil {
+		t.Fatalf("RotateCalendarToken() error = %v", err)
+	}
+	second, err := s.RotateCalendarToken(ctx, user)
+	if err != nil {
+		t.Fatalf("RotateCalendarToken() error = %v", err)
+	}
+	if first == second {
+		t.Fatal("RotateCalendarToken() returned the same token twice")
+	}
+	if string(repo.tokens[user]) == second {
+		t.Error("the token is stored in plain text")
+	}
+
+	tests := []struct {
+		name    string
+		user    uuid.UUID
+		token   string
+		wantErr bool
+	}{
+		{"current token", user, second, false},
+		{"rotated token", user, first, true},
+		{"empty token", user, "", true},
+		{"token of another user", other, second, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.CheckCalendarToken(ctx, tt.user, tt.token)
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("CheckCalendarToken() error = %v", err)
+				}
+				return
+			}
+			if kind := apperr.KindOf(err); err == nil || kind != apperr.KindNotFound {
+				t.Errorf("CheckCalendarToken() error = %v, want not found", err)
+			}
+		})
+	}
+}
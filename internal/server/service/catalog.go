@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/morphlinkk/subscriptions/internal/apperr"
+	"github.com/morphlinkk/subscriptions/internal/model"
+	"github.com/morphlinkk/subscriptions/internal/repository"
+)
+
+type CatalogService interface {
+	GetByID(ctx context.Context, id int64) (*model.Service, error)
+	AddService(ctx context.Context, params model.AddServiceParams) (*model.Service, error)
+	UpdateService(ctx context.Context, id int64, params model.UpdateServiceParams) (*model.Service, error)
+	DeleteService(ctx context.Context, id int64) error
+	ListServices(ctx context.Context, params model.ListServicesParams) ([]model.Service, error)
+}
+
+type catalogService struct {
+	repo repository.CatalogRepository
+}
+
+func NewCatalogService(repo repository.CatalogRepository) CatalogService {
+	return &catalogService{
+		repo,
+	}
+}
+
+func (s *catalogService) GetByID(ctx context.Context, id int64) (*model.Service, error) {
+	if err := validateServiceID(id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetById(ctx, id)
+}
+
+func (s *catalogService) AddService(ctx context.Context, params model.AddServiceParams) (*model.Service, error) {
+	params.Name = strings.TrimSpace(params.Name)
+	params.Aliases = normalizeAliases(params.Name, params.Aliases)
+	params.Currency = normalizeCurrency(params.Currency)
+
+	var v apperr.Validator
+	v.Check(params.Name != "", "name", apperr.CodeRequired, "name is required")
+	validateServiceDetails(&v, params.Website, params.DefaultPrice)
+	validateCurrency(&v, "currency", params.Currency)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkNamesFree(ctx, 0, append([]string{params.Name}, params.Aliases...)); err != nil {
+		return nil, err
+	}
+	return s.repo.AddService(ctx, &params)
+}
+
+func (s *catalogService) UpdateService(ctx context.Context, id int64, params model.UpdateServiceParams) (*model.Service, error) {
+	if err := validateServiceID(id); err != nil {
+		return nil, err
+	}
+
+	current, err := s.repo.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var v apperr.Validator
+	if params.Name != nil {
+		name := strings.TrimSpace(*params.Name)
+		params.Name = &name
+		v.Check(name != "", "name", apperr.CodeRequired, "name is provided but empty")
+	}
+	validateServiceDetails(&v, params.Website.Value, params.DefaultPrice.Value)
+	if params.Currency != nil {
+		currency := normalizeCurrency(*params.Currency)
+		params.Currency = &currency
+		validateCurrency(&v, "currency", currency)
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	name := current.Name
+	if params.Name != nil {
+		name = *params.Name
+	}
+	aliases := current.Aliases
+	if params.Aliases != nil {
+		params.Aliases = normalizeAliases(name, params.Aliases)
+		aliases = params.Aliases
+	}
+
+	if err := s.checkNamesFree(ctx, id, append([]string{name}, aliases...)); err != nil {
+		return nil, err
+	}
+	return s.repo.UpdateService(ctx, id, &params)
+}
+
+func (s *catalogService) DeleteService(ctx context.Context, id int64) error {
+	if err := validateServiceID(id); err != nil {
+		return err
+	}
+	return s.repo.DeleteService(ctx, id)
+}
+
+func (s *catalogService) ListServices(ctx context.Context, params model.ListServicesParams) ([]model.Service, error) {
+	if params.Limit <= 0 {
+		params.Limit = defaultListLimit
+	}
+	if params.Limit > maxListLimit {
+		params.Limit = maxListLimit
+	}
+	if params.Offset < 0 {
+		params.Offset = 0
+	}
+	return s.repo.ListServices(ctx, &params)
+}
+
+// checkNamesFree fails with a conflict when one of names is the name or an
+// alias of a service other than id.
+func (s *catalogService) checkNamesFree(ctx context.Context, id int64, names []string) error {
+	for _, name := range names {
+		existing, err := resolveService(ctx, s.repo, name)
+		if err != nil {
+			return err
+		}
+		if existing != nil && existing.ID != id {
+			return apperr.Conflict("%q is already used by service %q", name, existing.Name)
+		}
+	}
+	return nil
+}
+
+// resolveService returns the catalog entry whose name or alias matches name,
+// or nil when the catalog has none.
+func resolveService(ctx context.Context, repo repository.CatalogRepository, name string) (*model.Service, error) {
+	svc, err := repo.FindByName(ctx, name)
+	if apperr.KindOf(err) == apperr.KindNotFound {
+		return nil, nil
+	}
+	return svc, err
+}
+
+// normalizeAliases trims aliases and drops empty ones, duplicates and those
+// equal to name, all compared case-insensitively.
+func normalizeAliases(name string, aliases []string) []string {
+	seen := map[string]bool{strings.ToLower(name): true}
+	out := make([]string, 0, len(aliases))
+	for _, a := range aliases {
+		a = strings.TrimSpace(a)
+		key := strings.ToLower(a)
+		if a == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, a)
+	}
+	return out
+}
+
+func validateServiceDetails(v *apperr.Validator, website *string, defaultPrice *int) {
+	if website != nil && *website != "" {
+		u, err := url.Parse(*website)
+		v.Check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+			"website", apperr.CodeInvalidFormat, "website must be an http(s) URL")
+	}
+	if defaultPrice != nil {
+		v.Check(*defaultPrice > 0, "default_price", apperr.CodeOutOfRange, "default_price must be positive")
+	}
+}
+
+func validateServiceID(id int64) error {
+	if id <= 0 {
+		return apperr.InvalidField("id", apperr.CodeOutOfRange, "invalid service id")
+	}
+	return nil
+}
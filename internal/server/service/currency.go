@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/morphlinkk/subscriptions/internal/apperr"
+	"github.com/morphlinkk/subscriptions/internal/model"
+	"github.com/morphlinkk/subscriptions/internal/repository"
+)
+
+// normalizeCurrency returns code as an upper-case ISO 4217 code, or
+// model.DefaultCurrency when it is empty.
+func normalizeCurrency(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return model.DefaultCurrency
+	}
+	return code
+}
+
+func validateCurrency(v *apperr.Validator, field, code string) {
+	v.Check(model.IsCurrency(code), field, apperr.CodeInvalid, field+" must be a supported ISO 4217 currency code")
+}
+
+type currencyPair struct {
+	base, quote string
+}
+
+// currencyConverter converts amounts in minor units to one currency with the
+// exchange rates of each month, and remembers the rates it used.
+type currencyConverter struct {
+	target string
+	rates  map[time.Time]map[currencyPair]model.AppliedRate
+	used   map[time.Time]map[currencyPair]model.AppliedRate
+}
+
+// newCurrencyConverter returns a converter of the amounts of subs to target,
+// with the rates of the months from first to last. A nil target stands for
+// the one currency subs share; it is required when they use several.
+func newCurrencyConverter(ctx context.Context, repo repository.ExchangeRateRepository, subs []model.Subscription, target *string, first, last time.Time) (*currencyConverter, error) {
+	seen := map[string]bool{}
+	var currencies []string
+	for _, sub := range subs {
+		if !seen[sub.Currency] {
+			seen[sub.Currency] = true
+			currencies = append(currencies, sub.Currency)
+		}
+	}
+
+	c := &currencyConverter{
+		rates: map[time.Time]map[currencyPair]model.AppliedRate{},
+		used:  map[time.Time]map[currencyPair]model.AppliedRate{},
+	}
+	switch {
+	case target != nil:
+		c.target = *target
+	case len(currencies) > 1:
+		return nil, apperr.InvalidField("currency", apperr.CodeRequired,
+			"currency is required when subscriptions use several currencies: "+strings.Join(currencies, ", "))
+	case len(currencies) == 1:
+		c.target = currencies[0]
+	default:
+		c.target = model.DefaultCurrency
+	}
+
+	if len(currencies) == 0 || (len(currencies) == 1 && currencies[0] == c.target) {
+		return c, nil
+	}
+	if !seen[c.target] {
+		currencies = append(currencies, c.target)
+	}
+
+	rates, err := repo.ListMonthlyRates(ctx, first, last, currencies)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rates {
+		if c.rates[r.Month] == nil {
+			c.rates[r.Month] = map[currencyPair]model.AppliedRate{}
+		}
+		c.rates[r.Month][currencyPair{r.Base, r.Quote}] = r
+	}
+	return c, nil
+}
+
+// convert returns amount, in minor units of from, in minor units of the
+// target currency at the rates of month.
+func (c *currencyConverter) convert(amount float64, from string, month time.Time) (float64, error) {
+	if from == c.target {
+		return amount, nil
+	}
+
+	factor, used, ok := c.factor(month, from, c.target)
+	if !ok {
+		for _, pivot := range c.pivots(month) {
+			f1, u1, ok1 := c.factor(month, from, pivot)
+			f2, u2, ok2 := c.factor(month, pivot, c.target)
+			if ok1 && ok2 {
+				factor, used, ok = f1*f2, append(u1, u2...), true
+				break
+			}
+		}
+	}
+	if !ok {
+		return 0, apperr.InvalidField("currency", apperr.CodeUnknown,
+			fmt.Sprintf("no exchange rate from %s to %s for %s", from, c.target, month.Format("01-2006")))
+	}
+
+	if c.used[month] == nil {
+		c.used[month] = map[currencyPair]model.AppliedRate{}
+	}
+	for _, r := range used {
+		c.used[month][currencyPair{r.Base, r.Quote}] = r
+	}
+	return amount / model.MinorUnits(from) * factor * model.MinorUnits(c.target), nil
+}
+
+// factor returns how many units of to one unit of from is worth in month,
+// using a direct or an inverted rate.
+func (c *currencyConverter) factor(month time.Time, from, to string) (float64, []model.AppliedRate, bool) {
+	if r, ok := c.rates[month][currencyPair{from, to}]; ok {
+		return r.Rate, []model.AppliedRate{r}, true
+	}
+	if r, ok := c.rates[month][currencyPair{to, from}]; ok {
+		return 1 / r.Rate, []model.AppliedRate{r}, true
+	}
+	return 0, nil, false
+}
+
+// pivots returns the currencies that rates of month can be chained through,
+// in a stable order.
+func (c *currencyConverter) pivots(month time.Time) []string {
+	seen := map[string]bool{}
+	var pivots []string
+	for pair := range c.rates[month] {
+		for _, code := range []string{pair.base, pair.quote} {
+			if !seen[code] {
+				seen[code] = true
+				pivots = append(pivots, code)
+			}
+		}
+	}
+	sort.Strings(pivots)
+	return pivots
+}
+
+// ratesFor returns the rates used for month, ordered by currency pair.
+func (c *currencyConverter) ratesFor(month time.Time) []model.ExchangeRate {
+	var rates []model.ExchangeRate
+	for _, r := range c.used[month] {
+		rates = append(rates, r.ExchangeRate)
+	}
+	sort.Slice(rates, func(i, j int) bool {
+		if rates[i].Base != rates[j].Base {
+			return rates[i].Base < rates[j].Base
+		}
+		return rates[i].Quote < rates[j].Quote
+	})
+	return rates
+}
+
+// applied returns every rate used, ordered by month and currency pair.
+func (c *currencyConverter) applied() []model.AppliedRate {
+	months := make([]time.Time, 0, len(c.used))
+	for m := range c.used {
+		months = append(months, m)
+	}
+	sort.Slice(months, func(i, j int) bool { return months[i].Before(months[j]) })
+
+	var rates []model.AppliedRate
+	for _, m := range months {
+		for _, r := range c.ratesFor(m) {
+			rates = append(rates, model.AppliedRate{Month: m, ExchangeRate: r})
+		}
+	}
+	return rates
+}
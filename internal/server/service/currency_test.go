@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/morphlinkk/subscriptions/internal/model"
+	"github.com/morphlinkk/subscriptions/internal/repository"
+)
+
+// newConverter returns a converter to target with rates, each for its month.
+func newConverter(target string, rates ...model.AppliedRate) *currencyConverter {
+	c := &currencyConverter{
+		target: target,
+		rates:  map[time.Time]map[currencyPair]model.AppliedRate{},
+		used:   map[time.Time]map[currencyPair]model.AppliedRate{},
+	}
+	for _, r := range rates {
+		if c.rates[r.Month] == nil {
+			c.rates[r.Month] = map[currencyPair]model.AppliedRate{}
+		}
+		c.rates[r.Month][currencyPair{r.Base, r.Quote}] = r
+	}
+	return c
+}
+
+func rate(month time.Time, base, quote string, r float64) model.AppliedRate {
+	return model.AppliedRate{Month: month, ExchangeRate: model.ExchangeRate{Date: month, Base: base, Quote: quote, Rate: r}}
+}
+
+func TestCurrencyConverterConvert(t *testing.T) {
+	month := date(2026, time.October, 1)
+	usdRub := rate(month, "USD", "RUB", 80)
+	eurRub := rate(month, "EUR", "RUB", 100)
+	usdJpy := rate(month, "USD", "JPY", 150)
+	usdKwd := rate(month, "USD", "KWD", 0.3)
+
+	tests := []struct {
+		name   string
+		target string
+		rates  []model.AppliedRate
+		amount float64
+		from   string
+		want   float64
+		used   []model.AppliedRate
+	}{
+		{
+			name:   "same currency",
+			target: "RUB",
+			amount: 79900,
+			from:   "RUB",
+			want:   79900,
+		},
+		{
+			name:   "direct rate",
+			target: "RUB",
+			rates:  []model.AppliedRate{usdRub},
+			amount: 1000,
+			from:   "USD",
+			want:   80000,
+			used:   []model.AppliedRate{usdRub},
+		},
+		{
+			name:   "inverted rate",
+			target: "USD",
+			rates:  []model.AppliedRate{usdRub},
+			amount: 80000,
+			from:   "RUB",
+			want:   1000,
+			used:   []model.AppliedRate{usdRub},
+		},
+		{
+			name:   "pivot through a shared currency",
+			target: "USD",
+			rates:  []model.AppliedRate{usdRub, eurRub},
+			amount: 800,
+			from:   "EUR",
+			want:   1000,
+			used:   []model.AppliedRate{eurRub, usdRub},
+		},
+		{
+			name:   "pivot with both rates inverted",
+			target: "JPY",
+			rates:  []model.AppliedRate{usdRub, usdJpy},
+			amount: 8000,
+			from:   "RUB",
+			want:   150,
+			used:   []model.AppliedRate{usdJpy, usdRub},
+		},
+		{
+			name:   "to a currency without minor units",
+			target: "JPY",
+			rates:  []model.AppliedRate{usdJpy},
+			amount: 1000,
+			from:   "USD",
+			want:   1500,
+			used:   []model.AppliedRate{usdJpy},
+		},
+		{
+			name:   "to a currency with three decimals",
+			target: "KWD",
+			rates:  []model.AppliedRate{usdKwd},
+			amount: 1000,
+			from:   "USD",
+			want:   3000,
+			used:   []model.AppliedRate{usdKwd},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newConverter(tt.target, tt.rates...)
+			got, err := c.convert(tt.amount, tt.from, month)
+			if err != nil {
+				t.Fatalf("convert() error = %v", err)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("convert() = %v, want %v", got, tt.want)
+			}
+			if applied := c.applied(); !reflect.DeepEqual(applied, tt.used) {
+				t.Errorf("applied() = %v, want %v", applied, tt.used)
+			}
+		})
+	}
+}
+
+func TestCurrencyConverterConvertMissingRate(t *testing.T) {
+	month := date(2026, time.October, 1)
+
+	tests := []struct {
+		name  string
+		from  string
+		month time.Time
+	}{
+		{"no rate of the currency", "EUR", month},
+		{"no rates in the month", "USD", date(2026, time.November, 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newConverter("RUB", rate(month, "USD", "RUB", 80))
+			if _, err := c.convert(1000, tt.from, tt.month); err == nil {
+				t.Error("convert() error = nil, want an error")
+			}
+			if applied := c.applied(); len(applied) != 0 {
+				t.Errorf("applied() = %v, want none", applied)
+			}
+		})
+	}
+}
+
+// fakeRateRepo serves the same rates for every month and records the
+// currencies it was asked for.
+type fakeRateRepo struct {
+	repository.ExchangeRateRepository
+	rates      []model.ExchangeRate
+	currencies []string
+}
+
+func (r *fakeRateRepo) ListMonthlyRates(ctx context.Context, first, last time.Time, currencies []string) ([]model.AppliedRate, error) {
+	r.currencies = currencies
+	var applied []model.AppliedRate
+	for m := first; !m.After(last); m = m.AddDate(0, 1, 0) {
+		for _, rate := range r.rates {
+			applied = append(applied, model.AppliedRate{Month: m, ExchangeRate: rate})
+		}
+	}
+	return applied, nil
+}
+
+func TestNewCurrencyConverter(t *testing.T) {
+	rub := model.Subscription{Currency: "RUB"}
+	usd := model.Subscription{Currency: "USD"}
+
+	tests := []struct {
+		name           string
+		subs           []model.Subscription
+		target         *string
+		wantTarget     string
+		wantCurrencies []string
+		wantErr        bool
+	}{
+		{name: "no subscriptions", wantTarget: model.DefaultCurrency},
+		{name: "one currency", subs: []model.Subscription{usd, usd}, wantTarget: "USD"},
+		{name: "requested currency of the subscriptions", subs: []model.Subscription{usd}, target: ptr("USD"), wantTarget: "USD"},
+		{
+			name:           "requested currency",
+			subs:           []model.Subscription{usd},
+			target:         ptr("EUR"),
+			wantTarget:     "EUR",
+			wantCurrencies: []string{"USD", "EUR"},
+		},
+		{
+			name:           "several currencies",
+			subs:           []model.Subscription{rub, usd, rub},
+			target:         ptr("RUB"),
+			wantTarget:     "RUB",
+			wantCurrencies: []string{"RUB", "USD"},
+		},
+		{name: "several currencies without a target", subs: []model.Subscription{rub, usd}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRateRepo{}
+			month := date(2026, time.October, 1)
+			c, err := newCurrencyConverter(context.Background(), repo, tt.subs, tt.target, month, month)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("newCurrencyConverter() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newCurrencyConverter() error = %v", err)
+			}
+			if c.target != tt.wantTarget {
+				t.Errorf("target = %s, want %s", c.target, tt.wantTarget)
+			}
+			if !reflect.DeepEqual(repo.currencies, tt.wantCurrencies) {
+				t.Errorf("rates asked for %v, want %v", repo.currencies, tt.wantCurrencies)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/morphlinkk/subscriptions/internal/apperr"
+	"github.com/morphlinkk/subscriptions/internal/model"
+)
+
+// maxRenewalDays bounds the renewals window.
+const maxRenewalDays = 366
+
+// ListRenewals expands the subscriptions of params.UserID into the days they
+// are billed on between params.From and params.To, ordered by day, with a
+// running total converted to the currency of the calendar.
+func (s *subscriptionService) ListRenewals(ctx context.Context, params model.RenewalsParams) (*model.RenewalCalendar, error) {
+	from := today()
+	if params.From != nil {
+		from = *params.From
+	}
+	to := from.AddDate(0, 0, model.DefaultRenewalDays)
+	if params.To != nil {
+		to = *params.To
+	}
+
+	var v apperr.Validator
+	v.Check(!to.Before(from), "to", apperr.CodeOutOfRange, "to must not be before from")
+	v.Check(!to.After(from.AddDate(0, 0, maxRenewalDays)),
+		"to", apperr.CodeOutOfRange, fmt.Sprintf("the window must not be longer than %d days", maxRenewalDays))
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	currency, err := targetCurrency(params.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	first, last := monthStart(from), monthStart(to)
+	subs, err := s.repo.ListSubscriptionsInPeriod(ctx, &model.SumOfSubscriptionPricesParams{
+		UserID:      &params.UserID,
+		PeriodStart: &first,
+		PeriodEnd:   &last,
+	})
+	if err != nil {
+		return nil, err
+	}
+	prices, err := s.pricesOf(ctx, subs, nil)
+	if err != nil {
+		return nil, err
+	}
+	pauses, err := s.pausesOf(ctx, subs, nil)
+	if err != nil {
+		return nil, err
+	}
+	conv, err := newCurrencyConverter(ctx, s.rates, subs, currency, first, last)
+	if err != nil {
+		return nil, err
+	}
+
+	calendar := &model.RenewalCalendar{
+		From:     from,
+		To:       to,
+		Currency: conv.target,
+		Renewals: []model.Renewal{},
+	}
+	for _, sub := range subs {
+		calendar.Renewals = append(calendar.Renewals, renewalsOf(sub, prices[sub.ID], pauses[sub.ID], from, to)...)
+	}
+	sort.SliceStable(calendar.Renewals, func(i, j int) bool {
+		a, b := calendar.Renewals[i], calendar.Renewals[j]
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		return a.SubscriptionID < b.SubscriptionID
+	})
+
+	var total float64
+	for i := range calendar.Renewals {
+		r := &calendar.Renewals[i]
+		amount, err := conv.convert(float64(r.Amount), r.Currency, monthStart(r.Date))
+		if err != nil {
+			return nil, err
+		}
+		total += amount
+		r.RunningTotal = roundAmount(total)
+	}
+	calendar.Total = roundAmount(total)
+	calendar.Rates = conv.applied()
+	return calendar, nil
+}
+
+// renewalsOf returns the days in [from, to] sub is billed on after its trial,
+// each at the price and billing interval of its month from the price history.
+// Days follow that interval and anchor day from the start. Days in a paused
+// month, after the last billed day or charged nothing are left out.
+func renewalsOf(sub model.Subscription, prices []model.SubscriptionPrice, pauses []model.SubscriptionPause, from, to time.Time) []model.Renewal {
+	after := from.AddDate(0, 0, -1)
+	if sub.TrialEnd != nil && sub.TrialEnd.After(after) {
+		after = *sub.TrialEnd
+	}
+	lastDay := sub.LastBilledDay()
+
+	var renewals []model.Renewal
+	for month := monthStart(after); !month.After(to); month = month.AddDate(0, 1, 0) {
+		amount, billing := termsAt(sub, prices, month)
+		if pausedAt(pauses, month) || amount == 0 {
+			continue
+		}
+		next := month.AddDate(0, 1, 0)
+		on := month.AddDate(0, 0, -1)
+		if after.After(on) {
+			on = after
+		}
+		for day := billing.NextBilling(sub.StartDate, on); day.Before(next) && !day.After(to); day = billing.NextBilling(sub.StartDate, day) {
+			if lastDay != nil && day.After(*lastDay) {
+				return renewals
+			}
+			renewals = append(renewals, model.Renewal{
+				Date:           day,
+				SubscriptionID: sub.ID,
+				Service:        sub.Service,
+				Amount:         amount,
+				Currency:       sub.Currency,
+				Billing:        billing,
+			})
+		}
+	}
+	return renewals
+}
+
+// ListRenewalSchedules returns how each subscription of userID that is still
+// to renew recurs, starting after its trial. Paused, canceled and expired
+// subscriptions are left out.
+func (s *subscriptionService) ListRenewalSchedules(ctx context.Context, userID uuid.UUID) ([]model.RenewalSchedule, error) {
+	first := currentMonth()
+	last := time.Date(9999, time.December, 1, 0, 0, 0, 0, time.UTC)
+	subs, err := s.repo.ListSubscriptionsInPeriod(ctx, &model.SumOfSubscriptionPricesParams{
+		UserID:      &userID,
+		PeriodStart: &first,
+		PeriodEnd:   &last,
+	})
+	if err != nil {
+		return nil, err
+	}
+	pauses, err := s.pausesOf(ctx, subs, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	schedules := []model.RenewalSchedule{}
+	for _, sub := range subs {
+		switch sub.Status {
+		case model.StatusTrial, model.StatusActive, model.StatusPendingCancel:
+		default:
+			continue
+		}
+
+		after := sub.StartDate.AddDate(0, 0, -1)
+		if sub.TrialEnd != nil && sub.TrialEnd.After(after) {
+			after = *sub.TrialEnd
+		}
+		schedule := model.RenewalSchedule{
+			Subscription: sub,
+			First:        sub.Billing.NextBilling(sub.StartDate, after),
+			Until:        sub.LastBilledDay(),
+		}
+		if schedule.Until != nil && schedule.Until.Before(schedule.First) {
+			continue
+		}
+		for _, p := range pauses[sub.ID] {
+			if p.ResumedFrom == nil {
+				continue
+			}
+			for day := sub.Billing.NextBilling(sub.StartDate, p.PausedFrom.AddDate(0, 0, -1)); day.Before(*p.ResumedFrom); day = sub.Billing.NextBilling(sub.StartDate, day) {
+				schedule.Skipped = append(schedule.Skipped, day)
+			}
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules, nil
+}
@@ -0,0 +1,333 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/morphlinkk/subscriptions/internal/model"
+)
+
+func TestRenewalsOf(t *testing.T) {
+	monthly15 := model.BillingInterval{Unit: model.BillingMonth, Count: 1, AnchorDay: 15}
+	base := model.Subscription{ID: 1, Service: "Netflix", Price: 1000, Currency: "RUB", Billing: monthly15, StartDate: date(2026, time.January, 15)}
+	with := func(f func(*model.Subscription)) model.Subscription {
+		sub := base
+		f(&sub)
+		return sub
+	}
+
+	tests := []struct {
+		name     string
+		sub      model.Subscription
+		prices   []model.SubscriptionPrice
+		pauses   []model.SubscriptionPause
+		from, to time.Time
+		want     []time.Time
+	}{
+		{
+			name: "monthly",
+			sub:  base,
+			from: date(2026, time.October, 1),
+			to:   date(2026, time.December, 31),
+			want: []time.Time{date(2026, time.October, 15), date(2026, time.November, 15), date(2026, time.December, 15)},
+		},
+		{
+			name: "window bounds are inclusive",
+			sub:  base,
+			from: date(2026, time.October, 15),
+			to:   date(2026, time.November, 15),
+			want: []time.Time{date(2026, time.October, 15), date(2026, time.November, 15)},
+		},
+		{
+			name: "first renewal on the anchor day of the start month",
+			sub:  with(func(s *model.Subscription) { s.StartDate = date(2026, time.November, 1) }),
+			from: date(2026, time.October, 1),
+			to:   date(2026, time.December, 31),
+			want: []time.Time{date(2026, time.November, 15), date(2026, time.December, 15)},
+		},
+		{
+			name: "anchor 31 falls back in shorter months",
+			sub: with(func(s *model.Subscription) {
+				s.Billing.AnchorDay = 31
+				s.StartDate = date(2027, time.January, 31)
+			}),
+			from: date(2027, time.January, 1),
+			to:   date(2027, time.April, 30),
+			want: []time.Time{date(2027, time.January, 31), date(2027, time.February, 28), date(2027, time.March, 31), date(2027, time.April, 30)},
+		},
+		{
+			name: "weekly",
+			sub: with(func(s *model.Subscription) {
+				s.Billing = model.BillingInterval{Unit: model.BillingWeek, Count: 1, AnchorDay: 1}
+				s.StartDate = date(2026, time.October, 1)
+			}),
+			from: date(2026, time.October, 1),
+			to:   date(2026, time.October, 31),
+			want: []time.Time{date(2026, time.October, 5), date(2026, time.October, 12), date(2026, time.October, 19), date(2026, time.October, 26)},
+		},
+		{
+			name: "yearly",
+			sub: with(func(s *model.Subscription) {
+				s.Billing = model.BillingInterval{Unit: model.BillingYear, Count: 1, AnchorDay: 5}
+				s.StartDate = date(2025, time.March, 1)
+			}),
+			from: date(2026, time.January, 1),
+			to:   date(2026, time.December, 31),
+			want: []time.Time{date(2026, time.March, 5)},
+		},
+		{
+			name: "free trial is not billed",
+			sub: with(func(s *model.Subscription) {
+				s.StartDate = date(2026, time.October, 1)
+				s.TrialEnd = ptr(date(2026, time.October, 31))
+			}),
+			prices: []model.SubscriptionPrice{
+				{Price: 0, EffectiveFrom: date(2026, time.October, 1), Billing: monthly15},
+				{Price: 1000, EffectiveFrom: date(2026, time.November, 1), Billing: monthly15},
+			},
+			from: date(2026, time.October, 1),
+			to:   date(2026, time.December, 31),
+			want: []time.Time{date(2026, time.November, 15), date(2026, time.December, 15)},
+		},
+		{
+			name: "no renewal inside the trial",
+			sub: with(func(s *model.Subscription) {
+				s.Billing = model.MonthlyBilling
+				s.StartDate = date(2026, time.December, 1)
+				s.TrialEnd = ptr(date(2027, time.January, 15))
+			}),
+			from: date(2026, time.December, 1),
+			to:   date(2027, time.March, 31),
+			want: []time.Time{date(2027, time.February, 1), date(2027, time.March, 1)},
+		},
+		{
+			name: "billing interval follows the price history",
+			sub:  with(func(s *model.Subscription) { s.StartDate = date(2026, time.September, 1) }),
+			prices: []model.SubscriptionPrice{
+				{Price: 1000, EffectiveFrom: date(2026, time.September, 1), Billing: monthly15},
+				{Price: 300, EffectiveFrom: date(2026, time.November, 1), Billing: model.BillingInterval{Unit: model.BillingWeek, Count: 2, AnchorDay: 2}},
+			},
+			from: date(2026, time.October, 1),
+			to:   date(2026, time.November, 30),
+			want: []time.Time{date(2026, time.October, 15), date(2026, time.November, 10), date(2026, time.November, 24)},
+		},
+		{
+			name: "pause spanning a renewal",
+			sub:  base,
+			pauses: []model.SubscriptionPause{
+				{SubscriptionID: 1, PausedFrom: date(2026, time.November, 1), ResumedFrom: ptr(date(2026, time.December, 1))},
+			},
+			from: date(2026, time.October, 1),
+			to:   date(2026, time.December, 31),
+			want: []time.Time{date(2026, time.October, 15), date(2026, time.December, 15)},
+		},
+		{
+			name: "open pause",
+			sub:  base,
+			pauses: []model.SubscriptionPause{
+				{SubscriptionID: 1, PausedFrom: date(2026, time.November, 1)},
+			},
+			from: date(2026, time.October, 1),
+			to:   date(2026, time.December, 31),
+			want: []time.Time{date(2026, time.October, 15)},
+		},
+		{
+			name: "end date bills through its month",
+			sub:  with(func(s *model.Subscription) { s.EndDate = ptr(date(2026, time.November, 1)) }),
+			from: date(2026, time.October, 1),
+			to:   date(2026, time.December, 31),
+			want: []time.Time{date(2026, time.October, 15), date(2026, time.November, 15)},
+		},
+		{
+			name: "cancel at the end of the period",
+			sub: with(func(s *model.Subscription) {
+				s.EndDate = ptr(date(2026, time.November, 1))
+				s.CancelAt = ptr(date(2026, time.November, 14))
+			}),
+			from: date(2026, time.October, 1),
+			to:   date(2026, time.December, 31),
+			want: []time.Time{date(2026, time.October, 15)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := renewalsOf(tt.sub, tt.prices, tt.pauses, tt.from, tt.to)
+			if len(got) != len(tt.want) {
+				t.Fatalf("renewalsOf() = %v, want %v", renewalDates(got), tt.want)
+			}
+			for i, r := range got {
+				if !r.Date.Equal(tt.want[i]) {
+					t.Errorf("renewalsOf()[%d].Date = %s, want %s", i, r.Date.Format(time.DateOnly), tt.want[i].Format(time.DateOnly))
+				}
+				price, billing := termsAt(tt.sub, tt.prices, monthStart(r.Date))
+				if r.Amount != price {
+					t.Errorf("renewalsOf()[%d].Amount = %d, want %d", i, r.Amount, price)
+				}
+				if r.Billing != billing {
+					t.Errorf("renewalsOf()[%d].Billing = %+v, want %+v", i, r.Billing, billing)
+				}
+			}
+		})
+	}
+}
+
+func renewalDates(renewals []model.Renewal) []time.Time {
+	dates := make([]time.Time, len(renewals))
+	for i, r := range renewals {
+		dates[i] = r.Date
+	}
+	return dates
+}
+
+func (r *fakeSubscriptionRepo) ListSubscriptionsInPeriod(ctx context.Context, params *model.SumOfSubscriptionPricesParams) ([]model.Subscription, error) {
+	return r.subs, nil
+}
+
+func (r *fakeSubscriptionRepo) ListPrices(ctx context.Context, ids []int64, asOf *time.Time) (map[int64][]model.SubscriptionPrice, error) {
+	return r.prices, nil
+}
+
+func TestListRenewals(t *testing.T) {
+	monthly := func(anchor int) model.BillingInterval {
+		return model.BillingInterval{Unit: model.BillingMonth, Count: 1, AnchorDay: anchor}
+	}
+	subs := []model.Subscription{
+		{ID: 2, Service: "Spotify", Price: 1000, Currency: "USD", Billing: monthly(10), StartDate: date(2026, time.January, 10)},
+		{ID: 1, Service: "Netflix", Price: 79900, Currency: "RUB", Billing: monthly(10), StartDate: date(2026, time.January, 10)},
+		{ID: 3, Service: "Yandex Plus", Price: 29900, Currency: "RUB", Billing: monthly(1), StartDate: date(2026, time.January, 1)},
+	}
+	rates := []model.ExchangeRate{{Date: date(2026, time.October, 1), Base: "USD", Quote: "RUB", Rate: 80}}
+
+	tests := []struct {
+		name     string
+		subs     []model.Subscription
+		from, to time.Time
+		currency *string
+		want     []model.Renewal
+		wantErr  bool
+	}{
+		{
+			name:     "ordered by day and subscription with a running total",
+			subs:     subs,
+			from:     date(2026, time.October, 5),
+			to:       date(2026, time.November, 4),
+			currency: ptr("RUB"),
+			want: []model.Renewal{
+				{Date: date(2026, time.October, 10), SubscriptionID: 1, Amount: 79900, Currency: "RUB", RunningTotal: 79900},
+				{Date: date(2026, time.October, 10), SubscriptionID: 2, Amount: 1000, Currency: "USD", RunningTotal: 159900},
+				{Date: date(2026, time.November, 1), SubscriptionID: 3, Amount: 29900, Currency: "RUB", RunningTotal: 189800},
+			},
+		},
+		{
+			name: "one currency needs no target",
+			subs: subs[1:],
+			from: date(2026, time.October, 1),
+			to:   date(2026, time.October, 31),
+			want: []model.Renewal{
+				{Date: date(2026, time.October, 1), SubscriptionID: 3, Amount: 29900, Currency: "RUB", RunningTotal: 29900},
+				{Date: date(2026, time.October, 10), SubscriptionID: 1, Amount: 79900, Currency: "RUB", RunningTotal: 109800},
+			},
+		},
+		{
+			name:    "several currencies need a target",
+			subs:    subs,
+			from:    date(2026, time.October, 1),
+			to:      date(2026, time.October, 31),
+			wantErr: true,
+		},
+		{
+			name:     "to before from",
+			subs:     subs,
+			from:     date(2026, time.October, 2),
+			to:       date(2026, time.October, 1),
+			currency: ptr("RUB"),
+			wantErr:  true,
+		},
+		{
+			name:     "window longer than a year",
+			subs:     subs,
+			from:     date(2026, time.January, 1),
+			to:       date(2027, time.January, 3),
+			currency: ptr("RUB"),
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSubscriptionService(&fakeSubscriptionRepo{subs: tt.subs}, nil, nil, &fakeRateRepo{rates: rates})
+			got, err := s.ListRenewals(context.Background(), model.RenewalsParams{
+				From:     &tt.from,
+				To:       &tt.to,
+				Currency: tt.currency,
+			})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("ListRenewals() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ListRenewals() error = %v", err)
+			}
+			if len(got.Renewals) != len(tt.want) {
+				t.Fatalf("ListRenewals() dates = %v, want %d renewals", renewalDates(got.Renewals), len(tt.want))
+			}
+			for i, r := range got.Renewals {
+				w := tt.want[i]
+				if !r.Date.Equal(w.Date) || r.SubscriptionID != w.SubscriptionID || r.Amount != w.Amount ||
+					r.Currency != w.Currency || r.RunningTotal != w.RunningTotal {
+					t.Errorf("Renewals[%d] = %s #%d %d %s total %d, want %s #%d %d %s total %d", i,
+						r.Date.Format(time.DateOnly), r.SubscriptionID, r.Amount, r.Currency, r.RunningTotal,
+						w.Date.Format(time.DateOnly), w.SubscriptionID, w.Amount, w.Currency, w.RunningTotal)
+				}
+			}
+			if last := tt.want[len(tt.want)-1].RunningTotal; got.Total != last {
+				t.Errorf("Total = %d, want %d", got.Total, last)
+			}
+		})
+	}
+}
+
+func TestListRenewalSchedules(t *testing.T) {
+	monthly := model.BillingInterval{Unit: model.BillingMonth, Count: 1, AnchorDay: 10}
+	subs := []model.Subscription{
+		{ID: 1, Status: model.StatusActive, Billing: monthly, StartDate: date(2026, time.January, 10)},
+		{ID: 2, Status: model.StatusTrial, Billing: monthly, StartDate: date(2026, time.September, 10), TrialEnd: ptr(date(2026, time.October, 20))},
+		{ID: 3, Status: model.StatusPendingCancel, Billing: monthly, StartDate: date(2026, time.January, 10), CancelAt: ptr(date(2026, time.December, 9))},
+		{ID: 4, Status: model.StatusPaused, Billing: monthly, StartDate: date(2026, time.January, 10)},
+		{ID: 5, Status: model.StatusCanceled, Billing: monthly, StartDate: date(2026, time.January, 10), EndDate: ptr(date(2026, time.March, 1))},
+	}
+	pauses := map[int64][]model.SubscriptionPause{
+		1: {{SubscriptionID: 1, PausedFrom: date(2026, time.March, 1), ResumedFrom: ptr(date(2026, time.May, 1))}},
+	}
+	want := []model.RenewalSchedule{
+		{
+			Subscription: subs[0],
+			First:        date(2026, time.January, 10),
+			Skipped:      []time.Time{date(2026, time.March, 10), date(2026, time.April, 10)},
+		},
+		{Subscription: subs[1], First: date(2026, time.November, 10)},
+		{Subscription: subs[2], First: date(2026, time.January, 10), Until: ptr(date(2026, time.December, 9))},
+	}
+
+	s := NewSubscriptionService(&fakeSubscriptionRepo{subs: subs, pauses: pauses}, nil, nil, nil)
+	got, err := s.ListRenewalSchedules(context.Background(), uuid.New())
+	if err != nil {
+		t.Fatalf("ListRenewalSchedules() error = %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ListRenewalSchedules() returned %d schedules, want %d", len(got), len(want))
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Subscription.ID != w.Subscription.ID || !g.First.Equal(w.First) || !reflect.DeepEqual(g.Until, w.Until) || !reflect.DeepEqual(g.Skipped, w.Skipped) {
+			t.Errorf("schedule %d = {%d %v %v %v}, want {%d %v %v %v}", i,
+				g.Subscription.ID, g.First, g.Until, g.Skipped, w.Subscription.ID, w.First, w.Until, w.Skipped)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/morphlinkk/subscriptions/internal/model"
+)
+
+// monthStart truncates t to the first day of its month.
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// monthsBetween returns the number of calendar months in [from, to], counting
+// both ends. It returns 0 when to is before from.
+func monthsBetween(from, to time.Time) int {
+	n := (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month()) + 1
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// activeRange clips the billed months of sub to [periodStart, periodEnd].
+// Open-ended subscriptions run until periodEnd. ok is false when the
+// subscription has no billed month inside the period.
+func activeRange(sub model.Subscription, periodStart, periodEnd time.Time) (from, to time.Time, ok bool) {
+	from = monthStart(sub.StartDate)
+	if ps := monthStart(periodStart); from.Before(ps) {
+		from = ps
+	}
+
+	to = monthStart(periodEnd)
+	if sub.EndDate != nil {
+		if end := monthStart(*sub.EndDate); end.Before(to) {
+			to = end
+		}
+	}
+
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
+
+// termsAt returns the price of sub in month and the billing interval it is
+// paid per, from its price history, which is ordered by effective month.
+// Months before the first price take the first price, and subscriptions
+// without a history their latest price and interval.
+func termsAt(sub model.Subscription, prices []model.SubscriptionPrice, month time.Time) (int, model.BillingInterval) {
+	if len(prices) == 0 {
+		return sub.Price, sub.Billing
+	}
+	terms := prices[0]
+	for _, p := range prices[1:] {
+		if p.EffectiveFrom.After(month) {
+			break
+		}
+		terms = p
+	}
+	return terms.Price, terms.Billing
+}
+
+// priceAt returns the price of sub in month, as termsAt does.
+func priceAt(sub model.Subscription, prices []model.SubscriptionPrice, month time.Time) int {
+	price, _ := termsAt(sub, prices, month)
+	return price
+}
+
+// monthlyCostAt returns the cost of sub in month: the price it pays then,
+// normalized from the billing interval it is paid per then to a month.
+func monthlyCostAt(sub model.Subscription, prices []model.SubscriptionPrice, month time.Time) float64 {
+	price, billing := termsAt(sub, prices, month)
+	return billing.MonthlyCost(price)
+}
+
+// roundAmount rounds a normalized amount to a whole amount of money.
+func roundAmount(x float64) int64 {
+	return int64(math.Round(x))
+}
+
+// calculateSpend adds up the monthly cost of every overlapped month for every
+// subscription, so that a yearly price counts for a twelfth each month, and
+// leaves out the months the subscription was paused. Trial months are also
+// added up on their own. Each month is converted to the currency of conv at
+// that month's rates. The price and billing interval of an item are the ones
+// of its last overlapped month, in its own currency.
+func calculateSpend(subs []model.Subscription, prices map[int64][]model.SubscriptionPrice, pauses map[int64][]model.SubscriptionPause, conv *currencyConverter, periodStart, periodEnd time.Time) (*model.SpendSummary, error) {
+	summary := &model.SpendSummary{
+		PeriodStart: monthStart(periodStart),
+		PeriodEnd:   monthStart(periodEnd),
+		Currency:    conv.target,
+		Items:       make([]model.SubscriptionSpend, 0, len(subs)),
+	}
+
+	var total, trialTotal float64
+	for _, sub := range subs {
+		from, to, ok := activeRange(sub, periodStart, periodEnd)
+		if !ok {
+			continue
+		}
+
+		var subtotal, trialSubtotal float64
+		var paused, trial int
+		for m := from; !m.After(to); m = m.AddDate(0, 1, 0) {
+			if pausedAt(pauses[sub.ID], m) {
+				paused++
+				continue
+			}
+			cost, err := conv.convert(monthlyCostAt(sub, prices[sub.ID], m), sub.Currency, m)
+			if err != nil {
+				return nil, err
+			}
+			subtotal += cost
+			if sub.InTrial(m) {
+				trial++
+				trialSubtotal += cost
+			}
+		}
+
+		price, billing := termsAt(sub, prices[sub.ID], to)
+		summary.Items = append(summary.Items, model.SubscriptionSpend{
+			SubscriptionID: sub.ID,
+			Service:        sub.Service,
+			UserID:         sub.UserID,
+			Price:          price,
+			Currency:       sub.Currency,
+			Billing:        billing,
+			MonthlyCost:    billing.MonthlyCost(price),
+			Months:         monthsBetween(from, to) - paused,
+			PausedMonths:   paused,
+			TrialMonths:    trial,
+			Subtotal:       roundAmount(subtotal),
+			TrialSubtotal:  roundAmount(trialSubtotal),
+		})
+		total += subtotal
+		trialTotal += trialSubtotal
+	}
+
+	summary.Total = roundAmount(total)
+	summary.TrialTotal = roundAmount(trialTotal)
+	summary.Rates = conv.applied()
+	return summary, nil
+}
+
+// groupKey returns the key sub is grouped under in a time series bucket.
+func groupKey(sub model.Subscription, groupBy model.SpendGroupBy) string {
+	switch groupBy {
+	case model.SpendGroupByService:
+		return sub.Service
+	case model.SpendGroupByUser:
+		return sub.UserID.String()
+	default:
+		return ""
+	}
+}
+
+// calculateSpendTimeSeries returns one bucket per month of the period with the
+// monthly cost at that month's prices and rates and the number of
+// subscriptions active in it, leaving out paused subscriptions, along with the
+// part of both that was in a trial. Totals are rounded once the bucket is
+// complete.
+func calculateSpendTimeSeries(subs []model.Subscription, prices map[int64][]model.SubscriptionPrice, pauses map[int64][]model.SubscriptionPause, conv *currencyConverter, periodStart, periodEnd time.Time, groupBy model.SpendGroupBy) ([]model.SpendBucket, error) {
+	first := monthStart(periodStart)
+	buckets := make([]model.SpendBucket, monthsBetween(first, monthStart(periodEnd)))
+	groupIndex := make([]map[string]int, len(buckets))
+	costs := make([]float64, len(buckets))
+	trialCosts := make([]float64, len(buckets))
+	groupCosts := make([][]float64, len(buckets))
+	for i := range buckets {
+		buckets[i].Month = first.AddDate(0, i, 0)
+		buckets[i].Currency = conv.target
+		groupIndex[i] = map[string]int{}
+	}
+
+	for _, sub := range subs {
+		from, to, ok := activeRange(sub, periodStart, periodEnd)
+		if !ok {
+			continue
+		}
+
+		key := groupKey(sub, groupBy)
+		for m := from; !m.After(to); m = m.AddDate(0, 1, 0) {
+			if pausedAt(pauses[sub.ID], m) {
+				continue
+			}
+			i := monthsBetween(first, m) - 1
+			b := &buckets[i]
+			cost, err := conv.convert(monthlyCostAt(sub, prices[sub.ID], m), sub.Currency, m)
+			if err != nil {
+				return nil, err
+			}
+			costs[i] += cost
+			b.ActiveSubscriptions++
+			if sub.InTrial(m) {
+				trialCosts[i] += cost
+				b.TrialSubscriptions++
+			}
+
+			if groupBy == model.SpendGroupByNone {
+				continue
+			}
+			gi, ok := groupIndex[i][key]
+			if !ok {
+				gi = len(b.Groups)
+				groupIndex[i][key] = gi
+				b.Groups = append(b.Groups, model.SpendGroup{Key: key})
+				groupCosts[i] = append(groupCosts[i], 0)
+			}
+			groupCosts[i][gi] += cost
+			b.Groups[gi].ActiveSubscriptions++
+		}
+	}
+
+	for i := range buckets {
+		buckets[i].Total = roundAmount(costs[i])
+		buckets[i].TrialTotal = roundAmount(trialCosts[i])
+		buckets[i].Rates = conv.ratesFor(buckets[i].Month)
+		for gi := range buckets[i].Groups {
+			buckets[i].Groups[gi].Total = roundAmount(groupCosts[i][gi])
+		}
+		sort.Slice(buckets[i].Groups, func(a, b int) bool {
+			return buckets[i].Groups[a].Key < buckets[i].Groups[b].Key
+		})
+	}
+
+	return buckets, nil
+}
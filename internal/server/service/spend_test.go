@@ -0,0 +1,467 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/morphlinkk/subscriptions/internal/model"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// spendOf calls calculateSpend, converting to rubles.
+func spendOf(t *testing.T, subs []model.Subscription, prices map[int64][]model.SubscriptionPrice, periodStart, periodEnd time.Time) *model.SpendSummary {
+	t.Helper()
+	summary, err := calculateSpend(subs, prices, nil, newConverter(model.DefaultCurrency), periodStart, periodEnd)
+	if err != nil {
+		t.Fatalf("calculateSpend() error = %v", err)
+	}
+	return summary
+}
+
+// timeSeriesOf calls calculateSpendTimeSeries, converting to rubles.
+func timeSeriesOf(t *testing.T, subs []model.Subscription, prices map[int64][]model.SubscriptionPrice, periodStart, periodEnd time.Time, groupBy model.SpendGroupBy) []model.SpendBucket {
+	t.Helper()
+	buckets, err := calculateSpendTimeSeries(subs, prices, nil, newConverter(model.DefaultCurrency), periodStart, periodEnd, groupBy)
+	if err != nil {
+		t.Fatalf("calculateSpendTimeSeries() error = %v", err)
+	}
+	return buckets
+}
+
+func TestMonthsBetween(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     int
+	}{
+		{"same month", date(2026, time.March, 1), date(2026, time.March, 1), 1},
+		{"days are ignored", date(2026, time.March, 31), date(2026, time.April, 1), 2},
+		{"across a year", date(2025, time.November, 1), date(2026, time.February, 1), 4},
+		{"to before from", date(2026, time.March, 1), date(2026, time.January, 1), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := monthsBetween(tt.from, tt.to); got != tt.want {
+				t.Errorf("monthsBetween() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateSpend(t *testing.T) {
+	periodStart, periodEnd := date(2026, time.January, 1), date(2026, time.June, 1)
+
+	tests := []struct {
+		name       string
+		sub        model.Subscription
+		wantMonths int
+	}{
+		{
+			name:       "open-ended over the whole period",
+			sub:        model.Subscription{ID: 1, Price: 400, Billing: model.MonthlyBilling, Currency: "RUB", StartDate: date(2025, time.May, 1)},
+			wantMonths: 6,
+		},
+		{
+			name:       "starts inside the period",
+			sub:        model.Subscription{ID: 1, Price: 400, Billing: model.MonthlyBilling, Currency: "RUB", StartDate: date(2026, time.March, 20)},
+			wantMonths: 4,
+		},
+		{
+			name:       "ends inside the period",
+			sub:        model.Subscription{ID: 1, Price: 400, Billing: model.MonthlyBilling, Currency: "RUB", StartDate: date(2025, time.May, 1), EndDate: ptr(date(2026, time.February, 1))},
+			wantMonths: 2,
+		},
+		{
+			name:       "starts and ends in the same month",
+			sub:        model.Subscription{ID: 1, Price: 400, Billing: model.MonthlyBilling, Currency: "RUB", StartDate: date(2026, time.April, 1), EndDate: ptr(date(2026, time.April, 1))},
+			wantMonths: 1,
+		},
+		{
+			name:       "ends before the period",
+			sub:        model.Subscription{ID: 1, Price: 400, Billing: model.MonthlyBilling, Currency: "RUB", StartDate: date(2025, time.May, 1), EndDate: ptr(date(2025, time.December, 1))},
+			wantMonths: 0,
+		},
+		{
+			name:       "starts after the period",
+			sub:        model.Subscription{ID: 1, Price: 400, Billing: model.MonthlyBilling, Currency: "RUB", StartDate: date(2026, time.July, 1)},
+			wantMonths: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary := spendOf(t, []model.Subscription{tt.sub}, nil, periodStart, periodEnd)
+			want := int64(tt.sub.Price) * int64(tt.wantMonths)
+			if summary.Total != want {
+				t.Errorf("Total = %d, want %d", summary.Total, want)
+			}
+			if tt.wantMonths == 0 {
+				if len(summary.Items) != 0 {
+					t.Errorf("Items = %+v, want none", summary.Items)
+				}
+				return
+			}
+			if len(summary.Items) != 1 {
+				t.Fatalf("Items = %+v, want one", summary.Items)
+			}
+			if item := summary.Items[0]; item.Months != tt.wantMonths || item.Subtotal != want {
+				t.Errorf("item = %d months, %d, want %d months, %d", item.Months, item.Subtotal, tt.wantMonths, want)
+			}
+		})
+	}
+}
+
+func TestPriceAt(t *testing.T) {
+	sub := model.Subscription{Price: 999}
+	prices := []model.SubscriptionPrice{
+		{Price: 500, EffectiveFrom: date(2026, time.March, 1), Billing: model.MonthlyBilling},
+		{Price: 600, EffectiveFrom: date(2026, time.June, 1), Billing: model.MonthlyBilling},
+		{Price: 700, EffectiveFrom: date(2026, time.September, 1), Billing: model.MonthlyBilling},
+	}
+
+	tests := []struct {
+		name   string
+		prices []model.SubscriptionPrice
+		month  time.Time
+		want   int
+	}{
+		{"no history takes the latest price", nil, date(2026, time.April, 1), 999},
+		{"before the first price", prices, date(2026, time.January, 1), 500},
+		{"first price", prices, date(2026, time.March, 1), 500},
+		{"month before a change", prices, date(2026, time.May, 1), 500},
+		{"month of a change", prices, date(2026, time.June, 1), 600},
+		{"after the last change", prices, date(2027, time.January, 1), 700},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := priceAt(sub, tt.prices, tt.month); got != tt.want {
+				t.Errorf("priceAt(%s) = %d, want %d", tt.month.Format(time.DateOnly), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateSpendPriceHistory(t *testing.T) {
+	sub := model.Subscription{ID: 1, Price: 600, Billing: model.MonthlyBilling, Currency: "RUB", StartDate: date(2025, time.May, 1)}
+	prices := map[int64][]model.SubscriptionPrice{1: {
+		{Price: 400, EffectiveFrom: date(2025, time.May, 1), Billing: model.MonthlyBilling},
+		{Price: 600, EffectiveFrom: date(2026, time.March, 1), Billing: model.MonthlyBilling},
+	}}
+
+	summary := spendOf(t, []model.Subscription{sub}, prices, date(2026, time.January, 1), date(2026, time.April, 1))
+	if want := int64(2*400 + 2*600); summary.Total != want {
+		t.Errorf("Total = %d, want %d", summary.Total, want)
+	}
+	if item := summary.Items[0]; item.Price != 600 || item.Months != 4 {
+		t.Errorf("item = price %d over %d months, want 600 over 4", item.Price, item.Months)
+	}
+
+	buckets := timeSeriesOf(t, []model.Subscription{sub}, prices, date(2026, time.February, 1), date(2026, time.March, 1), model.SpendGroupByNone)
+	if len(buckets) != 2 || buckets[0].Total != 400 || buckets[1].Total != 600 {
+		t.Errorf("buckets = %+v, want 400 then 600", buckets)
+	}
+
+	yearly := model.BillingInterval{Unit: model.BillingYear, Count: 1, AnchorDay: 1}
+	switched := map[int64][]model.SubscriptionPrice{1: {
+		{Price: 400, EffectiveFrom: date(2025, time.May, 1), Billing: model.MonthlyBilling},
+		{Price: 6000, EffectiveFrom: date(2026, time.March, 1), Billing: yearly},
+	}}
+	buckets = timeSeriesOf(t, []model.Subscription{sub}, switched, date(2026, time.February, 1), date(2026, time.March, 1), model.SpendGroupByNone)
+	if len(buckets) != 2 || buckets[0].Total != 400 || buckets[1].Total != 500 {
+		t.Errorf("buckets after switching to yearly = %+v, want 400 then 500", buckets)
+	}
+}
+
+func TestCalculateSpendBilling(t *testing.T) {
+	periodStart, periodEnd := date(2026, time.January, 1), date(2026, time.June, 1)
+
+	tests := []struct {
+		name            string
+		billing         model.BillingInterval
+		price           int
+		wantMonthlyCost float64
+		wantSubtotal    int64
+	}{
+		{"monthly", model.MonthlyBilling, 1000, 1000, 6000},
+		{"quarterly", model.BillingInterval{Unit: model.BillingQuarter, Count: 1, AnchorDay: 1}, 3000, 1000, 6000},
+		{"yearly", model.BillingInterval{Unit: model.BillingYear, Count: 1, AnchorDay: 1}, 12000, 1000, 6000},
+		{"every two months", model.BillingInterval{Unit: model.BillingMonth, Count: 2, AnchorDay: 1}, 1000, 500, 3000},
+		{"rounded once per item", model.BillingInterval{Unit: model.BillingYear, Count: 1, AnchorDay: 1}, 1000, 1000.0 / 12, 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := model.Subscription{ID: 1, Price: tt.price, Billing: tt.billing, Currency: "RUB", StartDate: date(2025, time.May, 1)}
+			summary := spendOf(t, []model.Subscription{sub}, nil, periodStart, periodEnd)
+			item := summary.Items[0]
+			if item.Billing != tt.billing || item.MonthlyCost != tt.wantMonthlyCost {
+				t.Errorf("item = %v at %v a month, want %v at %v", item.Billing, item.MonthlyCost, tt.billing, tt.wantMonthlyCost)
+			}
+			if item.Subtotal != tt.wantSubtotal || summary.Total != tt.wantSubtotal {
+				t.Errorf("Subtotal, Total = %d, %d, want %d", item.Subtotal, summary.Total, tt.wantSubtotal)
+			}
+		})
+	}
+}
+
+func TestCalculateSpendPauses(t *testing.T) {
+	sub := model.Subscription{ID: 1, Price: 1000, Billing: model.MonthlyBilling, Currency: "RUB", StartDate: date(2025, time.May, 1)}
+	periodStart, periodEnd := date(2026, time.January, 1), date(2026, time.June, 1)
+
+	tests := []struct {
+		name         string
+		pauses       []model.SubscriptionPause
+		wantMonths   int
+		wantPaused   int
+		wantSubtotal int64
+		wantBuckets  []int64
+	}{
+		{
+			name:         "no pause",
+			wantMonths:   6,
+			wantSubtotal: 6000,
+			wantBuckets:  []int64{1000, 1000, 1000, 1000, 1000, 1000},
+		},
+		{
+			name:         "closed pause",
+			pauses:       []model.SubscriptionPause{{PausedFrom: date(2026, time.February, 1), ResumedFrom: ptr(date(2026, time.April, 1))}},
+			wantMonths:   4,
+			wantPaused:   2,
+			wantSubtotal: 4000,
+			wantBuckets:  []int64{1000, 0, 0, 1000, 1000, 1000},
+		},
+		{
+			name:         "pause from before the period",
+			pauses:       []model.SubscriptionPause{{PausedFrom: date(2025, time.October, 1), ResumedFrom: ptr(date(2026, time.March, 1))}},
+			wantMonths:   4,
+			wantPaused:   2,
+			wantSubtotal: 4000,
+			wantBuckets:  []int64{0, 0, 1000, 1000, 1000, 1000},
+		},
+		{
+			name:         "open pause",
+			pauses:       []model.SubscriptionPause{{PausedFrom: date(2026, time.May, 1)}},
+			wantMonths:   4,
+			wantPaused:   2,
+			wantSubtotal: 4000,
+			wantBuckets:  []int64{1000, 1000, 1000, 1000, 0, 0},
+		},
+		{
+			name: "two pauses",
+			pauses: []model.SubscriptionPause{
+				{PausedFrom: date(2026, time.January, 1), ResumedFrom: ptr(date(2026, time.February, 1))},
+				{PausedFrom: date(2026, time.June, 1)},
+			},
+			wantMonths:   4,
+			wantPaused:   2,
+			wantSubtotal: 4000,
+			wantBuckets:  []int64{0, 1000, 1000, 1000, 1000, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pauses := map[int64][]model.SubscriptionPause{sub.ID: tt.pauses}
+			summary, err := calculateSpend([]model.Subscription{sub}, nil, pauses, newConverter("RUB"), periodStart, periodEnd)
+			if err != nil {
+				t.Fatalf("calculateSpend() error = %v", err)
+			}
+			item := summary.Items[0]
+			if item.Months != tt.wantMonths || item.PausedMonths != tt.wantPaused || item.Subtotal != tt.wantSubtotal {
+				t.Errorf("item = %d months, %d paused, %d, want %d months, %d paused, %d",
+					item.Months, item.PausedMonths, item.Subtotal, tt.wantMonths, tt.wantPaused, tt.wantSubtotal)
+			}
+
+			buckets, err := calculateSpendTimeSeries([]model.Subscription{sub}, nil, pauses, newConverter("RUB"), periodStart, periodEnd, model.SpendGroupByNone)
+			if err != nil {
+				t.Fatalf("calculateSpendTimeSeries() error = %v", err)
+			}
+			totals := make([]int64, len(buckets))
+			for i, b := range buckets {
+				totals[i] = b.Total
+			}
+			if !reflect.DeepEqual(totals, tt.wantBuckets) {
+				t.Errorf("bucket totals = %v, want %v", totals, tt.wantBuckets)
+			}
+		})
+	}
+}
+
+func TestCalculateSpendTrials(t *testing.T) {
+	periodStart, periodEnd := date(2026, time.January, 1), date(2026, time.June, 1)
+	trialPrices := func(paidFrom time.Time) []model.SubscriptionPrice {
+		return []model.SubscriptionPrice{
+			{Price: 100, EffectiveFrom: date(2026, time.January, 1), Billing: model.MonthlyBilling},
+			{Price: 1000, EffectiveFrom: paidFrom, Billing: model.MonthlyBilling},
+		}
+	}
+
+	tests := []struct {
+		name              string
+		trialEnd          time.Time
+		prices            []model.SubscriptionPrice
+		wantTrialMonths   int
+		wantSubtotal      int64
+		wantTrialSubtotal int64
+	}{
+		{
+			name:              "trial ending on the last day of a month",
+			trialEnd:          date(2026, time.February, 28),
+			prices:            trialPrices(date(2026, time.March, 1)),
+			wantTrialMonths:   2,
+			wantSubtotal:      2*100 + 4*1000,
+			wantTrialSubtotal: 2 * 100,
+		},
+		{
+			name:              "trial ending mid-month",
+			trialEnd:          date(2026, time.February, 14),
+			prices:            trialPrices(date(2026, time.March, 1)),
+			wantTrialMonths:   2,
+			wantSubtotal:      2*100 + 4*1000,
+			wantTrialSubtotal: 2 * 100,
+		},
+		{
+			name:              "free trial",
+			trialEnd:          date(2026, time.January, 31),
+			prices:            []model.SubscriptionPrice{{Price: 0, EffectiveFrom: date(2026, time.January, 1), Billing: model.MonthlyBilling}, {Price: 1000, EffectiveFrom: date(2026, time.February, 1), Billing: model.MonthlyBilling}},
+			wantTrialMonths:   1,
+			wantSubtotal:      5 * 1000,
+			wantTrialSubtotal: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := model.Subscription{ID: 1, Price: 1000, Billing: model.MonthlyBilling, Currency: "RUB", StartDate: date(2026, time.January, 1), TrialEnd: &tt.trialEnd}
+			summary := spendOf(t, []model.Subscription{sub}, map[int64][]model.SubscriptionPrice{1: tt.prices}, periodStart, periodEnd)
+			item := summary.Items[0]
+			if item.TrialMonths != tt.wantTrialMonths || item.Subtotal != tt.wantSubtotal || item.TrialSubtotal != tt.wantTrialSubtotal {
+				t.Errorf("item = %d trial months, %d of %d, want %d trial months, %d of %d",
+					item.TrialMonths, item.TrialSubtotal, item.Subtotal, tt.wantTrialMonths, tt.wantTrialSubtotal, tt.wantSubtotal)
+			}
+			if summary.TrialTotal != tt.wantTrialSubtotal {
+				t.Errorf("TrialTotal = %d, want %d", summary.TrialTotal, tt.wantTrialSubtotal)
+			}
+
+			buckets := timeSeriesOf(t, []model.Subscription{sub}, map[int64][]model.SubscriptionPrice{1: tt.prices}, periodStart, periodEnd, model.SpendGroupByNone)
+			trials := 0
+			for _, b := range buckets {
+				trials += b.TrialSubscriptions
+			}
+			if trials != tt.wantTrialMonths {
+				t.Errorf("trial subscriptions over the buckets = %d, want %d", trials, tt.wantTrialMonths)
+			}
+		})
+	}
+}
+
+func TestCalculateSpendTimeSeries(t *testing.T) {
+	userA, userB := uuid.New(), uuid.New()
+	subs := []model.Subscription{
+		{ID: 1, Service: "Netflix", UserID: userA, Price: 400, Billing: model.MonthlyBilling, Currency: "RUB", StartDate: date(2025, time.May, 1)},
+		{ID: 2, Service: "Spotify", UserID: userA, Price: 200, Billing: model.MonthlyBilling, Currency: "RUB", StartDate: date(2026, time.February, 1), EndDate: ptr(date(2026, time.February, 1))},
+		{ID: 3, Service: "Netflix", UserID: userB, Price: 500, Billing: model.MonthlyBilling, Currency: "RUB", StartDate: date(2026, time.March, 1)},
+	}
+	periodStart, periodEnd := date(2026, time.January, 1), date(2026, time.March, 1)
+
+	tests := []struct {
+		name    string
+		groupBy model.SpendGroupBy
+		want    []model.SpendBucket
+	}{
+		{
+			name:    "ungrouped",
+			groupBy: model.SpendGroupByNone,
+			want: []model.SpendBucket{
+				{Month: date(2026, time.January, 1), Currency: "RUB", Total: 400, ActiveSubscriptions: 1},
+				{Month: date(2026, time.February, 1), Currency: "RUB", Total: 600, ActiveSubscriptions: 2},
+				{Month: date(2026, time.March, 1), Currency: "RUB", Total: 900, ActiveSubscriptions: 2},
+			},
+		},
+		{
+			name:    "by service",
+			groupBy: model.SpendGroupByService,
+			want: []model.SpendBucket{
+				{Month: date(2026, time.January, 1), Currency: "RUB", Total: 400, ActiveSubscriptions: 1, Groups: []model.SpendGroup{
+					{Key: "Netflix", Total: 400, ActiveSubscriptions: 1},
+				}},
+				{Month: date(2026, time.February, 1), Currency: "RUB", Total: 600, ActiveSubscriptions: 2, Groups: []model.SpendGroup{
+					{Key: "Netflix", Total: 400, ActiveSubscriptions: 1},
+					{Key: "Spotify", Total: 200, ActiveSubscriptions: 1},
+				}},
+				{Month: date(2026, time.March, 1), Currency: "RUB", Total: 900, ActiveSubscriptions: 2, Groups: []model.SpendGroup{
+					{Key: "Netflix", Total: 900, ActiveSubscriptions: 2},
+				}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := timeSeriesOf(t, subs, nil, periodStart, periodEnd, tt.groupBy)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("calculateSpendTimeSeries() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateSpendTimeSeriesByUser(t *testing.T) {
+	userA, userB := uuid.New(), uuid.New()
+	subs := []model.Subscription{
+		{ID: 1, UserID: userA, Price: 400, Billing: model.MonthlyBilling, Currency: "RUB", StartDate: date(2025, time.May, 1)},
+		{ID: 2, UserID: userB, Price: 500, Billing: model.MonthlyBilling, Currency: "RUB", StartDate: date(2025, time.May, 1)},
+		{ID: 3, UserID: userA, Price: 100, Billing: model.MonthlyBilling, Currency: "RUB", StartDate: date(2025, time.May, 1)},
+	}
+	month := date(2026, time.January, 1)
+
+	buckets := timeSeriesOf(t, subs, nil, month, month, model.SpendGroupByUser)
+	if len(buckets) != 1 {
+		t.Fatalf("buckets = %+v, want one", buckets)
+	}
+	totals := map[string]int64{}
+	for _, g := range buckets[0].Groups {
+		totals[g.Key] = g.Total
+	}
+	if want := map[string]int64{userA.String(): 500, userB.String(): 500}; !reflect.DeepEqual(totals, want) {
+		t.Errorf("group totals = %v, want %v", totals, want)
+	}
+}
+
+func TestCalculateSpendConvertsEachMonth(t *testing.T) {
+	jan, feb := date(2026, time.January, 1), date(2026, time.February, 1)
+	conv := newConverter("RUB", rate(jan, "USD", "RUB", 80), rate(feb, "USD", "RUB", 90))
+	subs := []model.Subscription{
+		{ID: 1, Price: 1000, Currency: "USD", Billing: model.MonthlyBilling, StartDate: date(2025, time.May, 1)},
+		{ID: 2, Price: 50000, Currency: "RUB", Billing: model.MonthlyBilling, StartDate: date(2025, time.May, 1)},
+	}
+
+	summary, err := calculateSpend(subs, nil, nil, conv, jan, feb)
+	if err != nil {
+		t.Fatalf("calculateSpend() error = %v", err)
+	}
+	if item := summary.Items[0]; item.Subtotal != 80000+90000 || item.Price != 1000 || item.Currency != "USD" {
+		t.Errorf("USD item = %d %s, subtotal %d, want 1000 USD, subtotal %d", item.Price, item.Currency, item.Subtotal, 80000+90000)
+	}
+	if want := int64(80000 + 90000 + 2*50000); summary.Total != want || summary.Currency != "RUB" {
+		t.Errorf("Total = %d %s, want %d RUB", summary.Total, summary.Currency, want)
+	}
+	if want := []model.AppliedRate{rate(jan, "USD", "RUB", 80), rate(feb, "USD", "RUB", 90)}; !reflect.DeepEqual(summary.Rates, want) {
+		t.Errorf("Rates = %v, want %v", summary.Rates, want)
+	}
+
+	if _, err := calculateSpend(subs, nil, nil, conv, jan, date(2026, time.March, 1)); err == nil {
+		t.Error("calculateSpend() without a rate for March: error = nil, want an error")
+	}
+}
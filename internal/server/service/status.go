@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/morphlinkk/subscriptions/internal/apperr"
+	"github.com/morphlinkk/subscriptions/internal/model"
+)
+
+// currentMonth returns the first day of the current month in UTC.
+func currentMonth() time.Time {
+	return monthStart(time.Now().UTC())
+}
+
+// ChangeStatus takes params.Action on subscription id in
+// params.EffectiveMonth, the current month by default:
+//   - pause stops billing from that month on, and resume from that month on
+//     again. Neither can take effect in a future month.
+//   - cancel makes that month the last billed one. The subscription is
+//     pending_cancel until then and canceled afterwards. With
+//     params.AtPeriodEnd it instead ends with the current billing period, or
+//     the trial, and is pending_cancel until that day.
+//   - reactivate clears the end date, which also undoes a cancel at period
+//     end before its day. A canceled or expired subscription is
+//     billed again from that month on; the months in between count as paused.
+func (s *subscriptionService) ChangeStatus(ctx context.Context, id int64, params model.ChangeStatusParams) (*model.Subscription, error) {
+	if err := validateID(id); err != nil {
+		return nil, err
+	}
+
+	sub, err := s.repo.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !model.CanTransition(params.Action, sub.Status) {
+		return nil, apperr.Conflict("subscription %d is %s and cannot %s", id, sub.Status, params.Action)
+	}
+
+	pauses, err := s.repo.ListPauses(ctx, []int64{id}, nil)
+	if err != nil {
+		return nil, err
+	}
+	var open, last *model.SubscriptionPause
+	if n := len(pauses[id]); n > 0 {
+		last = &pauses[id][n-1]
+		if last.ResumedFrom == nil {
+			open = last
+		}
+	}
+
+	current := currentMonth()
+	month := current
+	if params.EffectiveMonth != nil {
+		month = monthStart(*params.EffectiveMonth)
+	}
+
+	t := model.StatusTransitionParams{
+		StatusChange: model.StatusChange{
+			Action:         params.Action,
+			From:           sub.Status,
+			EffectiveMonth: month,
+		},
+		Version: sub.Version,
+	}
+
+	var v apperr.Validator
+	v.Check(!params.AtPeriodEnd || params.Action == model.ActionCancel,
+		"at_period_end", apperr.CodeInvalid, "at_period_end only applies to cancel")
+	start := monthStart(sub.StartDate)
+	switch params.Action {
+	case model.ActionPause:
+		v.Check(!month.Before(start),
+			"effective_month", apperr.CodeOutOfRange, "effective_month must not be before the subscription's start month")
+		v.Check(sub.EndDate == nil || !month.After(monthStart(*sub.EndDate)),
+			"effective_month", apperr.CodeOutOfRange, "effective_month must not be after the subscription's end month")
+		v.Check(!month.After(current), "effective_month", apperr.CodeOutOfRange, "effective_month must not be in the future")
+		v.Check(last == nil || last.ResumedFrom == nil || !month.Before(*last.ResumedFrom),
+			"effective_month", apperr.CodeOutOfRange, "effective_month must not be before the previous pause ended")
+		t.To = model.StatusPaused
+		t.Pause = &model.SubscriptionPause{SubscriptionID: id, PausedFrom: month}
+
+	case model.ActionResume:
+		v.Check(open == nil || !month.Before(open.PausedFrom),
+			"effective_month", apperr.CodeOutOfRange, "effective_month must not be before the pause started")
+		v.Check(!month.After(current), "effective_month", apperr.CodeOutOfRange, "effective_month must not be in the future")
+		t.To = model.StatusActive
+		t.ResumeFrom = &month
+
+	case model.ActionCancel:
+		if params.AtPeriodEnd {
+			v.Check(params.EffectiveMonth == nil, "effective_month", apperr.CodeInvalid, "effective_month cannot be combined with at_period_end")
+			if sub.Status == model.StatusPaused {
+				return nil, apperr.Conflict("subscription %d is paused and has no billing period to end, cancel it with effective_month", id)
+			}
+			cancelAtPeriodEnd(&v, sub, &t)
+			break
+		}
+		v.Check(!month.Before(start),
+			"effective_month", apperr.CodeOutOfRange, "effective_month must not be before the subscription's start month")
+		v.Check(sub.EndDate == nil || !month.After(monthStart(*sub.EndDate)),
+			"effective_month", apperr.CodeOutOfRange, "effective_month must not be after the subscription's end month")
+		t.To = model.StatusCanceled
+		if month.After(current) {
+			t.To = model.StatusPendingCancel
+		}
+		t.EndDate = model.NullableOf(month)
+		if open != nil {
+			next := month.AddDate(0, 1, 0)
+			t.ResumeFrom = &next
+		}
+
+	case model.ActionReactivate:
+		t.To = model.StatusActive
+		t.EndDate = model.Nullable[time.Time]{Set: true}
+		if sub.Status == model.StatusPendingCancel || sub.EndDate == nil {
+			break
+		}
+
+		end := monthStart(*sub.EndDate)
+		v.Check(!month.Before(end),
+			"effective_month", apperr.CodeOutOfRange, "effective_month must not be before the subscription's end month")
+		v.Check(!month.After(current), "effective_month", apperr.CodeOutOfRange, "effective_month must not be in the future")
+		if open != nil {
+			t.ResumeFrom = &month
+		} else if gap := end.AddDate(0, 1, 0); month.After(gap) {
+			t.Pause = &model.SubscriptionPause{SubscriptionID: id, PausedFrom: gap, ResumedFrom: &month}
+		}
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	return s.repo.ChangeStatus(ctx, id, &t)
+}
+
+// cancelAtPeriodEnd fills t to cancel sub on the last day of its current
+// billing period, or of its trial, so that it stays pending_cancel until then.
+func cancelAtPeriodEnd(v *apperr.Validator, sub *model.Subscription, t *model.StatusTransitionParams) {
+	day := today()
+	if day.Before(sub.StartDate) {
+		v.Add("at_period_end", apperr.CodeInvalid, "the subscription has not started yet, cancel it with effective_month")
+		return
+	}
+
+	end := sub.Billing.PeriodEnd(sub.StartDate, day)
+	if sub.Status == model.StatusTrial && sub.TrialEnd != nil {
+		end = *sub.TrialEnd
+	}
+	month := monthStart(end)
+	v.Check(sub.EndDate == nil || !month.After(monthStart(*sub.EndDate)),
+		"at_period_end", apperr.CodeOutOfRange, "the subscription ends before its current billing period does")
+
+	t.To = model.StatusPendingCancel
+	t.EffectiveMonth = month
+	t.EndDate = model.NullableOf(month)
+	t.CancelAt = &end
+}
+
+// ListStatusChanges returns the status changes of subscription id, oldest
+// first.
+func (s *subscriptionService) ListStatusChanges(ctx context.Context, id int64) ([]model.StatusChange, error) {
+	if _, err := s.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListStatusChanges(ctx, id)
+}
+
+// pausesOf returns the pauses of subs keyed by subscription ID.
+func (s *subscriptionService) pausesOf(ctx context.Context, subs []model.Subscription, asOf *time.Time) (map[int64][]model.SubscriptionPause, error) {
+	if len(subs) == 0 {
+		return nil, nil
+	}
+	ids := make([]int64, len(subs))
+	for i, sub := range subs {
+		ids[i] = sub.ID
+	}
+	return s.repo.ListPauses(ctx, ids, asOf)
+}
+
+// pausedAt reports whether month falls within one of pauses.
+func pausedAt(pauses []model.SubscriptionPause, month time.Time) bool {
+	for _, p := range pauses {
+		if p.Covers(month) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,352 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/morphlinkk/subscriptions/internal/apperr"
+	"github.com/morphlinkk/subscriptions/internal/model"
+	"github.com/morphlinkk/subscriptions/internal/repository"
+)
+
+// fakeSubscriptionRepo serves a fixed set of subscriptions, their prices and
+// pauses and records the status transition it is asked to write. Methods the tests
+// do not use are left unimplemented.
+type fakeSubscriptionRepo struct {
+	repository.SubscriptionRepository
+	subs       []model.Subscription
+	prices     map[int64][]model.SubscriptionPrice
+	pauses     map[int64][]model.SubscriptionPause
+	transition *model.StatusTransitionParams
+}
+
+func (r *fakeSubscriptionRepo) GetById(ctx context.Context, id int64) (*model.Subscription, error) {
+	for _, s := range r.subs {
+		if s.ID == id {
+			return &s, nil
+		}
+	}
+	return nil, apperr.NotFound("subscription %d not found", id)
+}
+
+func (r *fakeSubscriptionRepo) ListPauses(ctx context.Context, ids []int64, asOf *time.Time) (map[int64][]model.SubscriptionPause, error) {
+	return r.pauses, nil
+}
+
+func (r *fakeSubscriptionRepo) ChangeStatus(ctx context.Context, id int64, params *model.StatusTransitionParams) (*model.Subscription, error) {
+	r.transition = params
+	sub, err := r.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	sub.Status = params.To
+	return sub, nil
+}
+
+func TestChangeStatus(t *testing.T) {
+	current := currentMonth()
+	ago := func(months int) time.Time { return current.AddDate(0, -months, 0) }
+	start := ago(12)
+	active := model.Subscription{ID: 1, Status: model.StatusActive, StartDate: start}
+	paused := model.Subscription{ID: 1, Status: model.StatusPaused, StartDate: start}
+	canceled := model.Subscription{ID: 1, Status: model.StatusCanceled, StartDate: start, EndDate: ptr(ago(4))}
+	pendingCancel := model.Subscription{ID: 1, Status: model.StatusPendingCancel, StartDate: start, EndDate: ptr(current.AddDate(0, 2, 0))}
+
+	tests := []struct {
+		name     string
+		sub      model.Subscription
+		pauses   []model.SubscriptionPause
+		params   model.ChangeStatusParams
+		want     *model.StatusTransitionParams
+		wantKind apperr.Kind
+	}{
+		{
+			name:   "pause this month",
+			sub:    active,
+			params: model.ChangeStatusParams{Action: model.ActionPause},
+			want: &model.StatusTransitionParams{
+				StatusChange: model.StatusChange{Action: model.ActionPause, From: model.StatusActive, To: model.StatusPaused, EffectiveMonth: current},
+				Pause:        &model.SubscriptionPause{SubscriptionID: 1, PausedFrom: current},
+			},
+		},
+		{
+			name:   "pause from a past month",
+			sub:    active,
+			params: model.ChangeStatusParams{Action: model.ActionPause, EffectiveMonth: ptr(ago(2).AddDate(0, 0, 14))},
+			want: &model.StatusTransitionParams{
+				StatusChange: model.StatusChange{Action: model.ActionPause, From: model.StatusActive, To: model.StatusPaused, EffectiveMonth: ago(2)},
+				Pause:        &model.SubscriptionPause{SubscriptionID: 1, PausedFrom: ago(2)},
+			},
+		},
+		{
+			name:     "pause in a future month",
+			sub:      active,
+			params:   model.ChangeStatusParams{Action: model.ActionPause, EffectiveMonth: ptr(current.AddDate(0, 1, 0))},
+			wantKind: apperr.KindValidation,
+		},
+		{
+			name:     "pause before the start",
+			sub:      active,
+			params:   model.ChangeStatusParams{Action: model.ActionPause, EffectiveMonth: ptr(ago(13))},
+			wantKind: apperr.KindValidation,
+		},
+		{
+			name:     "pause before the previous pause ended",
+			sub:      active,
+			pauses:   []model.SubscriptionPause{{SubscriptionID: 1, PausedFrom: ago(6), ResumedFrom: ptr(ago(3))}},
+			params:   model.ChangeStatusParams{Action: model.ActionPause, EffectiveMonth: ptr(ago(4))},
+			wantKind: apperr.KindValidation,
+		},
+		{
+			name:     "pause a paused subscription",
+			sub:      paused,
+			params:   model.ChangeStatusParams{Action: model.ActionPause},
+			wantKind: apperr.KindConflict,
+		},
+		{
+			name:   "resume",
+			sub:    paused,
+			pauses: []model.SubscriptionPause{{SubscriptionID: 1, PausedFrom: ago(2)}},
+			params: model.ChangeStatusParams{Action: model.ActionResume},
+			want: &model.StatusTransitionParams{
+				StatusChange: model.StatusChange{Action: model.ActionResume, From: model.StatusPaused, To: model.StatusActive, EffectiveMonth: current},
+				ResumeFrom:   &current,
+			},
+		},
+		{
+			name:     "resume before the pause started",
+			sub:      paused,
+			pauses:   []model.SubscriptionPause{{SubscriptionID: 1, PausedFrom: ago(2)}},
+			params:   model.ChangeStatusParams{Action: model.ActionResume, EffectiveMonth: ptr(ago(3))},
+			wantKind: apperr.KindValidation,
+		},
+		{
+			name:     "resume an active subscription",
+			sub:      active,
+			params:   model.ChangeStatusParams{Action: model.ActionResume},
+			wantKind: apperr.KindConflict,
+		},
+		{
+			name:   "cancel this month",
+			sub:    active,
+			params: model.ChangeStatusParams{Action: model.ActionCancel},
+			want: &model.StatusTransitionParams{
+				StatusChange: model.StatusChange{Action: model.ActionCancel, From: model.StatusActive, To: model.StatusCanceled, EffectiveMonth: current},
+				EndDate:      model.NullableOf(current),
+			},
+		},
+		{
+			name:   "cancel in a future month",
+			sub:    active,
+			params: model.ChangeStatusParams{Action: model.ActionCancel, EffectiveMonth: ptr(current.AddDate(0, 3, 0))},
+			want: &model.StatusTransitionParams{
+				StatusChange: model.StatusChange{Action: model.ActionCancel, From: model.StatusActive, To: model.StatusPendingCancel, EffectiveMonth: current.AddDate(0, 3, 0)},
+				EndDate:      model.NullableOf(current.AddDate(0, 3, 0)),
+			},
+		},
+		{
+			name:   "cancel a paused subscription ends the pause after the end month",
+			sub:    paused,
+			pauses: []model.SubscriptionPause{{SubscriptionID: 1, PausedFrom: ago(2)}},
+			params: model.ChangeStatusParams{Action: model.ActionCancel, EffectiveMonth: ptr(ago(1))},
+			want: &model.StatusTransitionParams{
+				StatusChange: model.StatusChange{Action: model.ActionCancel, From: model.StatusPaused, To: model.StatusCanceled, EffectiveMonth: ago(1)},
+				EndDate:      model.NullableOf(ago(1)),
+				ResumeFrom:   &current,
+			},
+		},
+		{
+			name:     "cancel a canceled subscription",
+			sub:      canceled,
+			params:   model.ChangeStatusParams{Action: model.ActionCancel},
+			wantKind: apperr.KindConflict,
+		},
+		{
+			name:   "reactivate a pending cancel",
+			sub:    pendingCancel,
+			params: model.ChangeStatusParams{Action: model.ActionReactivate},
+			want: &model.StatusTransitionParams{
+				StatusChange: model.StatusChange{Action: model.ActionReactivate, From: model.StatusPendingCancel, To: model.StatusActive, EffectiveMonth: current},
+				EndDate:      model.Nullable[time.Time]{Set: true},
+			},
+		},
+		{
+			name:   "reactivate right after the end month",
+			sub:    canceled,
+			params: model.ChangeStatusParams{Action: model.ActionReactivate, EffectiveMonth: ptr(ago(3))},
+			want: &model.StatusTransitionParams{
+				StatusChange: model.StatusChange{Action: model.ActionReactivate, From: model.StatusCanceled, To: model.StatusActive, EffectiveMonth: ago(3)},
+				EndDate:      model.Nullable[time.Time]{Set: true},
+			},
+		},
+		{
+			name:   "reactivate later pauses the months in between",
+			sub:    canceled,
+			params: model.ChangeStatusParams{Action: model.ActionReactivate},
+			want: &model.StatusTransitionParams{
+				StatusChange: model.StatusChange{Action: model.ActionReactivate, From: model.StatusCanceled, To: model.StatusActive, EffectiveMonth: current},
+				EndDate:      model.Nullable[time.Time]{Set: true},
+				Pause:        &model.SubscriptionPause{SubscriptionID: 1, PausedFrom: ago(3), ResumedFrom: &current},
+			},
+		},
+		{
+			name:     "reactivate before the end month",
+			sub:      canceled,
+			params:   model.ChangeStatusParams{Action: model.ActionReactivate, EffectiveMonth: ptr(ago(5))},
+			wantKind: apperr.KindValidation,
+		},
+		{
+			name:     "reactivate an active subscription",
+			sub:      active,
+			params:   model.ChangeStatusParams{Action: model.ActionReactivate},
+			wantKind: apperr.KindConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeSubscriptionRepo{
+				subs:   []model.Subscription{tt.sub},
+				pauses: map[int64][]model.SubscriptionPause{tt.sub.ID: tt.pauses},
+			}
+			s := NewSubscriptionService(repo, nil, nil, nil)
+
+			_, err := s.ChangeStatus(context.Background(), tt.sub.ID, tt.params)
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("ChangeStatus() wrote %+v, want an error", repo.transition)
+				}
+				if kind := apperr.KindOf(err); kind != tt.wantKind {
+					t.Errorf("ChangeStatus() error kind = %v, want %v", kind, tt.wantKind)
+				}
+				if repo.transition != nil {
+					t.Errorf("ChangeStatus() wrote %+v after an error", repo.transition)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ChangeStatus() error = %v", err)
+			}
+			if !reflect.DeepEqual(repo.transition, tt.want) {
+				t.Errorf("ChangeStatus() wrote %+v, want %+v", repo.transition, tt.want)
+			}
+		})
+	}
+}
+
+func TestChangeStatusAtPeriodEnd(t *testing.T) {
+	now := today()
+	start := now.AddDate(0, -3, 0)
+	monthly := model.BillingInterval{Unit: model.BillingMonth, Count: 1, AnchorDay: start.Day()}
+	periodEnd := monthly.PeriodEnd(start, now)
+	trialEnd := now.AddDate(0, 0, 10)
+
+	tests := []struct {
+		name     string
+		sub      model.Subscription
+		params   model.ChangeStatusParams
+		want     *model.StatusTransitionParams
+		wantKind apperr.Kind
+	}{
+		{
+			name:   "active ends with the billing period",
+			sub:    model.Subscription{ID: 1, Status: model.StatusActive, Billing: monthly, StartDate: start},
+			params: model.ChangeStatusParams{Action: model.ActionCancel, AtPeriodEnd: true},
+			want: &model.StatusTransitionParams{
+				StatusChange: model.StatusChange{Action: model.ActionCancel, From: model.StatusActive, To: model.StatusPendingCancel, EffectiveMonth: monthStart(periodEnd)},
+				EndDate:      model.NullableOf(monthStart(periodEnd)),
+				CancelAt:     &periodEnd,
+			},
+		},
+		{
+			name:   "trial ends with the trial",
+			sub:    model.Subscription{ID: 1, Status: model.StatusTrial, Billing: monthly, StartDate: start, TrialEnd: &trialEnd},
+			params: model.ChangeStatusParams{Action: model.ActionCancel, AtPeriodEnd: true},
+			want: &model.StatusTransitionParams{
+				StatusChange: model.StatusChange{Action: model.ActionCancel, From: model.StatusTrial, To: model.StatusPendingCancel, EffectiveMonth: monthStart(trialEnd)},
+				EndDate:      model.NullableOf(monthStart(trialEnd)),
+				CancelAt:     &trialEnd,
+			},
+		},
+		{
+			name:     "paused has no billing period",
+			sub:      model.Subscription{ID: 1, Status: model.StatusPaused, Billing: monthly, StartDate: start},
+			params:   model.ChangeStatusParams{Action: model.ActionCancel, AtPeriodEnd: true},
+			wantKind: apperr.KindConflict,
+		},
+		{
+			name:     "not started yet",
+			sub:      model.Subscription{ID: 1, Status: model.StatusActive, Billing: monthly, StartDate: now.AddDate(0, 1, 0)},
+			params:   model.ChangeStatusParams{Action: model.ActionCancel, AtPeriodEnd: true},
+			wantKind: apperr.KindValidation,
+		},
+		{
+			name:     "ends before the billing period does",
+			sub:      model.Subscription{ID: 1, Status: model.StatusActive, Billing: model.BillingInterval{Unit: model.BillingYear, Count: 1, AnchorDay: 1}, StartDate: start, EndDate: ptr(currentMonth())},
+			params:   model.ChangeStatusParams{Action: model.ActionCancel, AtPeriodEnd: true},
+			wantKind: apperr.KindValidation,
+		},
+		{
+			name:     "with effective_month",
+			sub:      model.Subscription{ID: 1, Status: model.StatusActive, Billing: monthly, StartDate: start},
+			params:   model.ChangeStatusParams{Action: model.ActionCancel, AtPeriodEnd: true, EffectiveMonth: ptr(currentMonth())},
+			wantKind: apperr.KindValidation,
+		},
+		{
+			name:     "for another action",
+			sub:      model.Subscription{ID: 1, Status: model.StatusActive, Billing: monthly, StartDate: start},
+			params:   model.ChangeStatusParams{Action: model.ActionPause, AtPeriodEnd: true},
+			wantKind: apperr.KindValidation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeSubscriptionRepo{subs: []model.Subscription{tt.sub}}
+			s := NewSubscriptionService(repo, nil, nil, nil)
+
+			_, err := s.ChangeStatus(context.Background(), tt.sub.ID, tt.params)
+			if tt.want == nil {
+				if kind := apperr.KindOf(err); err == nil || kind != tt.wantKind {
+					t.Fatalf("ChangeStatus() error = %v, want a %v error", err, tt.wantKind)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ChangeStatus() error = %v", err)
+			}
+			if !reflect.DeepEqual(repo.transition, tt.want) {
+				t.Errorf("ChangeStatus() wrote %+v, want %+v", repo.transition, tt.want)
+			}
+		})
+	}
+}
+
+func TestPausedAt(t *testing.T) {
+	pauses := []model.SubscriptionPause{
+		{PausedFrom: date(2026, time.February, 1), ResumedFrom: ptr(date(2026, time.April, 1))},
+		{PausedFrom: date(2026, time.September, 1)},
+	}
+
+	tests := []struct {
+		month time.Time
+		want  bool
+	}{
+		{date(2026, time.January, 1), false},
+		{date(2026, time.February, 1), true},
+		{date(2026, time.March, 1), true},
+		{date(2026, time.April, 1), false},
+		{date(2026, time.August, 1), false},
+		{date(2027, time.January, 1), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.month.Format("01-2006"), func(t *testing.T) {
+			if got := pausedAt(pauses, tt.month); got != tt.want {
+				t.Errorf("pausedAt(%s) = %v, want %v", tt.month.Format("01-2006"), got, tt.want)
+			}
+		})
+	}
+}
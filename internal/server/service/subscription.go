@@ -2,80 +2,560 @@ package service
 
 import (
 	"context"
-	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/morphlinkk/subscriptions/internal/apperr"
 	"github.com/morphlinkk/subscriptions/internal/model"
 	"github.com/morphlinkk/subscriptions/internal/repository"
 )
 
 type SubscriptionService interface {
 	GetByID(ctx context.Context, id int64) (*model.Subscription, error)
+	GetByIDAsOf(ctx context.Context, id int64, asOf time.Time) (*model.Subscription, error)
 	AddSubscription(ctx context.Context, params model.AddSubscriptionParams) (*model.Subscription, error)
 	UpdateSubscription(ctx context.Context, id int64, sub model.UpdateSubscriptionParams) (*model.Subscription, error)
-	ListSubscriptions(ctx context.Context, params model.ListSubscriptionsParams) ([]model.Subscription, error)
-	GetSumOfSubscriptionPrices(ctx context.Context, params model.SumOfSubscriptionPricesParams) (int64, error)
+	DeleteSubscription(ctx context.Context, id int64) error
+	RestoreSubscription(ctx context.Context, id int64) (*model.Subscription, error)
+	PurgeSubscription(ctx context.Context, id int64) error
+	TransferSubscription(ctx context.Context, id int64, params model.TransferSubscriptionParams) (*model.SubscriptionTransfer, error)
+	ListSubscriptions(ctx context.Context, params model.ListSubscriptionsParams) (*model.SubscriptionPage, error)
+	SuggestServiceNames(ctx context.Context, params model.SuggestServicesParams) ([]model.ServiceSuggestion, error)
+	GetSumOfSubscriptionPrices(ctx context.Context, params model.SumOfSubscriptionPricesParams) (*model.SpendSummary, error)
+	GetSpendTimeSeries(ctx context.Context, params model.SpendTimeSeriesParams) ([]model.SpendBucket, error)
+	ListPrices(ctx context.Context, id int64, asOf *time.Time) ([]model.SubscriptionPrice, error)
+	ChangeServicePrice(ctx context.Context, params model.ServicePriceChangeParams) (*model.ServicePriceChange, error)
+	ChangeStatus(ctx context.Context, id int64, params model.ChangeStatusParams) (*model.Subscription, error)
+	ListStatusChanges(ctx context.Context, id int64) ([]model.StatusChange, error)
+	ConvertEndedTrials(ctx context.Context, today time.Time) ([]model.Subscription, error)
+	FlagEndingTrials(ctx context.Context, until time.Time) ([]model.Subscription, error)
+	ApplyDueTerms(ctx context.Context, month time.Time) ([]model.Subscription, error)
+	ListRenewals(ctx context.Context, params model.RenewalsParams) (*model.RenewalCalendar, error)
+	ListRenewalSchedules(ctx context.Context, userID uuid.UUID) ([]model.RenewalSchedule, error)
 }
 
+const (
+	defaultListLimit    = 20
+	maxListLimit        = 1000
+	defaultSuggestLimit = 10
+	maxSuggestLimit     = 50
+	maxBillingCount     = 120
+	maxEndingWithin     = 3660
+	maxPeriodMonths     = 600
+)
+
 type subscriptionService struct {
-	repo repository.SubscriptionRepository
+	repo    repository.SubscriptionRepository
+	catalog repository.CatalogRepository
+	users   repository.UserRepository
+	rates   repository.ExchangeRateRepository
 }
 
-func NewSubscriptionService(repo repository.SubscriptionRepository) SubscriptionService {
+func NewSubscriptionService(repo repository.SubscriptionRepository, catalog repository.CatalogRepository, users repository.UserRepository, rates repository.ExchangeRateRepository) SubscriptionService {
 	return &subscriptionService{
 		repo,
+		catalog,
+		users,
+		rates,
 	}
 }
 
 func (s *subscriptionService) GetByID(ctx context.Context, id int64) (*model.Subscription, error) {
-	if id <= 0 {
-		return nil, errors.New("invalid subscription id")
+	if err := validateID(id); err != nil {
+		return nil, err
 	}
 	return s.repo.GetById(ctx, id)
 }
 
+// GetByIDAsOf returns the subscription as it was stored at asOf. It is not
+// found when it did not exist yet or was deleted at that moment.
+func (s *subscriptionService) GetByIDAsOf(ctx context.Context, id int64, asOf time.Time) (*model.Subscription, error) {
+	if err := validateID(id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByIdAsOf(ctx, id, asOf)
+}
+
+// AddSubscription links the subscription to the catalog entry matching its
+// service name or one of its aliases, storing the canonical name. A zero price
+// falls back to the catalog's default price when it is in the same currency.
+// New subscriptions are active unless they start as a trial. The user must
+// exist.
 func (s *subscriptionService) AddSubscription(ctx context.Context, params model.AddSubscriptionParams) (*model.Subscription, error) {
-	if params.Price <= 0 {
-		return nil, errors.New("price must be positive")
+	params.Service = strings.TrimSpace(params.Service)
+	params.Currency = normalizeCurrency(params.Currency)
+	if params.Service != "" {
+		svc, err := resolveService(ctx, s.catalog, params.Service)
+		if err != nil {
+			return nil, err
+		}
+		if svc != nil {
+			params.Service = svc.Name
+			params.ServiceID = &svc.ID
+			if params.Price == 0 && svc.DefaultPrice != nil && svc.Currency == params.Currency {
+				params.Price = *svc.DefaultPrice
+			}
+		}
 	}
-	if params.Service == "" {
-		return nil, errors.New("service name is required")
+
+	var v apperr.Validator
+	v.Check(params.Price > 0, "price", apperr.CodeOutOfRange, "price must be positive")
+	validateCurrency(&v, "currency", params.Currency)
+	validateTrial(&v, &params)
+	params.Billing = defaultBilling(params.Billing)
+	validateBilling(&v, params.Billing)
+	v.Check(params.Service != "", "service_name", apperr.CodeRequired, "service name is required")
+	v.Check(params.UserID != uuid.Nil, "user_id", apperr.CodeRequired, "user_id is required")
+	v.Check(params.EndDate == nil || !params.EndDate.Before(params.StartDate),
+		"end_date", apperr.CodeOutOfRange, "end_date must not be before start_date")
+	if err := v.Err(); err != nil {
+		return nil, err
 	}
-	if params.UserID == uuid.Nil {
-		return nil, errors.New("user_id is required")
+
+	if err := checkUserExists(ctx, s.users, "user_id", params.UserID); err != nil {
+		return nil, err
 	}
 	return s.repo.AddSubscription(ctx, &params)
 }
 
+// UpdateSubscription applies a price or billing interval from
+// params.PriceEffectiveFrom on when it is set, which must fall within the
+// subscription's months. Billing fields are validated together with the ones
+// left unchanged. The end date only changes here while the subscription is
+// active or in a trial; otherwise cancel and reactivate move it.
 func (s *subscriptionService) UpdateSubscription(ctx context.Context, id int64, params model.UpdateSubscriptionParams) (*model.Subscription, error) {
-	if id <= 0 {
-		return nil, errors.New("invalid subscription id")
+	if err := validateID(id); err != nil {
+		return nil, err
+	}
+
+	var v apperr.Validator
+	v.Check(params.UserID == nil, "user_id", apperr.CodeInvalid, "user_id cannot be updated, transfer the subscription instead")
+	v.Check(params.Price == nil || *params.Price > 0, "price", apperr.CodeOutOfRange, "price must be positive")
+	billingChanged := params.BillingUnit != nil || params.BillingCount != nil || params.BillingAnchorDay != nil
+	v.Check(params.PriceEffectiveFrom == nil || params.Price != nil || billingChanged,
+		"effective_from", apperr.CodeInvalid, "effective_from requires price or a billing field")
+	if params.Service != nil {
+		name := strings.TrimSpace(*params.Service)
+		params.Service = &name
+		v.Check(name != "", "service_name", apperr.CodeRequired, "service name is provided but empty")
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
 	}
-	if params.Price != nil && *params.Price <= 0 {
-		return nil, errors.New("price must be positive")
+
+	if params.PriceEffectiveFrom != nil || billingChanged || params.EndDate.Set {
+		sub, err := s.repo.GetById(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if params.EndDate.Set {
+			if sub.Status != model.StatusActive && sub.Status != model.StatusTrial {
+				return nil, apperr.Conflict("subscription %d is %s, change its end date with cancel or reactivate", id, sub.Status)
+			}
+			// The status the end date was allowed for must still hold when
+			// it is written.
+			if params.ExpectedVersions == nil {
+				params.ExpectedVersions = []int{sub.Version}
+			} else if !slices.Contains(params.ExpectedVersions, sub.Version) {
+				return nil, apperr.PreconditionFailed("subscription %d has changed, its current version is %d", id, sub.Version)
+			}
+		}
+
+		if billingChanged {
+			validateBilling(&v, model.MergeBilling(sub.Billing, params))
+		}
+
+		if params.PriceEffectiveFrom != nil {
+			from := monthStart(*params.PriceEffectiveFrom)
+			params.PriceEffectiveFrom = &from
+
+			end := sub.EndDate
+			if params.EndDate.Set {
+				end = params.EndDate.Value
+			}
+			v.Check(!from.Before(monthStart(sub.StartDate)),
+				"effective_from", apperr.CodeOutOfRange, "effective_from must not be before the subscription's start month")
+			v.Check(end == nil || !from.After(monthStart(*end)),
+				"effective_from", apperr.CodeOutOfRange, "effective_from must not be after the subscription's end month")
+		}
+
+		if err := v.Err(); err != nil {
+			return nil, err
+		}
 	}
-	if params.Service != nil && *params.Service == "" {
-		return nil, errors.New("service name is provided but empty")
+
+	if params.Service != nil {
+		svc, err := resolveService(ctx, s.catalog, *params.Service)
+		if err != nil {
+			return nil, err
+		}
+		params.ServiceID = nil
+		if svc != nil {
+			params.Service = &svc.Name
+			params.ServiceID = &svc.ID
+		}
 	}
+	params.CurrentMonth = currentMonth()
 	return s.repo.UpdateSubscription(ctx, id, &params)
 }
 
-func (s *subscriptionService) ListSubscriptions(ctx context.Context, params model.ListSubscriptionsParams) ([]model.Subscription, error) {
+func (s *subscriptionService) DeleteSubscription(ctx context.Context, id int64) error {
+	if err := validateID(id); err != nil {
+		return err
+	}
+	return s.repo.DeleteSubscription(ctx, id)
+}
+
+func (s *subscriptionService) RestoreSubscription(ctx context.Context, id int64) (*model.Subscription, error) {
+	if err := validateID(id); err != nil {
+		return nil, err
+	}
+	return s.repo.RestoreSubscription(ctx, id)
+}
+
+func (s *subscriptionService) PurgeSubscription(ctx context.Context, id int64) error {
+	if err := validateID(id); err != nil {
+		return err
+	}
+	return s.repo.PurgeSubscription(ctx, id)
+}
+
+// TransferSubscription hands subscription id over to another user from
+// params.EffectiveMonth on. The current owner keeps the earlier months, so the
+// month must fall strictly after the start month and within the end month.
+func (s *subscriptionService) TransferSubscription(ctx context.Context, id int64, params model.TransferSubscriptionParams) (*model.SubscriptionTransfer, error) {
+	if err := validateID(id); err != nil {
+		return nil, err
+	}
+
+	var v apperr.Validator
+	v.Check(params.UserID != uuid.Nil, "user_id", apperr.CodeRequired, "user_id is required")
+	v.Check(!params.EffectiveMonth.IsZero(), "effective_month", apperr.CodeRequired, "effective_month is required")
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	params.EffectiveMonth = monthStart(params.EffectiveMonth)
+
+	sub, err := s.repo.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if sub.Status != model.StatusActive {
+		return nil, apperr.Conflict("subscription %d is %s, only active subscriptions can be transferred", id, sub.Status)
+	}
+	v.Check(params.UserID != sub.UserID, "user_id", apperr.CodeInvalid, "subscription already belongs to user_id")
+	v.Check(params.EffectiveMonth.After(monthStart(sub.StartDate)),
+		"effective_month", apperr.CodeOutOfRange, "effective_month must be after the subscription's start month")
+	v.Check(sub.EndDate == nil || !params.EffectiveMonth.After(monthStart(*sub.EndDate)),
+		"effective_month", apperr.CodeOutOfRange, "effective_month must not be after the subscription's end month")
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := checkUserExists(ctx, s.users, "user_id", params.UserID); err != nil {
+		return nil, err
+	}
+	return s.repo.TransferSubscription(ctx, id, &params)
+}
+
+func (s *subscriptionService) ListSubscriptions(ctx context.Context, params model.ListSubscriptionsParams) (*model.SubscriptionPage, error) {
 	if params.Limit <= 0 {
-		params.Limit = 20
+		params.Limit = defaultListLimit
+	}
+	if params.Limit > maxListLimit {
+		params.Limit = maxListLimit
 	}
 	if params.Offset < 0 {
 		params.Offset = 0
 	}
+	if params.Query != nil && strings.TrimSpace(*params.Query) == "" {
+		params.Query = nil
+	}
+	if len(params.Sort) == 0 {
+		params.Sort = model.DefaultSubscriptionSort
+		if params.Query != nil {
+			params.Sort = model.DefaultSearchSort
+		}
+	}
+	if err := validateListParams(params); err != nil {
+		return nil, err
+	}
+	params.Today = today()
 	return s.repo.ListSubscriptions(ctx, &params)
 }
 
-func (s *subscriptionService) GetSumOfSubscriptionPrices(ctx context.Context, params model.SumOfSubscriptionPricesParams) (int64, error) {
-	if params.PeriodStart == nil {
-		return 0, errors.New("period_start is required")
+func (s *subscriptionService) SuggestServiceNames(ctx context.Context, params model.SuggestServicesParams) ([]model.ServiceSuggestion, error) {
+	params.Query = strings.TrimSpace(params.Query)
+	if params.Query == "" {
+		return nil, apperr.InvalidField("q", apperr.CodeRequired, "q is required")
+	}
+	if params.Limit <= 0 {
+		params.Limit = defaultSuggestLimit
+	}
+	if params.Limit > maxSuggestLimit {
+		params.Limit = maxSuggestLimit
+	}
+	return s.repo.SuggestServiceNames(ctx, &params)
+}
+
+func (s *subscriptionService) GetSumOfSubscriptionPrices(ctx context.Context, params model.SumOfSubscriptionPricesParams) (*model.SpendSummary, error) {
+	if err := validatePeriod(params.PeriodStart, params.PeriodEnd); err != nil {
+		return nil, err
+	}
+	currency, err := targetCurrency(params.Currency)
+	if err != nil {
+		return nil, err
+	}
+	params.Currency = currency
+
+	subs, err := s.repo.ListSubscriptionsInPeriod(ctx, &params)
+	if err != nil {
+		return nil, err
+	}
+	prices, err := s.pricesOf(ctx, subs, params.AsOf)
+	if err != nil {
+		return nil, err
+	}
+	pauses, err := s.pausesOf(ctx, subs, params.AsOf)
+	if err != nil {
+		return nil, err
+	}
+	conv, err := newCurrencyConverter(ctx, s.rates, subs, params.Currency, monthStart(*params.PeriodStart), monthStart(*params.PeriodEnd))
+	if err != nil {
+		return nil, err
+	}
+	return calculateSpend(subs, prices, pauses, conv, *params.PeriodStart, *params.PeriodEnd)
+}
+
+func (s *subscriptionService) GetSpendTimeSeries(ctx context.Context, params model.SpendTimeSeriesParams) ([]model.SpendBucket, error) {
+	if err := validatePeriod(params.PeriodStart, params.PeriodEnd); err != nil {
+		return nil, err
+	}
+	switch params.GroupBy {
+	case model.SpendGroupByNone, model.SpendGroupByService, model.SpendGroupByUser:
+	default:
+		return nil, apperr.InvalidField("group_by", apperr.CodeInvalid, "group_by must be service_name or user_id")
 	}
-	if params.PeriodEnd == nil {
-		return 0, errors.New("period_end is required")
+	currency, err := targetCurrency(params.Currency)
+	if err != nil {
+		return nil, err
+	}
+	params.Currency = currency
+
+	subs, err := s.repo.ListSubscriptionsInPeriod(ctx, &model.SumOfSubscriptionPricesParams{
+		UserID:      params.UserID,
+		ServiceName: params.ServiceName,
+		PeriodStart: params.PeriodStart,
+		PeriodEnd:   params.PeriodEnd,
+		AsOf:        params.AsOf,
+	})
+	if err != nil {
+		return nil, err
+	}
+	prices, err := s.pricesOf(ctx, subs, params.AsOf)
+	if err != nil {
+		return nil, err
+	}
+	pauses, err := s.pausesOf(ctx, subs, params.AsOf)
+	if err != nil {
+		return nil, err
+	}
+	conv, err := newCurrencyConverter(ctx, s.rates, subs, params.Currency, monthStart(*params.PeriodStart), monthStart(*params.PeriodEnd))
+	if err != nil {
+		return nil, err
+	}
+	return calculateSpendTimeSeries(subs, prices, pauses, conv, *params.PeriodStart, *params.PeriodEnd, params.GroupBy)
+}
+
+// ListPrices returns the price history of subscription id, oldest first, as
+// recorded at asOf when set.
+func (s *subscriptionService) ListPrices(ctx context.Context, id int64, asOf *time.Time) ([]model.SubscriptionPrice, error) {
+	var err error
+	if asOf != nil {
+		_, err = s.GetByIDAsOf(ctx, id, *asOf)
+	} else {
+		_, err = s.GetByID(ctx, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	prices, err := s.repo.ListPrices(ctx, []int64{id}, asOf)
+	if err != nil {
+		return nil, err
+	}
+	return prices[id], nil
+}
+
+// ChangeServicePrice changes the price of a service's subscriptions in
+// params.Currency, or with params.DryRun previews the change. A service name
+// matching a catalog entry or alias stands for its canonical name.
+func (s *subscriptionService) ChangeServicePrice(ctx context.Context, params model.ServicePriceChangeParams) (*model.ServicePriceChange, error) {
+	params.Service = strings.TrimSpace(params.Service)
+	params.Currency = strings.ToUpper(strings.TrimSpace(params.Currency))
+
+	var v apperr.Validator
+	v.Check(params.Service != "", "service_name", apperr.CodeRequired, "service name is required")
+	if params.Currency == "" {
+		v.Add("currency", apperr.CodeRequired, "currency is required")
+	} else {
+		validateCurrency(&v, "currency", params.Currency)
+	}
+	v.Check(params.OldPrice > 0, "old_price", apperr.CodeOutOfRange, "old_price must be positive")
+	v.Check(params.NewPrice > 0, "new_price", apperr.CodeOutOfRange, "new_price must be positive")
+	v.Check(params.NewPrice != params.OldPrice, "new_price", apperr.CodeInvalid, "new_price must differ from old_price")
+	v.Check(!params.EffectiveMonth.IsZero(), "effective_month", apperr.CodeRequired, "effective_month is required")
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	params.EffectiveMonth = monthStart(params.EffectiveMonth)
+	params.CurrentMonth = currentMonth()
+
+	svc, err := resolveService(ctx, s.catalog, params.Service)
+	if err != nil {
+		return nil, err
+	}
+	if svc != nil {
+		params.Service = svc.Name
+	}
+
+	subs, err := s.repo.ChangeServicePrice(ctx, &params)
+	if err != nil {
+		return nil, err
+	}
+	var delta float64
+	for _, sub := range subs {
+		delta += sub.Billing.MonthlyCost(params.NewPrice) - sub.Billing.MonthlyCost(params.OldPrice)
+	}
+	return &model.ServicePriceChange{
+		Params:        params,
+		Subscriptions: subs,
+		MonthlyDelta:  roundAmount(delta),
+	}, nil
+}
+
+// ApplyDueTerms lists on each subscription the price and billing interval
+// that apply to it in month, once a change recorded for a later month comes.
+func (s *subscriptionService) ApplyDueTerms(ctx context.Context, month time.Time) ([]model.Subscription, error) {
+	return s.repo.ApplyDueTerms(ctx, monthStart(month))
+}
+
+// pricesOf returns the price histories of subs keyed by subscription ID.
+func (s *subscriptionService) pricesOf(ctx context.Context, subs []model.Subscription, asOf *time.Time) (map[int64][]model.SubscriptionPrice, error) {
+	if len(subs) == 0 {
+		return nil, nil
+	}
+	ids := make([]int64, len(subs))
+	for i, sub := range subs {
+		ids[i] = sub.ID
+	}
+	return s.repo.ListPrices(ctx, ids, asOf)
+}
+
+func validateListParams(params model.ListSubscriptionsParams) error {
+	var v apperr.Validator
+
+	seen := map[string]bool{}
+	for _, f := range params.Sort {
+		if !model.IsSubscriptionSortField(f.Field) {
+			v.Add("sort", apperr.CodeInvalid, "cannot sort by "+f.Field)
+		} else if f.Field == model.SortByRelevance && params.Query == nil {
+			v.Add("sort", apperr.CodeInvalid, "sorting by relevance requires q")
+		} else if seen[f.Field] {
+			v.Add("sort", apperr.CodeInvalid, "duplicate sort field "+f.Field)
+		}
+		seen[f.Field] = true
+	}
+
+	if params.After != nil {
+		v.Check(params.Offset == 0, "offset", apperr.CodeInvalid, "offset cannot be combined with cursor")
+		keys := len(params.Sort)
+		if !seen[model.SortByID] {
+			keys++
+		}
+		v.Check(params.After.Sort == params.Sort.String() && len(params.After.Values) == keys,
+			"cursor", apperr.CodeInvalid, "cursor was created for a different sort")
+	}
+
+	if params.EndingWithin != nil {
+		v.Check(*params.EndingWithin >= 0 && *params.EndingWithin <= maxEndingWithin,
+			"ending_within", apperr.CodeOutOfRange, fmt.Sprintf("ending_within must be between 0 and %d days", maxEndingWithin))
+	}
+	if params.PriceMin != nil && params.PriceMax != nil {
+		v.Check(*params.PriceMin <= *params.PriceMax, "price_max", apperr.CodeOutOfRange, "price_max must not be below price_min")
+	}
+	if params.StartedFrom != nil && params.StartedTo != nil {
+		v.Check(!params.StartedTo.Before(*params.StartedFrom), "started_to", apperr.CodeOutOfRange, "started_to must not be before started_from")
+	}
+	if params.EndedFrom != nil && params.EndedTo != nil {
+		v.Check(!params.EndedTo.Before(*params.EndedFrom), "ended_to", apperr.CodeOutOfRange, "ended_to must not be before ended_from")
+	}
+
+	return v.Err()
+}
+
+// defaultBilling fills the unset fields of b with monthly billing on the 1st.
+func defaultBilling(b model.BillingInterval) model.BillingInterval {
+	if b.Unit == "" {
+		b.Unit = model.MonthlyBilling.Unit
+	}
+	if b.Count == 0 {
+		b.Count = model.MonthlyBilling.Count
+	}
+	if b.AnchorDay == 0 {
+		b.AnchorDay = model.MonthlyBilling.AnchorDay
+	}
+	return b
+}
+
+func validateBilling(v *apperr.Validator, b model.BillingInterval) {
+	if !model.IsBillingUnit(b.Unit) {
+		v.Add("billing_interval", apperr.CodeInvalid, "billing_interval must be week, month, quarter or year")
+		return
+	}
+	v.Check(b.Count > 0 && b.Count <= maxBillingCount,
+		"billing_interval_count", apperr.CodeOutOfRange, fmt.Sprintf("billing_interval_count must be between 1 and %d", maxBillingCount))
+	if b.Unit == model.BillingWeek {
+		v.Check(b.AnchorDay >= 1 && b.AnchorDay <= 7,
+			"billing_anchor_day", apperr.CodeOutOfRange, "billing_anchor_day must be an ISO weekday between 1 and 7 for weekly billing")
+	} else {
+		v.Check(b.AnchorDay >= 1 && b.AnchorDay <= 31,
+			"billing_anchor_day", apperr.CodeOutOfRange, "billing_anchor_day must be a day of the month between 1 and 31")
+	}
+}
+
+func validateID(id int64) error {
+	if id <= 0 {
+		return apperr.InvalidField("id", apperr.CodeOutOfRange, "invalid subscription id")
+	}
+	return nil
+}
+
+// targetCurrency normalizes the currency amounts are converted to, when one
+// is requested.
+func targetCurrency(currency *string) (*string, error) {
+	if currency == nil {
+		return nil, nil
+	}
+	code := normalizeCurrency(*currency)
+	var v apperr.Validator
+	validateCurrency(&v, "currency", code)
+	return &code, v.Err()
+}
+
+func validatePeriod(start, end *time.Time) error {
+	var v apperr.Validator
+	v.Check(start != nil, "period_start", apperr.CodeRequired, "period_start is required")
+	v.Check(end != nil, "period_end", apperr.CodeRequired, "period_end is required")
+	if start != nil && end != nil {
+		v.Check(!end.Before(*start), "period_end", apperr.CodeOutOfRange, "period_end must not be before period_start")
+		v.Check(monthsBetween(*start, *end) <= maxPeriodMonths,
+			"period_end", apperr.CodeOutOfRange, fmt.Sprintf("the period must not be longer than %d months", maxPeriodMonths))
 	}
-	return s.repo.GetSumOfSubscriptionPrices(ctx, &params)
+	return v.Err()
 }
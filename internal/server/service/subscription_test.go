@@ -0,0 +1,124 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/morphlinkk/subscriptions/internal/model"
+)
+
+func TestValidateListParams(t *testing.T) {
+	priceSort := model.SubscriptionSort{{Field: model.SortByPrice, Desc: true}}
+
+	tests := []struct {
+		name    string
+		params  model.ListSubscriptionsParams
+		wantErr bool
+	}{
+		{
+			name:   "default sort",
+			params: model.ListSubscriptionsParams{Sort: model.DefaultSubscriptionSort},
+		},
+		{
+			name:    "unknown sort field",
+			params:  model.ListSubscriptionsParams{Sort: model.SubscriptionSort{{Field: "user_id"}}},
+			wantErr: true,
+		},
+		{
+			name:    "duplicate sort field",
+			params:  model.ListSubscriptionsParams{Sort: model.SubscriptionSort{{Field: model.SortByPrice}, {Field: model.SortByPrice, Desc: true}}},
+			wantErr: true,
+		},
+		{
+			name: "cursor of the same sort",
+			params: model.ListSubscriptionsParams{
+				Sort:  priceSort,
+				After: &model.SubscriptionCursor{Sort: "-price", Values: []string{"799", "1"}},
+			},
+		},
+		{
+			name: "cursor of a sort ending with id",
+			params: model.ListSubscriptionsParams{
+				Sort:  model.SubscriptionSort{{Field: model.SortByPrice}, {Field: model.SortByID}},
+				After: &model.SubscriptionCursor{Sort: "price,id", Values: []string{"799", "1"}},
+			},
+		},
+		{
+			name: "cursor of a different sort",
+			params: model.ListSubscriptionsParams{
+				Sort:  priceSort,
+				After: &model.SubscriptionCursor{Sort: "price", Values: []string{"799", "1"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "cursor with a missing value",
+			params: model.ListSubscriptionsParams{
+				Sort:  priceSort,
+				After: &model.SubscriptionCursor{Sort: "-price", Values: []string{"799"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "cursor with an offset",
+			params: model.ListSubscriptionsParams{
+				Sort:   priceSort,
+				Offset: 20,
+				After:  &model.SubscriptionCursor{Sort: "-price", Values: []string{"799", "1"}},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "price_max below price_min",
+			params:  model.ListSubscriptionsParams{PriceMin: ptr(500), PriceMax: ptr(400)},
+			wantErr: true,
+		},
+		{
+			name:   "equal price bounds",
+			params: model.ListSubscriptionsParams{PriceMin: ptr(500), PriceMax: ptr(500)},
+		},
+		{
+			name:    "started_to before started_from",
+			params:  model.ListSubscriptionsParams{StartedFrom: ptr(date(2026, time.March, 1)), StartedTo: ptr(date(2026, time.February, 1))},
+			wantErr: true,
+		},
+		{
+			name:    "ended_to before ended_from",
+			params:  model.ListSubscriptionsParams{EndedFrom: ptr(date(2026, time.March, 1)), EndedTo: ptr(date(2026, time.February, 1))},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateListParams(tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateListParams() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidatePeriod(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end *time.Time
+		wantErr    bool
+	}{
+		{"one month", ptr(date(2026, time.March, 1)), ptr(date(2026, time.March, 1)), false},
+		{"600 months", ptr(date(2000, time.January, 1)), ptr(date(2049, time.December, 1)), false},
+		{"601 months", ptr(date(2000, time.January, 1)), ptr(date(2050, time.January, 1)), true},
+		{"end before start", ptr(date(2026, time.March, 1)), ptr(date(2026, time.February, 1)), true},
+		{"missing start", nil, ptr(date(2026, time.March, 1)), true},
+		{"missing end", ptr(date(2026, time.March, 1)), nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePeriod(tt.start, tt.end)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validatePeriod() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
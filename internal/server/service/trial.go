@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/morphlinkk/subscriptions/internal/apperr"
+	"github.com/morphlinkk/subscriptions/internal/model"
+)
+
+// today returns the current date in UTC.
+func today() time.Time {
+	now := time.Now().UTC()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// validateTrial checks the trial of a new subscription and defaults its
+// status: a subscription whose trial has not ended yet starts as a trial,
+// any other one as active.
+func validateTrial(v *apperr.Validator, params *model.AddSubscriptionParams) {
+	v.Check(params.TrialPrice >= 0, "trial_price", apperr.CodeOutOfRange, "trial_price must not be negative")
+	v.Check(params.TrialPrice == 0 || params.TrialEnd != nil, "trial_price", apperr.CodeInvalid, "trial_price requires trial_end")
+
+	ongoing := false
+	if params.TrialEnd != nil {
+		v.Check(!params.TrialEnd.Before(params.StartDate),
+			"trial_end", apperr.CodeOutOfRange, "trial_end must not be before start_date")
+		ongoing = !params.TrialEnd.Before(today())
+	}
+
+	switch params.Status {
+	case "":
+		params.Status = model.StatusActive
+		if ongoing {
+			params.Status = model.StatusTrial
+		}
+	case model.StatusTrial:
+		v.Check(ongoing, "trial_end", apperr.CodeRequired, "a trial requires a trial_end that is today or later")
+	case model.StatusActive:
+		v.Check(!ongoing, "status", apperr.CodeInvalid, "status must be trial until trial_end has passed")
+	default:
+		v.Add("status", apperr.CodeInvalid, "status must be active or trial")
+	}
+}
+
+// ConvertEndedTrials makes every trial whose last day is before today
+// active, charging its price from then on.
+func (s *subscriptionService) ConvertEndedTrials(ctx context.Context, today time.Time) ([]model.Subscription, error) {
+	return s.repo.ConvertEndedTrials(ctx, today)
+}
+
+// FlagEndingTrials flags the trials that end until, inclusive, and were not
+// flagged before, so that each one is reported once ahead of its conversion.
+func (s *subscriptionService) FlagEndingTrials(ctx context.Context, until time.Time) ([]model.Subscription, error) {
+	return s.repo.FlagEndingTrials(ctx, until)
+}
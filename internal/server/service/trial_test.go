@@ -0,0 +1,92 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/morphlinkk/subscriptions/internal/apperr"
+	"github.com/morphlinkk/subscriptions/internal/model"
+)
+
+func TestValidateTrial(t *testing.T) {
+	now := today()
+	start := now.AddDate(0, -1, 0)
+
+	tests := []struct {
+		name       string
+		params     model.AddSubscriptionParams
+		wantStatus model.SubscriptionStatus
+		wantErr    bool
+	}{
+		{
+			name:       "no trial is active",
+			params:     model.AddSubscriptionParams{StartDate: start},
+			wantStatus: model.StatusActive,
+		},
+		{
+			name:       "ongoing trial",
+			params:     model.AddSubscriptionParams{StartDate: start, TrialEnd: ptr(now.AddDate(0, 0, 7)), TrialPrice: 100},
+			wantStatus: model.StatusTrial,
+		},
+		{
+			name:       "trial ending today is ongoing",
+			params:     model.AddSubscriptionParams{StartDate: start, TrialEnd: ptr(now)},
+			wantStatus: model.StatusTrial,
+		},
+		{
+			name:       "ended trial is active",
+			params:     model.AddSubscriptionParams{StartDate: start, TrialEnd: ptr(now.AddDate(0, 0, -1))},
+			wantStatus: model.StatusActive,
+		},
+		{
+			name:    "trial_price without trial_end",
+			params:  model.AddSubscriptionParams{StartDate: start, TrialPrice: 100},
+			wantErr: true,
+		},
+		{
+			name:    "negative trial_price",
+			params:  model.AddSubscriptionParams{StartDate: start, TrialEnd: ptr(now), TrialPrice: -1},
+			wantErr: true,
+		},
+		{
+			name:    "trial_end before start_date",
+			params:  model.AddSubscriptionParams{StartDate: start, TrialEnd: ptr(start.AddDate(0, 0, -1))},
+			wantErr: true,
+		},
+		{
+			name:    "trial status after the trial",
+			params:  model.AddSubscriptionParams{StartDate: start, TrialEnd: ptr(now.AddDate(0, 0, -1)), Status: model.StatusTrial},
+			wantErr: true,
+		},
+		{
+			name:    "active status during the trial",
+			params:  model.AddSubscriptionParams{StartDate: start, TrialEnd: ptr(now.AddDate(0, 0, 1)), Status: model.StatusActive},
+			wantErr: true,
+		},
+		{
+			name:    "other status",
+			params:  model.AddSubscriptionParams{StartDate: start, Status: model.StatusPaused},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v apperr.Validator
+			params := tt.params
+			validateTrial(&v, &params)
+			err := v.Err()
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("validateTrial() status %s, want an error", params.Status)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateTrial() error = %v", err)
+			}
+			if params.Status != tt.wantStatus {
+				t.Errorf("Status = %s, want %s", params.Status, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"net/mail"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/morphlinkk/subscriptions/internal/apperr"
+	"github.com/morphlinkk/subscriptions/internal/model"
+	"github.com/morphlinkk/subscriptions/internal/repository"
+)
+
+type UserService interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*model.User, error)
+	AddUser(ctx context.Context, params model.AddUserParams) (*model.User, error)
+	UpdateUser(ctx context.Context, id uuid.UUID, params model.UpdateUserParams) (*model.User, error)
+	DeleteUser(ctx context.Context, id uuid.UUID) error
+	ListUsers(ctx context.Context, params model.ListUsersParams) ([]model.User, error)
+	RotateCalendarToken(ctx context.Context, id uuid.UUID) (string, error)
+	CheckCalendarToken(ctx context.Context, id uuid.UUID, token string) error
+}
+
+type userService struct {
+	repo repository.UserRepository
+}
+
+func NewUserService(repo repository.UserRepository) UserService {
+	return &userService{
+		repo,
+	}
+}
+
+func (s *userService) GetByID(ctx context.Context, id uuid.UUID) (*model.User, error) {
+	return s.repo.GetById(ctx, id)
+}
+
+func (s *userService) AddUser(ctx context.Context, params model.AddUserParams) (*model.User, error) {
+	params.Name = strings.TrimSpace(params.Name)
+	params.Email = normalizeEmail(params.Email)
+
+	var v apperr.Validator
+	v.Check(params.ID == nil || *params.ID != uuid.Nil, "id", apperr.CodeInvalid, "id must not be the nil UUID")
+	v.Check(params.Name != "", "name", apperr.CodeRequired, "name is required")
+	validateEmail(&v, params.Email)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	return s.repo.AddUser(ctx, &params)
+}
+
+func (s *userService) UpdateUser(ctx context.Context, id uuid.UUID, params model.UpdateUserParams) (*model.User, error) {
+	var v apperr.Validator
+	if params.Name != nil {
+		name := strings.TrimSpace(*params.Name)
+		params.Name = &name
+		v.Check(name != "", "name", apperr.CodeRequired, "name is provided but empty")
+	}
+	params.Email = normalizeEmail(params.Email)
+	validateEmail(&v, params.Email)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	return s.repo.UpdateUser(ctx, id, &params)
+}
+
+func (s *userService) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	return s.repo.DeleteUser(ctx, id)
+}
+
+func (s *userService) ListUsers(ctx context.Context, params model.ListUsersParams) ([]model.User, error) {
+	if params.Limit <= 0 {
+		params.Limit = defaultListLimit
+	}
+	if params.Limit > maxListLimit {
+		params.Limit = maxListLimit
+	}
+	if params.Offset < 0 {
+		params.Offset = 0
+	}
+	return s.repo.ListUsers(ctx, &params)
+}
+
+// checkUserExists reports an unknown user as a validation error on field, so
+// that callers referencing a user get a 422 rather than a 404.
+func checkUserExists(ctx context.Context, repo repository.UserRepository, field string, id uuid.UUID) error {
+	_, err := repo.GetById(ctx, id)
+	if apperr.KindOf(err) == apperr.KindNotFound {
+		return apperr.InvalidField(field, apperr.CodeUnknown, field+" does not match any user")
+	}
+	return err
+}
+
+func normalizeEmail(email *string) *string {
+	if email == nil {
+		return nil
+	}
+	e := strings.TrimSpace(*email)
+	return &e
+}
+
+func validateEmail(v *apperr.Validator, email *string) {
+	if email == nil {
+		return
+	}
+	addr, err := mail.ParseAddress(*email)
+	v.Check(err == nil && addr.Address == *email, "email", apperr.CodeInvalidFormat, "email must be a valid address")
+}